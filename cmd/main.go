@@ -6,6 +6,8 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	// embed timezone database for airport local times
+	_ "time/tzdata"
 
 	"github.com/yuanyu90221/airline-order-system/internal/application"
 	"github.com/yuanyu90221/airline-order-system/internal/config"
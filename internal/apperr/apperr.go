@@ -0,0 +1,110 @@
+package apperr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Code: machine readable error code of response, clients switch on code instead of message
+type Code string
+
+const (
+	BadRequest           Code = "bad_request"
+	ValidationFailed     Code = "validation_failed"
+	Unauthorized         Code = "unauthorized"
+	Forbidden            Code = "forbidden"
+	NotFound             Code = "not_found"
+	Conflict             Code = "conflict"
+	InsufficientSeats    Code = "insufficient_seats"
+	PreconditionRequired Code = "precondition_required"
+	TooManyRequests      Code = "too_many_requests"
+	Internal             Code = "internal"
+	NotImplemented       Code = "not_implemented"
+	Unavailable          Code = "unavailable"
+)
+
+// statusOfCode: http status of every code, the only place codes are mapped to status
+var statusOfCode = map[Code]int{
+	BadRequest:           http.StatusBadRequest,
+	ValidationFailed:     http.StatusBadRequest,
+	Unauthorized:         http.StatusUnauthorized,
+	Forbidden:            http.StatusForbidden,
+	NotFound:             http.StatusNotFound,
+	Conflict:             http.StatusConflict,
+	InsufficientSeats:    http.StatusConflict,
+	PreconditionRequired: http.StatusPreconditionRequired,
+	TooManyRequests:      http.StatusTooManyRequests,
+	Internal:             http.StatusInternalServerError,
+	NotImplemented:       http.StatusNotImplemented,
+	Unavailable:          http.StatusServiceUnavailable,
+}
+
+// Error: domain error with code, message is safe to show to clients
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+// Status: http status of code, 500 for unknown code
+func Status(code Code) int {
+	if status, ok := statusOfCode[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+/*
+*
+CodeOf: code of first domain error in chain, well known errors of database, validator and network are mapped too,
+empty when err carries no code
+*/
+func CodeOf(err error) Code {
+	var domainErr *Error
+	var valErrs validator.ValidationErrors
+	var netErr net.Error
+	switch {
+	case errors.As(err, &domainErr):
+		return domainErr.Code
+	case errors.As(err, &valErrs):
+		return ValidationFailed
+	case errors.Is(err, sql.ErrNoRows):
+		return NotFound
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return Unavailable
+	}
+	return ""
+}
+
+// errorStatus: status of error response, statuses other than 4xx and 5xx are server errors
+func errorStatus(status int) int {
+	if status < http.StatusBadRequest || status > 599 || http.StatusText(status) == "" {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// codeOfStatus: code of errors without code, by status handler responded with, statuses without code are named by status text
+func codeOfStatus(status int) Code {
+	for code, codeStatus := range statusOfCode {
+		if codeStatus == status && code != ValidationFailed && code != InsufficientSeats {
+			return code
+		}
+	}
+	name := strings.ToLower(http.StatusText(status))
+	name = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(name)
+	return Code(name)
+}
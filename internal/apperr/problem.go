@@ -0,0 +1,83 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType: media type of problem details of RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// Problem: RFC 7807 problem details, code and errors are extension members
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Code   Code         `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError: failed validation rule of request field, field is json path like passengers[0].name
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+/*
+*
+NewProblem: problem of err, status follows code of err, fallbackStatus is used for errors without code,
+only messages of domain errors and validation failures are sent, other details are logged
+so database, redis and parser messages do not leak
+*/
+func NewProblem(fallbackStatus int, err error) Problem {
+	code := CodeOf(err)
+	status := Status(code)
+	if code == "" {
+		status = errorStatus(fallbackStatus)
+		code = codeOfStatus(status)
+	}
+	problem := Problem{
+		Type:   fmt.Sprintf("urn:aos:problem:%s", code),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: http.StatusText(status),
+		Code:   code,
+	}
+	var domainErr *Error
+	var valErrs validator.ValidationErrors
+	switch {
+	case errors.As(err, &valErrs):
+		problem.Detail = "request validation failed"
+		problem.Errors = make([]FieldError, 0, len(valErrs))
+		for _, fieldErr := range valErrs {
+			problem.Errors = append(problem.Errors, FieldError{
+				Field: fieldPath(fieldErr.Namespace()),
+				Rule:  fieldErr.Tag(),
+				Param: fieldErr.Param(),
+			})
+		}
+	case !errors.As(err, &domainErr):
+		log.Printf("%s: %v", code, err)
+	case status >= http.StatusInternalServerError:
+		log.Printf("%s: %v", code, err)
+		problem.Detail = domainErr.Message
+	default:
+		problem.Detail = err.Error()
+	}
+	return problem
+}
+
+// fieldPath: namespace without name of validated struct
+func fieldPath(namespace string) string {
+	if _, path, found := strings.Cut(namespace, "."); found {
+		return path
+	}
+	return namespace
+}
@@ -6,28 +6,33 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	bloomfilter "github.com/alovn/go-bloomfilter"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
+	"github.com/yuanyu90221/airline-order-system/internal/auth"
 	"github.com/yuanyu90221/airline-order-system/internal/broker"
 	"github.com/yuanyu90221/airline-order-system/internal/config"
 	"github.com/yuanyu90221/airline-order-system/internal/db"
+	"github.com/yuanyu90221/airline-order-system/internal/metrics"
 	"github.com/yuanyu90221/airline-order-system/internal/types"
 	"github.com/yuanyu90221/airline-order-system/internal/util"
 )
 
 // define app dependency
 type App struct {
-	router      *gin.Engine
-	rdb         *redis.Client
-	config      *config.Config
-	db          *sql.DB
-	bFilter     bloomfilter.BloomFilter
-	broker      *broker.Broker
-	orderWorker types.Worker
+	router  *gin.Engine
+	rdb     *redis.Client
+	config  *config.Config
+	db      *sql.DB
+	bFilter bloomfilter.BloomFilter
+	broker  *broker.Broker
+	workers []types.Worker
+	// verify bearer tokens of requests
+	verifier *auth.Verifier
 }
 
 func New(config *config.Config) *App {
@@ -44,18 +49,43 @@ func New(config *config.Config) *App {
 	if err != nil {
 		util.FailOnError(err, "failed to connect rabbitMq")
 	}
+	verifier, err := auth.NewVerifier(config)
+	if err != nil {
+		util.FailOnError(err, "failed to setup jwt verifier")
+	}
 	app := &App{
-		rdb:     rdb,
-		config:  config,
-		db:      dbConn,
-		bFilter: bloomfilter.NewRedisBloomFilter(rdb, "redis-bloom-filter", 100000),
-		broker:  broker,
+		rdb:      rdb,
+		config:   config,
+		db:       dbConn,
+		bFilter:  bloomfilter.NewRedisBloomFilter(rdb, "redis-bloom-filter", 100000),
+		broker:   broker,
+		verifier: verifier,
 	}
 
+	metrics.Register(dbConn, rdb)
 	app.loadRoutes()
 	app.loadOrderRoutes()
 	app.loadFlightRoutes()
+	app.loadItineraryRoutes()
+	app.loadCustomerRoutes()
+	app.loadAirportRoutes()
+	app.loadScheduleRoutes()
+	app.loadAircraftTypeRoutes()
+	app.loadTicketRoutes()
+	app.loadSeatMapRoutes()
+	app.loadCheckinRoutes()
+	app.loadBoardingRoutes()
+	app.loadOverbookingRoutes()
+	app.loadNoShowRoutes()
+	app.loadAgencyRoutes()
+	app.loadWaitingRoomRoutes()
+	app.loadAbuseRoutes()
 	app.setupOrderWorker()
+	app.setupGatekeeperWorker()
+	app.setupGateCloseWorker()
+	app.setupOverbookingWorker()
+	app.setupNoShowStatsWorker()
+	app.setupScheduleWorker()
 	return app
 }
 
@@ -64,6 +94,13 @@ func (app *App) Start(ctx context.Context) error {
 		Addr:    fmt.Sprintf(":%s", app.config.Port),
 		Handler: app.router,
 	}
+	// prometheus scrape endpoint is kept off public api port
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+	metricsServer := &http.Server{
+		Addr:    fmt.Sprintf(":%s", app.config.MetricsPort),
+		Handler: metricsMux,
+	}
 	err := app.rdb.Ping(ctx).Err()
 	if err != nil {
 		return fmt.Errorf("failed to connect redis: %w", err)
@@ -81,20 +118,33 @@ func (app *App) Start(ctx context.Context) error {
 		}
 	}()
 	log.Printf("Starting server on %s", app.config.Port)
-	errCh := make(chan error, 1)
+	// every goroutine can report once without blocking, errCh is closed once after all of them finished
+	errCh := make(chan error, len(app.workers)+2)
+	var wg sync.WaitGroup
+	wg.Add(len(app.workers) + 2)
 	go func() {
-		err = server.ListenAndServe()
-		if err != nil {
+		defer wg.Done()
+		if err := server.ListenAndServe(); err != nil {
 			errCh <- fmt.Errorf("failed to start server: %w", err)
 		}
-		util.CloseChannel(errCh)
 	}()
 	go func() {
-		err = app.orderWorker.Run(ctx)
-		if err != nil {
-			errCh <- fmt.Errorf("failed to run worker: %w", err)
+		defer wg.Done()
+		if err := metricsServer.ListenAndServe(); err != nil {
+			errCh <- fmt.Errorf("failed to start metrics server: %w", err)
 		}
-		util.CloseChannel(errCh)
+	}()
+	for _, worker := range app.workers {
+		go func(worker types.Worker) {
+			defer wg.Done()
+			if err := worker.Run(ctx); err != nil {
+				errCh <- fmt.Errorf("failed to run worker: %w", err)
+			}
+		}(worker)
+	}
+	go func() {
+		wg.Wait()
+		close(errCh)
 	}()
 	select {
 	case err = <-errCh:
@@ -102,6 +152,9 @@ func (app *App) Start(ctx context.Context) error {
 	case <-ctx.Done():
 		timeout, cancel := context.WithTimeout(context.Background(), time.Second*10)
 		defer cancel()
+		if err := metricsServer.Shutdown(timeout); err != nil {
+			log.Println("failed to shutdown metrics server", err)
+		}
 		return server.Shutdown(timeout)
 	}
 }
@@ -1,19 +1,53 @@
 package application
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yuanyu90221/airline-order-system/internal/auth"
+	"github.com/yuanyu90221/airline-order-system/internal/metrics"
+	"github.com/yuanyu90221/airline-order-system/internal/service/abuse"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/aircraft"
+	"github.com/yuanyu90221/airline-order-system/internal/service/airport"
+	"github.com/yuanyu90221/airline-order-system/internal/service/boarding"
+	"github.com/yuanyu90221/airline-order-system/internal/service/checkin"
+	"github.com/yuanyu90221/airline-order-system/internal/service/customer"
 	"github.com/yuanyu90221/airline-order-system/internal/service/flight"
+	"github.com/yuanyu90221/airline-order-system/internal/service/itinerary"
+	"github.com/yuanyu90221/airline-order-system/internal/service/noshow"
 	"github.com/yuanyu90221/airline-order-system/internal/service/order"
+	"github.com/yuanyu90221/airline-order-system/internal/service/overbooking"
+	"github.com/yuanyu90221/airline-order-system/internal/service/ratelimit"
+	"github.com/yuanyu90221/airline-order-system/internal/service/schedule"
+	"github.com/yuanyu90221/airline-order-system/internal/service/seatmap"
+	"github.com/yuanyu90221/airline-order-system/internal/service/ticket"
+	"github.com/yuanyu90221/airline-order-system/internal/service/waitingroom"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
 )
 
 // define route
 func (app *App) loadRoutes() {
 	gin.SetMode(app.config.GinMode)
 	router := gin.New()
-	// recovery middleware
-	router.Use(gin.Recovery())
+	// rate limits and abuse signals use client ip, forwarded headers are only honored from configured proxies
+	util.FailOnError(router.SetTrustedProxies(trustedProxies(app.config.TrustedProxies)), "failed to set trusted proxies")
+	rateLimitRules, err := ratelimit.ParseRules(app.config.RateLimitRules)
+	if err != nil {
+		util.FailOnError(err, "failed to parse rate limit rules")
+	}
+	// requests are measured including recovered panics, bearer tokens and agency api keys are verified for every route,
+	// then requests are limited by ip, customer and api key
+	router.Use(metrics.Instrument(), gin.Recovery(), auth.Authenticate(app.verifier), agency.AuthenticateAPIKey(app.newAgencyService()),
+		ratelimit.Limit(ratelimit.NewCacheStore(app.rdb), rateLimitRules))
+
+	// unknown routes answer with problem details like every other error
+	router.NoRoute(func(ctx *gin.Context) {
+		util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("route %s %s not found", ctx.Request.Method, ctx.Request.URL.Path))
+	})
 
 	// default health
 	router.GET("/", func(ctx *gin.Context) {
@@ -22,22 +56,180 @@ func (app *App) loadRoutes() {
 	app.router = router
 }
 
+// orderGroup: order routes identify customer and only serve orders owned by caller
+func (app *App) orderGroup() *gin.RouterGroup {
+	orderGroup := app.router.Group("/orders")
+	orderGroup.Use(customer.IdentifyCustomer(app.newCustomerService()), order.RequireOrderOwner(order.NewOrderStore(app.db)))
+	return orderGroup
+}
+
+// adminGroup: admin routes need admin role
+func (app *App) adminGroup() *gin.RouterGroup {
+	adminGroup := app.router.Group("/admin")
+	adminGroup.Use(auth.RequireRole(auth.RoleAdmin))
+	return adminGroup
+}
+
+// managementGroup: reads stay public, writes need admin role
+func (app *App) managementGroup(relativePath string) *gin.RouterGroup {
+	managementGroup := app.router.Group(relativePath)
+	managementGroup.Use(auth.RequireRoleForWrites(auth.RoleAdmin))
+	return managementGroup
+}
+
 // setup order route
 func (app *App) loadOrderRoutes() {
-	orderGroup := app.router.Group("/orders")
+	orderGroup := app.orderGroup()
 	orderCacheStore := order.NewCacheStore(app.rdb)
 	flightCacheStore := flight.NewCacheStore(app.rdb)
 	orderStore := order.NewOrderStore(app.db)
-	orderHandler := order.NewHandler(orderCacheStore, flightCacheStore, app.bFilter, app.broker, orderStore)
+	flightStore := flight.NewFlightStore(app.db)
+	ticketService := ticket.NewTicketService(ticket.NewTicketStore(app.db), app.config.AirlinePrefix)
+	orderService := order.NewOrderService(app.db, orderStore, flightStore, ticketService)
+	orderHandler := order.NewHandler(orderCacheStore, flightCacheStore, app.bFilter, app.broker, orderStore, orderService,
+		agency.NewCacheStore(app.rdb), waitingroom.NewCacheStore(app.rdb), app.newAbuseService())
 	orderHandler.RegisterRoute(orderGroup)
 }
 
+// setup customer and signed in customer route
+func (app *App) loadCustomerRoutes() {
+	customerHandler := customer.NewHandler(app.newCustomerService(), order.NewOrderStore(app.db), app.verifier)
+	customerHandler.RegisterRoute(app.router.Group("/customers"))
+	customerHandler.RegisterMeRoute(app.router.Group("/me"))
+}
+
+// setup itinerary route
+func (app *App) loadItineraryRoutes() {
+	itineraryGroup := app.router.Group("/itineraries")
+	orderCacheStore := order.NewCacheStore(app.rdb)
+	waitingRoomCacheStore := waitingroom.NewCacheStore(app.rdb)
+	itineraryService := itinerary.NewItineraryService(orderCacheStore, flight.NewCacheStore(app.rdb), app.bFilter, app.broker,
+		agency.NewCacheStore(app.rdb), waitingRoomCacheStore)
+	itineraryGroup.Use(customer.IdentifyCustomer(app.newCustomerService()))
+	itineraryHandler := itinerary.NewHandler(itinerary.NewItineraryStore(app.db), itineraryService,
+		flight.NewFlightStore(app.db), order.NewOrderStore(app.db), orderCacheStore,
+		airport.NewDirectory(airport.NewAirportStore(app.db)), waitingRoomCacheStore,
+		app.newAbuseService())
+	itineraryHandler.RegisterRoute(itineraryGroup)
+}
+
+// setup aircraft type route
+func (app *App) loadAircraftTypeRoutes() {
+	aircraftTypeGroup := app.managementGroup("/aircraft-types")
+	aircraftTypeHandler := aircraft.NewHandler(aircraft.NewAircraftTypeStore(app.db))
+	aircraftTypeHandler.RegisterRoute(aircraftTypeGroup)
+}
+
+// setup schedule route
+func (app *App) loadScheduleRoutes() {
+	scheduleGroup := app.managementGroup("/schedules")
+	scheduleHandler := schedule.NewHandler(app.newScheduleService(), schedule.NewScheduleStore(app.db),
+		airport.NewDirectory(airport.NewAirportStore(app.db)), aircraft.NewAircraftTypeStore(app.db), app.config.CarrierCode,
+		int(app.config.ScheduleGenerateDays))
+	scheduleHandler.RegisterRoute(scheduleGroup)
+}
+
+// setup airport route
+func (app *App) loadAirportRoutes() {
+	airportGroup := app.managementGroup("/airports")
+	airportHandler := airport.NewHandler(airport.NewAirportStore(app.db))
+	airportHandler.RegisterRoute(airportGroup)
+}
+
+// setup ticket route
+func (app *App) loadTicketRoutes() {
+	ticketGroup := app.router.Group("/tickets")
+	ticketStore := ticket.NewTicketStore(app.db)
+	ticketGroup.Use(customer.IdentifyCustomer(app.newCustomerService()), order.RequireTicketHolder(order.NewOrderStore(app.db), ticketStore))
+	ticketHandler := ticket.NewHandler(ticketStore)
+	ticketHandler.RegisterRoute(ticketGroup)
+}
+
 // setup flight route
 func (app *App) loadFlightRoutes() {
-	flightGroup := app.router.Group("/flights")
+	flightGroup := app.managementGroup("/flights")
 	orderCacheStore := order.NewCacheStore(app.rdb)
 	flightCacheStore := flight.NewCacheStore(app.rdb)
 	flightStore := flight.NewFlightStore(app.db)
-	flightHandler := flight.NewHandler(orderCacheStore, flightCacheStore, flightStore, app.bFilter)
+	flightHandler := flight.NewHandler(orderCacheStore, flightCacheStore, flightStore, app.bFilter, app.newFlightService(),
+		airport.NewDirectory(airport.NewAirportStore(app.db)))
 	flightHandler.RegisterRoute(flightGroup)
 }
+
+// setup seat map route
+func (app *App) loadSeatMapRoutes() {
+	seatMapStore := seatmap.NewSeatMapStore(app.db)
+	seatCacheStore := seatmap.NewCacheStore(app.rdb)
+	orderStore := order.NewOrderStore(app.db)
+	seatMapHandler := seatmap.NewHandler(seatMapStore, seatCacheStore, orderStore)
+	seatMapHandler.RegisterRoute(app.managementGroup("/seatmaps"))
+	seatMapHandler.RegisterFlightRoute(app.managementGroup("/flights"))
+	seatMapHandler.RegisterOrderRoute(app.orderGroup())
+}
+
+// setup check-in route
+func (app *App) loadCheckinRoutes() {
+	orderStore := order.NewOrderStore(app.db)
+	flightStore := flight.NewFlightStore(app.db)
+	seatMapStore := seatmap.NewSeatMapStore(app.db)
+	seatCacheStore := seatmap.NewCacheStore(app.rdb)
+	checkinCacheStore := checkin.NewCacheStore(app.rdb)
+	checkinService := checkin.NewCheckinService(orderStore, flightStore, seatMapStore, seatCacheStore, checkinCacheStore,
+		time.Duration(app.config.CheckinOpenHours)*time.Hour, time.Duration(app.config.CheckinCloseMinutes)*time.Minute)
+	checkinHandler := checkin.NewHandler(checkinService, seatMapStore, airport.NewDirectory(airport.NewAirportStore(app.db)),
+		app.config.CarrierCode)
+	checkinHandler.RegisterOrderRoute(app.orderGroup())
+}
+
+// setup denied boarding route
+func (app *App) loadBoardingRoutes() {
+	boardingStore := boarding.NewBoardingStore(app.db)
+	orderStore := order.NewOrderStore(app.db)
+	boardingHandler := boarding.NewHandler(app.newDeniedBoardingService(), boardingStore, orderStore)
+	boardingHandler.RegisterOrderRoute(app.orderGroup())
+	boardingHandler.RegisterAdminRoute(app.adminGroup())
+}
+
+// setup overbooking policy route
+func (app *App) loadOverbookingRoutes() {
+	policyStore := overbooking.NewPolicyStore(app.db)
+	overbookingHandler := overbooking.NewHandler(app.newOverbookingService(), policyStore)
+	overbookingHandler.RegisterAdminRoute(app.adminGroup())
+}
+
+// setup no-show stats route
+func (app *App) loadNoShowRoutes() {
+	noShowHandler := noshow.NewHandler(noshow.NewNoShowStore(app.db))
+	noShowHandler.RegisterAdminRoute(app.adminGroup())
+}
+
+// setup travel agency and api key route
+func (app *App) loadAgencyRoutes() {
+	agencyHandler := agency.NewHandler(app.newAgencyService(), agency.NewAgencyStore(app.db))
+	agencyHandler.RegisterAdminRoute(app.adminGroup())
+}
+
+// setup waiting room route
+func (app *App) loadWaitingRoomRoutes() {
+	waitingRoomHandler := waitingroom.NewHandler(waitingroom.NewCacheStore(app.rdb), flight.NewFlightStore(app.db),
+		time.Duration(app.config.WaitingRoomAdmissionSeconds)*time.Second)
+	waitingRoomHandler.RegisterRoute(app.router.Group("/waiting-room"))
+	waitingRoomHandler.RegisterAdminRoute(app.adminGroup())
+}
+
+// setup abuse decision route
+func (app *App) loadAbuseRoutes() {
+	abuseHandler := abuse.NewHandler(abuse.NewAbuseStore(app.db))
+	abuseHandler.RegisterAdminRoute(app.adminGroup())
+}
+
+// trustedProxies: ips or cidrs of comma separated list, nil trusts no proxy
+func trustedProxies(list string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(list, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
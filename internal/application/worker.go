@@ -1,15 +1,123 @@
 package application
 
 import (
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/service/abuse"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/aircraft"
+	"github.com/yuanyu90221/airline-order-system/internal/service/airport"
+	"github.com/yuanyu90221/airline-order-system/internal/service/boarding"
+	"github.com/yuanyu90221/airline-order-system/internal/service/customer"
 	"github.com/yuanyu90221/airline-order-system/internal/service/flight"
+	"github.com/yuanyu90221/airline-order-system/internal/service/noshow"
 	"github.com/yuanyu90221/airline-order-system/internal/service/order"
+	"github.com/yuanyu90221/airline-order-system/internal/service/overbooking"
+	"github.com/yuanyu90221/airline-order-system/internal/service/schedule"
+	"github.com/yuanyu90221/airline-order-system/internal/service/seatmap"
+	"github.com/yuanyu90221/airline-order-system/internal/service/ticket"
+	"github.com/yuanyu90221/airline-order-system/internal/service/waitingroom"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
 )
 
 func (app *App) setupOrderWorker() {
 	flightCacheStore := flight.NewCacheStore(app.rdb)
 	flightStore := flight.NewFlightStore(app.db)
 	orderStore := order.NewOrderStore(app.db)
-	orderService := order.NewOrderService(app.db, orderStore, flightStore)
+	ticketService := ticket.NewTicketService(ticket.NewTicketStore(app.db), app.config.AirlinePrefix)
+	orderService := order.NewOrderService(app.db, orderStore, flightStore, ticketService)
 	orderWorker := order.NewOrderWorker(orderService, flightCacheStore, app.broker)
-	app.orderWorker = orderWorker
+	app.workers = append(app.workers, orderWorker)
+}
+
+func (app *App) setupGatekeeperWorker() {
+	gatekeeperWorker := waitingroom.NewGatekeeperWorker(waitingroom.NewCacheStore(app.rdb),
+		time.Duration(app.config.WaitingRoomTickMilliseconds)*time.Millisecond)
+	app.workers = append(app.workers, gatekeeperWorker)
+}
+
+func (app *App) setupGateCloseWorker() {
+	boardingStore := boarding.NewBoardingStore(app.db)
+	gateCloseWorker := boarding.NewGateCloseWorker(app.newDeniedBoardingService(), boardingStore,
+		time.Duration(app.config.GateCloseMinutes)*time.Minute, time.Duration(app.config.GateCloseIntervalSeconds)*time.Second)
+	app.workers = append(app.workers, gateCloseWorker)
+}
+
+func (app *App) setupOverbookingWorker() {
+	recomputeWorker := overbooking.NewRecomputeWorker(app.newOverbookingService(), flight.NewFlightStore(app.db),
+		time.Duration(app.config.OverbookingRecomputeHorizonHours)*time.Hour,
+		time.Duration(app.config.OverbookingRecomputeIntervalSeconds)*time.Second)
+	app.workers = append(app.workers, recomputeWorker)
+}
+
+func (app *App) setupScheduleWorker() {
+	scheduleStore := schedule.NewScheduleStore(app.db)
+	generateWorker := schedule.NewGenerateWorker(app.newScheduleService(), scheduleStore,
+		int(app.config.ScheduleGenerateDays), time.Duration(app.config.ScheduleGenerateIntervalSeconds)*time.Second)
+	app.workers = append(app.workers, generateWorker)
+}
+
+func (app *App) newScheduleService() *schedule.ScheduleService {
+	return schedule.NewScheduleService(schedule.NewScheduleStore(app.db), app.newFlightService(),
+		airport.NewDirectory(airport.NewAirportStore(app.db)))
+}
+
+func (app *App) newFlightService() *flight.FlightService {
+	return flight.NewFlightService(flight.NewFlightStore(app.db), flight.NewCacheStore(app.rdb), app.bFilter,
+		seatmap.NewSeatMapStore(app.db), app.newOverbookingService(), airport.NewDirectory(airport.NewAirportStore(app.db)),
+		aircraft.NewAircraftTypeStore(app.db), app.config.CarrierCode)
+}
+
+func (app *App) newOverbookingService() *overbooking.OverbookingService {
+	return overbooking.NewOverbookingService(overbooking.NewPolicyStore(app.db), flight.NewFlightStore(app.db),
+		order.NewCacheStore(app.rdb), flight.NewCacheStore(app.rdb), noshow.NewNoShowStore(app.db),
+		airport.NewDirectory(airport.NewAirportStore(app.db)))
+}
+
+func (app *App) setupNoShowStatsWorker() {
+	statsWorker := noshow.NewStatsWorker(noshow.NewNoShowStore(app.db),
+		time.Duration(app.config.NoShowStatsIntervalSeconds)*time.Second)
+	app.workers = append(app.workers, statsWorker)
+}
+
+func (app *App) newDeniedBoardingService() *boarding.DeniedBoardingService {
+	rules := types.DeniedBoardingRules{
+		SelectionOrder:         app.config.DeniedBoardingSelection,
+		VolunteerCompensation:  app.config.DeniedBoardingVolunteerCompensation,
+		ShortDelayCompensation: app.config.DeniedBoardingShortDelayCompensation,
+		LongDelayCompensation:  app.config.DeniedBoardingLongDelayCompensation,
+		LongDelay:              time.Duration(app.config.DeniedBoardingLongDelayHours) * time.Hour,
+	}
+	return boarding.NewDeniedBoardingService(boarding.NewBoardingStore(app.db), flight.NewFlightStore(app.db),
+		seatmap.NewSeatMapStore(app.db), order.NewCacheStore(app.rdb), flight.NewCacheStore(app.rdb), agency.NewCacheStore(app.rdb), app.broker, rules)
+}
+
+func (app *App) newCustomerService() *customer.CustomerService {
+	return customer.NewCustomerService(customer.NewCustomerStore(app.db))
+}
+
+func (app *App) newAgencyService() *agency.AgencyService {
+	return agency.NewAgencyService(agency.NewAgencyStore(app.db), agency.NewCacheStore(app.rdb),
+		time.Duration(app.config.ApiKeyCacheSeconds)*time.Second)
+}
+
+// newAbuseService: nil when abuse screening is disabled, order handlers then skip screening
+func (app *App) newAbuseService() types.AbuseService {
+	if !app.config.AbuseEnabled {
+		return nil
+	}
+	signals, err := abuse.ParseSignalRules(app.config.AbuseRules)
+	if err != nil {
+		util.FailOnError(err, "failed to parse abuse rules")
+	}
+	return abuse.NewAbuseService(abuse.NewAbuseStore(app.db), abuse.NewCacheStore(app.rdb), abuse.Rules{
+		Signals:        signals,
+		Window:         time.Duration(app.config.AbuseWindowMinutes) * time.Minute,
+		UnpaidHold:     time.Duration(app.config.AbuseUnpaidHoldMinutes) * time.Minute,
+		FlagScore:      app.config.AbuseFlagScore,
+		ChallengeScore: app.config.AbuseChallengeScore,
+		BlockScore:     app.config.AbuseBlockScore,
+		ChallengeBits:  app.config.AbuseChallengeBits,
+	})
 }
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+var (
+	ErrAuthenticationRequired = apperr.New(apperr.Unauthorized, "authentication required")
+	ErrForbidden              = apperr.New(apperr.Forbidden, "permission denied")
+)
+
+/*
+*
+Authenticate: attach principal of bearer token, request without bearer token passes through as anonymous
+*/
+func Authenticate(verifier *Verifier) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		header := ctx.GetHeader("Authorization")
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			ctx.Next()
+			return
+		}
+		principal, err := verifier.Verify(strings.TrimSpace(token))
+		if err != nil {
+			ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			util.WriteError(ctx.Writer, http.StatusUnauthorized, err)
+			ctx.Abort()
+			return
+		}
+		SetPrincipal(ctx, principal)
+		ctx.Next()
+	}
+}
+
+// RequireRole: reject anonymous request with 401 and principal without role with 403
+func RequireRole(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := GetPrincipal(ctx)
+		if !ok {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			util.WriteError(ctx.Writer, http.StatusUnauthorized, ErrAuthenticationRequired)
+			ctx.Abort()
+			return
+		}
+		if !principal.HasRole(role) {
+			util.WriteError(ctx.Writer, http.StatusForbidden, fmt.Errorf("%w: %s role required", ErrForbidden, role))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireRoleForWrites: reads stay public, other methods need role
+func RequireRoleForWrites(role string) gin.HandlerFunc {
+	requireRole := RequireRole(role)
+	return func(ctx *gin.Context) {
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			ctx.Next()
+		default:
+			requireRole(ctx)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleCustomer = "customer"
+	// travel agency authenticated with api key
+	RoleAgency = "agency"
+)
+
+// principalKey: gin context key of authenticated principal
+const principalKey = "auth_principal"
+
+// Principal: subject and roles of authenticated caller
+type Principal struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+func (principal Principal) HasRole(role string) bool {
+	return slices.Contains(principal.Roles, role)
+}
+
+func SetPrincipal(ctx *gin.Context, principal Principal) {
+	ctx.Set(principalKey, principal)
+}
+
+// GetPrincipal: principal of request, false for anonymous request
+func GetPrincipal(ctx *gin.Context) (Principal, bool) {
+	value, ok := ctx.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := value.(Principal)
+	return principal, ok
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/config"
+)
+
+var (
+	ErrInvalidToken            = apperr.New(apperr.Unauthorized, "invalid token")
+	ErrTokenIssuingUnavailable = apperr.New(apperr.NotImplemented, "token issuing requires HS256 with JWT_SECRET")
+)
+
+// leeway: clock skew tolerated on exp, nbf and iat
+const leeway = 30 * time.Second
+
+// verify bearer JWT with configured algorithm and keys, issue customer tokens when secret is shared
+type Verifier struct {
+	algorithm  string
+	secret     []byte
+	publicKey  *rsa.PublicKey
+	jwks       map[string]*rsa.PublicKey
+	issuer     string
+	audience   string
+	rolesClaim string
+	tokenTTL   time.Duration
+	parser     *jwt.Parser
+}
+
+/*
+*
+NewVerifier: HS256 with JwtSecret or RS256 with JwtPublicKeyFile and JwtJWKSFile,
+every bearer token is rejected when no key is configured
+*/
+func NewVerifier(config *config.Config) (*Verifier, error) {
+	verifier := &Verifier{
+		algorithm:  strings.ToUpper(config.JwtAlgorithm),
+		issuer:     config.JwtIssuer,
+		audience:   config.JwtAudience,
+		rolesClaim: config.JwtRolesClaim,
+		tokenTTL:   time.Duration(config.JwtTokenTTLMinutes) * time.Minute,
+	}
+	switch verifier.algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		verifier.secret = []byte(config.JwtSecret)
+		if len(verifier.secret) == 0 {
+			log.Println("JWT_SECRET not set, bearer tokens are rejected")
+		}
+	case jwt.SigningMethodRS256.Alg():
+		if config.JwtPublicKeyFile != "" {
+			pemData, err := os.ReadFile(config.JwtPublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read jwt public key file %w", err)
+			}
+			verifier.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pemData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse jwt public key %w", err)
+			}
+		}
+		if config.JwtJWKSFile != "" {
+			jwks, err := loadJWKS(config.JwtJWKSFile)
+			if err != nil {
+				return nil, err
+			}
+			verifier.jwks = jwks
+		}
+		if verifier.publicKey == nil && len(verifier.jwks) == 0 {
+			log.Println("JWT_PUBLIC_KEY_FILE and JWT_JWKS_FILE not set, bearer tokens are rejected")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %s", config.JwtAlgorithm)
+	}
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{verifier.algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(leeway),
+	}
+	if verifier.issuer != "" {
+		options = append(options, jwt.WithIssuer(verifier.issuer))
+	}
+	if verifier.audience != "" {
+		options = append(options, jwt.WithAudience(verifier.audience))
+	}
+	verifier.parser = jwt.NewParser(options...)
+	return verifier, nil
+}
+
+// keyFunc: verification key of token, RS256 key is selected by kid when jwks is configured
+func (verifier *Verifier) keyFunc(token *jwt.Token) (any, error) {
+	if verifier.algorithm == jwt.SigningMethodHS256.Alg() {
+		if len(verifier.secret) == 0 {
+			return nil, fmt.Errorf("no verification key configured")
+		}
+		return verifier.secret, nil
+	}
+	if keyID, ok := token.Header["kid"].(string); ok && keyID != "" {
+		if key, ok := verifier.jwks[keyID]; ok {
+			return key, nil
+		}
+		if verifier.publicKey == nil {
+			return nil, fmt.Errorf("unknown kid %s", keyID)
+		}
+	}
+	if verifier.publicKey != nil {
+		return verifier.publicKey, nil
+	}
+	// token without kid is accepted only when jwks holds single key
+	if len(verifier.jwks) == 1 {
+		for _, key := range verifier.jwks {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("no verification key for token")
+}
+
+/*
+*
+Verify: principal of valid token, subject is required, roles claim is array or space separated string
+*/
+func (verifier *Verifier) Verify(tokenString string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := verifier.parser.ParseWithClaims(tokenString, claims, verifier.keyFunc); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+	principal := Principal{Subject: subject, Roles: []string{}}
+	switch roles := claims[verifier.rolesClaim].(type) {
+	case string:
+		principal.Roles = strings.Fields(roles)
+	case []any:
+		for _, role := range roles {
+			if roleName, ok := role.(string); ok {
+				principal.Roles = append(principal.Roles, roleName)
+			}
+		}
+	}
+	return principal, nil
+}
+
+/*
+*
+IssueToken: HS256 token of subject and roles valid for JwtTokenTTLMinutes
+*/
+func (verifier *Verifier) IssueToken(subject string, roles []string) (string, time.Duration, error) {
+	if verifier.algorithm != jwt.SigningMethodHS256.Alg() || len(verifier.secret) == 0 {
+		return "", 0, ErrTokenIssuingUnavailable
+	}
+	now := time.Now().UTC()
+	claims := jwt.MapClaims{
+		"sub":               subject,
+		verifier.rolesClaim: roles,
+		"iat":               now.Unix(),
+		"exp":               now.Add(verifier.tokenTTL).Unix(),
+	}
+	if verifier.issuer != "" {
+		claims["iss"] = verifier.issuer
+	}
+	if verifier.audience != "" {
+		claims["aud"] = verifier.audience
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(verifier.secret)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to sign token %w", err)
+	}
+	return token, verifier.tokenTTL, nil
+}
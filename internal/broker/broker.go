@@ -8,8 +8,16 @@ import (
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/metrics"
 )
 
+// ErrUnavailable: message could not be published, booking should be retried later
+var ErrUnavailable = apperr.New(apperr.Unavailable, "message broker unavailable")
+
+// PublishedAtHeader: header with publish time of message in unix milliseconds
+const PublishedAtHeader = "x-published-at-ms"
+
 type Broker struct {
 	uri             string
 	consumer_conn   *amqp.Connection
@@ -170,17 +178,24 @@ func (broker *Broker) GenerateDeliveryChannel(ctx context.Context, qName string)
 	}
 	return msgch, err
 }
-func (broker *Broker) SendMessageToQueue(ctx context.Context, qName string, data []byte) error {
+func (broker *Broker) SendMessageToQueue(ctx context.Context, qName string, data []byte) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.BrokerPublishDuration.WithLabelValues(qName).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.BrokerPublishFailures.WithLabelValues(qName).Inc()
+		}
+	}()
 	broker.publisher_mutex.Lock()
 	defer broker.publisher_mutex.Unlock()
 	if broker.publisher_ch == nil || broker.publisher_ch.IsClosed() {
 		if err := broker.HandlePublisherConnectCh(); err != nil {
-			return err
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
 		}
 	}
 	queue, err := broker.publisher_ch.QueueDeclare(qName, false, false, false, false, nil)
 	if err != nil {
-		return fmt.Errorf("declare queue failed with %s %w", qName, err)
+		return fmt.Errorf("%w: declare queue failed with %s %w", ErrUnavailable, qName, err)
 	}
 	// setup timeout for send queue
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -194,12 +209,15 @@ func (broker *Broker) SendMessageToQueue(ctx context.Context, qName string, data
 		amqp.Publishing{
 			ContentType: "application/json",
 			Body:        []byte(data),
+			// timestamp has second precision, consumers measure queue lag from PublishedAtHeader
+			Timestamp: time.Now().UTC(),
+			Headers:   amqp.Table{PublishedAtHeader: time.Now().UnixMilli()},
 		},
 	)
 	if err != nil {
 		log.Printf("failed to publish message to queue %v\n", err)
 		defer broker.publisher_ch.Close()
-		return fmt.Errorf("failed to publish message to queue %w", err)
+		return fmt.Errorf("%w: failed to publish message to queue %w", ErrUnavailable, err)
 	}
 	return nil
 }
@@ -234,3 +252,21 @@ func (broker *Broker) Close() error {
 	}
 	return nil
 }
+
+/*
+*
+PublishedAt: publish time of message in milliseconds from PublishedAtHeader,
+second precision timestamp for messages published without header, false when neither is set
+*/
+func PublishedAt(msg amqp.Delivery) (time.Time, bool) {
+	switch publishedAt := msg.Headers[PublishedAtHeader].(type) {
+	case int64:
+		return time.UnixMilli(publishedAt), true
+	case int32:
+		return time.UnixMilli(int64(publishedAt)), true
+	}
+	if msg.Timestamp.IsZero() {
+		return time.Time{}, false
+	}
+	return msg.Timestamp, true
+}
@@ -14,6 +14,74 @@ type Config struct {
 	DbURL          string `mapstructure:"DB_URL"`
 	RabbitMQURL    string `mapstructure:"RABBITMQ_URL"`
 	OrderQueueName string `mapstructure:"ORDER_QUEUE_NAME"`
+	AirlinePrefix  string `mapstructure:"AIRLINE_PREFIX"`
+	CarrierCode    string `mapstructure:"CARRIER_CODE"`
+	// prometheus scrape endpoint /metrics listens on MetricsPort apart from public api
+	MetricsPort string `mapstructure:"METRICS_PORT"`
+	// check-in window opens CheckinOpenHours and closes CheckinCloseMinutes before flight_date
+	CheckinOpenHours    int64 `mapstructure:"CHECKIN_OPEN_HOURS"`
+	CheckinCloseMinutes int64 `mapstructure:"CHECKIN_CLOSE_MINUTES"`
+	// denied boarding runs GateCloseMinutes before flight_date
+	GateCloseMinutes                     int64   `mapstructure:"GATE_CLOSE_MINUTES"`
+	GateCloseIntervalSeconds             int64   `mapstructure:"GATE_CLOSE_INTERVAL_SECONDS"`
+	DeniedBoardingSelection              string  `mapstructure:"DENIED_BOARDING_SELECTION"`
+	DeniedBoardingVolunteerCompensation  float64 `mapstructure:"DENIED_BOARDING_VOLUNTEER_COMPENSATION"`
+	DeniedBoardingShortDelayCompensation float64 `mapstructure:"DENIED_BOARDING_SHORT_DELAY_COMPENSATION"`
+	DeniedBoardingLongDelayCompensation  float64 `mapstructure:"DENIED_BOARDING_LONG_DELAY_COMPENSATION"`
+	DeniedBoardingLongDelayHours         int64   `mapstructure:"DENIED_BOARDING_LONG_DELAY_HOURS"`
+	// overbooking recompute worker adjusts flights departing within OverbookingRecomputeHorizonHours
+	OverbookingRecomputeHorizonHours    int64 `mapstructure:"OVERBOOKING_RECOMPUTE_HORIZON_HOURS"`
+	OverbookingRecomputeIntervalSeconds int64 `mapstructure:"OVERBOOKING_RECOMPUTE_INTERVAL_SECONDS"`
+	// no-show stats worker records outcomes of departed flights every NoShowStatsIntervalSeconds
+	NoShowStatsIntervalSeconds int64 `mapstructure:"NOSHOW_STATS_INTERVAL_SECONDS"`
+	// schedule generator keeps flights materialized ScheduleGenerateDays ahead
+	ScheduleGenerateDays            int64 `mapstructure:"SCHEDULE_GENERATE_DAYS"`
+	ScheduleGenerateIntervalSeconds int64 `mapstructure:"SCHEDULE_GENERATE_INTERVAL_SECONDS"`
+	// connecting itineraries need ConnectionMinMinutes to ConnectionMaxMinutes between arrival and next departure
+	ConnectionMinMinutes int64 `mapstructure:"CONNECTION_MIN_MINUTES"`
+	ConnectionMaxMinutes int64 `mapstructure:"CONNECTION_MAX_MINUTES"`
+	// seat counter keys are prefixed with {InventoryHashTag} so multi-flight scripts stay in one redis cluster slot,
+	// empty keeps plain flight_id keys of a single redis node, set it only on a fresh inventory since counters move to new keys
+	InventoryHashTag string `mapstructure:"INVENTORY_HASH_TAG"`
+	// fare calendar responses are cached FareCalendarCacheSeconds, at least one second
+	FareCalendarCacheSeconds int64 `mapstructure:"FARE_CALENDAR_CACHE_SECONDS"`
+	// flight search pages are cached FlightSearchCacheSeconds, 0 disables search cache
+	FlightSearchCacheSeconds int64 `mapstructure:"FLIGHT_SEARCH_CACHE_SECONDS"`
+	// bearer tokens are JWT signed with JwtAlgorithm, HS256 uses JwtSecret,
+	// RS256 uses public key of JwtPublicKeyFile or keys of local JwtJWKSFile selected by kid
+	JwtAlgorithm     string `mapstructure:"JWT_ALGORITHM"`
+	JwtSecret        string `mapstructure:"JWT_SECRET"`
+	JwtPublicKeyFile string `mapstructure:"JWT_PUBLIC_KEY_FILE"`
+	JwtJWKSFile      string `mapstructure:"JWT_JWKS_FILE"`
+	// iss and aud are only checked when configured
+	JwtIssuer     string `mapstructure:"JWT_ISSUER"`
+	JwtAudience   string `mapstructure:"JWT_AUDIENCE"`
+	JwtRolesClaim string `mapstructure:"JWT_ROLES_CLAIM"`
+	// customer tokens issued with HS256 expire after JwtTokenTTLMinutes
+	JwtTokenTTLMinutes int64 `mapstructure:"JWT_TOKEN_TTL_MINUTES"`
+	// agency api keys are cached ApiKeyCacheSeconds, revoked keys are evicted immediately
+	ApiKeyCacheSeconds int64 `mapstructure:"API_KEY_CACHE_SECONDS"`
+	// rate limits per route, "METHOD /path=scope:limit/unit;..." separated by comma,
+	// scope is ip, customer or api_key, unit is s, m or h, "*" applies to routes without own rule, empty disables
+	RateLimitRules string `mapstructure:"RATE_LIMIT_RULES"`
+	// client ip is taken from X-Forwarded-For only behind TrustedProxies, comma separated ips or cidrs,
+	// empty trusts no proxy and uses remote address
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+	// gatekeeper admits queued waiting room tokens every WaitingRoomTickMilliseconds,
+	// admitted tokens could order within WaitingRoomAdmissionSeconds
+	WaitingRoomTickMilliseconds int64 `mapstructure:"WAITING_ROOM_TICK_MILLISECONDS"`
+	WaitingRoomAdmissionSeconds int64 `mapstructure:"WAITING_ROOM_ADMISSION_SECONDS"`
+	// order requests are scored by AbuseRules "signal:limit:weight,...", a signal reaching limit adds weight,
+	// counted within AbuseWindowMinutes, orders unpaid AbuseUnpaidHoldMinutes after creation count as unpaid_expired
+	AbuseEnabled           bool   `mapstructure:"ABUSE_ENABLED"`
+	AbuseRules             string `mapstructure:"ABUSE_RULES"`
+	AbuseWindowMinutes     int64  `mapstructure:"ABUSE_WINDOW_MINUTES"`
+	AbuseUnpaidHoldMinutes int64  `mapstructure:"ABUSE_UNPAID_HOLD_MINUTES"`
+	// score reaching AbuseFlagScore is flagged, AbuseChallengeScore needs proof of work of AbuseChallengeBits, AbuseBlockScore is blocked
+	AbuseFlagScore      int64 `mapstructure:"ABUSE_FLAG_SCORE"`
+	AbuseChallengeScore int64 `mapstructure:"ABUSE_CHALLENGE_SCORE"`
+	AbuseBlockScore     int64 `mapstructure:"ABUSE_BLOCK_SCORE"`
+	AbuseChallengeBits  int64 `mapstructure:"ABUSE_CHALLENGE_BITS"`
 }
 
 var AppConfig *Config
@@ -25,11 +93,99 @@ func init() {
 	v.SetConfigType("env")
 	v.AutomaticEnv()
 	util.FailOnError(v.BindEnv("PORT"), "Failed on Bind PORT")
+	util.FailOnError(v.BindEnv("METRICS_PORT"), "Failed on Bind METRICS_PORT")
+	v.SetDefault("METRICS_PORT", "9090")
 	util.FailOnError(v.BindEnv("REDIS_URL"), "Failed on Bind REDIS_URL")
 	util.FailOnError(v.BindEnv("GIN_MODE"), "Failed on Bind GIN_MODE")
 	util.FailOnError(v.BindEnv("DB_URL"), "Failed on Bind DB_URL")
 	util.FailOnError(v.BindEnv("RABBITMQ_URL"), "Failed on Bind RABBITMQ_URL")
 	util.FailOnError(v.BindEnv("ORDER_QUEUE_NAME"), "Failed on ORDER_QUEUE_NAME")
+	util.FailOnError(v.BindEnv("AIRLINE_PREFIX"), "Failed on Bind AIRLINE_PREFIX")
+	v.SetDefault("AIRLINE_PREFIX", "999")
+	util.FailOnError(v.BindEnv("CARRIER_CODE"), "Failed on Bind CARRIER_CODE")
+	v.SetDefault("CARRIER_CODE", "ZZ")
+	util.FailOnError(v.BindEnv("CHECKIN_OPEN_HOURS"), "Failed on Bind CHECKIN_OPEN_HOURS")
+	v.SetDefault("CHECKIN_OPEN_HOURS", 48)
+	util.FailOnError(v.BindEnv("CHECKIN_CLOSE_MINUTES"), "Failed on Bind CHECKIN_CLOSE_MINUTES")
+	v.SetDefault("CHECKIN_CLOSE_MINUTES", 60)
+	util.FailOnError(v.BindEnv("GATE_CLOSE_MINUTES"), "Failed on Bind GATE_CLOSE_MINUTES")
+	v.SetDefault("GATE_CLOSE_MINUTES", 15)
+	util.FailOnError(v.BindEnv("GATE_CLOSE_INTERVAL_SECONDS"), "Failed on Bind GATE_CLOSE_INTERVAL_SECONDS")
+	v.SetDefault("GATE_CLOSE_INTERVAL_SECONDS", 60)
+	util.FailOnError(v.BindEnv("DENIED_BOARDING_SELECTION"), "Failed on Bind DENIED_BOARDING_SELECTION")
+	v.SetDefault("DENIED_BOARDING_SELECTION", "last_checkin")
+	util.FailOnError(v.BindEnv("DENIED_BOARDING_VOLUNTEER_COMPENSATION"), "Failed on Bind DENIED_BOARDING_VOLUNTEER_COMPENSATION")
+	v.SetDefault("DENIED_BOARDING_VOLUNTEER_COMPENSATION", 200)
+	util.FailOnError(v.BindEnv("DENIED_BOARDING_SHORT_DELAY_COMPENSATION"), "Failed on Bind DENIED_BOARDING_SHORT_DELAY_COMPENSATION")
+	v.SetDefault("DENIED_BOARDING_SHORT_DELAY_COMPENSATION", 300)
+	util.FailOnError(v.BindEnv("DENIED_BOARDING_LONG_DELAY_COMPENSATION"), "Failed on Bind DENIED_BOARDING_LONG_DELAY_COMPENSATION")
+	v.SetDefault("DENIED_BOARDING_LONG_DELAY_COMPENSATION", 600)
+	util.FailOnError(v.BindEnv("DENIED_BOARDING_LONG_DELAY_HOURS"), "Failed on Bind DENIED_BOARDING_LONG_DELAY_HOURS")
+	v.SetDefault("DENIED_BOARDING_LONG_DELAY_HOURS", 4)
+	util.FailOnError(v.BindEnv("OVERBOOKING_RECOMPUTE_HORIZON_HOURS"), "Failed on Bind OVERBOOKING_RECOMPUTE_HORIZON_HOURS")
+	v.SetDefault("OVERBOOKING_RECOMPUTE_HORIZON_HOURS", 72)
+	util.FailOnError(v.BindEnv("OVERBOOKING_RECOMPUTE_INTERVAL_SECONDS"), "Failed on Bind OVERBOOKING_RECOMPUTE_INTERVAL_SECONDS")
+	v.SetDefault("OVERBOOKING_RECOMPUTE_INTERVAL_SECONDS", 300)
+	util.FailOnError(v.BindEnv("NOSHOW_STATS_INTERVAL_SECONDS"), "Failed on Bind NOSHOW_STATS_INTERVAL_SECONDS")
+	v.SetDefault("NOSHOW_STATS_INTERVAL_SECONDS", 3600)
+	util.FailOnError(v.BindEnv("SCHEDULE_GENERATE_DAYS"), "Failed on Bind SCHEDULE_GENERATE_DAYS")
+	v.SetDefault("SCHEDULE_GENERATE_DAYS", 60)
+	util.FailOnError(v.BindEnv("SCHEDULE_GENERATE_INTERVAL_SECONDS"), "Failed on Bind SCHEDULE_GENERATE_INTERVAL_SECONDS")
+	v.SetDefault("SCHEDULE_GENERATE_INTERVAL_SECONDS", 3600)
+	util.FailOnError(v.BindEnv("CONNECTION_MIN_MINUTES"), "Failed on Bind CONNECTION_MIN_MINUTES")
+	v.SetDefault("CONNECTION_MIN_MINUTES", 45)
+	util.FailOnError(v.BindEnv("CONNECTION_MAX_MINUTES"), "Failed on Bind CONNECTION_MAX_MINUTES")
+	v.SetDefault("CONNECTION_MAX_MINUTES", 720)
+	util.FailOnError(v.BindEnv("INVENTORY_HASH_TAG"), "Failed on Bind INVENTORY_HASH_TAG")
+	v.SetDefault("INVENTORY_HASH_TAG", "")
+	util.FailOnError(v.BindEnv("FARE_CALENDAR_CACHE_SECONDS"), "Failed on Bind FARE_CALENDAR_CACHE_SECONDS")
+	v.SetDefault("FARE_CALENDAR_CACHE_SECONDS", 60)
+	util.FailOnError(v.BindEnv("FLIGHT_SEARCH_CACHE_SECONDS"), "Failed on Bind FLIGHT_SEARCH_CACHE_SECONDS")
+	v.SetDefault("FLIGHT_SEARCH_CACHE_SECONDS", 15)
+	util.FailOnError(v.BindEnv("JWT_ALGORITHM"), "Failed on Bind JWT_ALGORITHM")
+	v.SetDefault("JWT_ALGORITHM", "HS256")
+	util.FailOnError(v.BindEnv("JWT_SECRET"), "Failed on Bind JWT_SECRET")
+	v.SetDefault("JWT_SECRET", "")
+	util.FailOnError(v.BindEnv("JWT_PUBLIC_KEY_FILE"), "Failed on Bind JWT_PUBLIC_KEY_FILE")
+	v.SetDefault("JWT_PUBLIC_KEY_FILE", "")
+	util.FailOnError(v.BindEnv("JWT_JWKS_FILE"), "Failed on Bind JWT_JWKS_FILE")
+	v.SetDefault("JWT_JWKS_FILE", "")
+	util.FailOnError(v.BindEnv("JWT_ISSUER"), "Failed on Bind JWT_ISSUER")
+	v.SetDefault("JWT_ISSUER", "")
+	util.FailOnError(v.BindEnv("JWT_AUDIENCE"), "Failed on Bind JWT_AUDIENCE")
+	v.SetDefault("JWT_AUDIENCE", "")
+	util.FailOnError(v.BindEnv("JWT_ROLES_CLAIM"), "Failed on Bind JWT_ROLES_CLAIM")
+	v.SetDefault("JWT_ROLES_CLAIM", "roles")
+	util.FailOnError(v.BindEnv("JWT_TOKEN_TTL_MINUTES"), "Failed on Bind JWT_TOKEN_TTL_MINUTES")
+	v.SetDefault("JWT_TOKEN_TTL_MINUTES", 60)
+	util.FailOnError(v.BindEnv("API_KEY_CACHE_SECONDS"), "Failed on Bind API_KEY_CACHE_SECONDS")
+	v.SetDefault("API_KEY_CACHE_SECONDS", 300)
+	util.FailOnError(v.BindEnv("RATE_LIMIT_RULES"), "Failed on Bind RATE_LIMIT_RULES")
+	v.SetDefault("RATE_LIMIT_RULES", "*=ip:600/m;customer:300/m;api_key:1200/m,"+
+		"POST /orders/=ip:30/m;customer:10/m;api_key:300/m,"+
+		"POST /itineraries/orders=ip:30/m;customer:10/m;api_key:300/m")
+	util.FailOnError(v.BindEnv("TRUSTED_PROXIES"), "Failed on Bind TRUSTED_PROXIES")
+	v.SetDefault("TRUSTED_PROXIES", "")
+	util.FailOnError(v.BindEnv("WAITING_ROOM_TICK_MILLISECONDS"), "Failed on Bind WAITING_ROOM_TICK_MILLISECONDS")
+	v.SetDefault("WAITING_ROOM_TICK_MILLISECONDS", 200)
+	util.FailOnError(v.BindEnv("WAITING_ROOM_ADMISSION_SECONDS"), "Failed on Bind WAITING_ROOM_ADMISSION_SECONDS")
+	v.SetDefault("WAITING_ROOM_ADMISSION_SECONDS", 300)
+	util.FailOnError(v.BindEnv("ABUSE_ENABLED"), "Failed on Bind ABUSE_ENABLED")
+	v.SetDefault("ABUSE_ENABLED", true)
+	util.FailOnError(v.BindEnv("ABUSE_RULES"), "Failed on Bind ABUSE_RULES")
+	v.SetDefault("ABUSE_RULES", "velocity:10:40,flight_spread:4:30,unpaid_expired:3:40,identical_passengers:3:30")
+	util.FailOnError(v.BindEnv("ABUSE_WINDOW_MINUTES"), "Failed on Bind ABUSE_WINDOW_MINUTES")
+	v.SetDefault("ABUSE_WINDOW_MINUTES", 60)
+	util.FailOnError(v.BindEnv("ABUSE_UNPAID_HOLD_MINUTES"), "Failed on Bind ABUSE_UNPAID_HOLD_MINUTES")
+	v.SetDefault("ABUSE_UNPAID_HOLD_MINUTES", 30)
+	util.FailOnError(v.BindEnv("ABUSE_FLAG_SCORE"), "Failed on Bind ABUSE_FLAG_SCORE")
+	v.SetDefault("ABUSE_FLAG_SCORE", 30)
+	util.FailOnError(v.BindEnv("ABUSE_CHALLENGE_SCORE"), "Failed on Bind ABUSE_CHALLENGE_SCORE")
+	v.SetDefault("ABUSE_CHALLENGE_SCORE", 60)
+	util.FailOnError(v.BindEnv("ABUSE_BLOCK_SCORE"), "Failed on Bind ABUSE_BLOCK_SCORE")
+	v.SetDefault("ABUSE_BLOCK_SCORE", 100)
+	util.FailOnError(v.BindEnv("ABUSE_CHALLENGE_BITS"), "Failed on Bind ABUSE_CHALLENGE_BITS")
+	v.SetDefault("ABUSE_CHALLENGE_BITS", 20)
 	err := v.ReadInConfig()
 	if err != nil {
 		log.Println("Load from environment variable")
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+const namespace = "aos"
+
+// outcomes of order scripts
+const (
+	OutcomeValid    = "valid"
+	OutcomeWait     = "wait"
+	OutcomeRejected = "rejected"
+)
+
+// outcomes of order worker messages, besides OutcomeRejected
+const (
+	OutcomeAcked    = "acked"
+	OutcomeRequeued = "requeued"
+)
+
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of http requests by gin route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	HTTPRequestSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_size_bytes",
+		Help:      "Body size of http requests by gin route.",
+		Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+	}, []string{"method", "route"})
+	HTTPResponseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "response_size_bytes",
+		Help:      "Body size of http responses by gin route.",
+		Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+	}, []string{"method", "route"})
+	OrderScriptOutcomes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "order",
+		Name:      "script_outcomes_total",
+		Help:      "Outcomes of order lua scripts by flight, valid, wait or rejected.",
+	}, []string{"script", "flight_id", "outcome"})
+	BrokerPublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "broker",
+		Name:      "publish_duration_seconds",
+		Help:      "Latency of publishing messages to queue.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"queue"})
+	BrokerPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "broker",
+		Name:      "publish_failures_total",
+		Help:      "Messages failed to publish to queue.",
+	}, []string{"queue"})
+	OrderWorkerQueueLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "order_worker",
+		Name:      "queue_lag_seconds",
+		Help:      "Time create order events wait in queue before order worker takes them.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 16),
+	})
+	OrderWorkerProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "order_worker",
+		Name:      "processing_duration_seconds",
+		Help:      "Time order worker spends on create order event, acked, rejected or requeued.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+)
+
+const (
+	// flightSeriesIdle: series of flight without order script for this long are deleted, departed flights take no orders
+	flightSeriesIdle = 24 * time.Hour
+	// flightSeriesSweep: idle flight series are looked for at most this often
+	flightSeriesSweep = 10 * time.Minute
+)
+
+// flightSeries: last order script of each flight with series in OrderScriptOutcomes
+var flightSeries = struct {
+	sync.Mutex
+	lastSeen  map[string]time.Time
+	lastSweep time.Time
+}{lastSeen: make(map[string]time.Time)}
+
+/*
+*
+ObserveOrderScript: count outcome of order script on flight, rejected when seats are insufficient,
+series of flights idle for flightSeriesIdle are deleted so flight_id label stays bounded by flights on sale
+*/
+func ObserveOrderScript(script string, flightID string, isValid bool, isWait bool) {
+	outcome := OutcomeValid
+	switch {
+	case !isValid:
+		outcome = OutcomeRejected
+	case isWait:
+		outcome = OutcomeWait
+	}
+	OrderScriptOutcomes.WithLabelValues(script, flightID, outcome).Inc()
+	now := time.Now()
+	flightSeries.Lock()
+	defer flightSeries.Unlock()
+	flightSeries.lastSeen[flightID] = now
+	if now.Sub(flightSeries.lastSweep) < flightSeriesSweep {
+		return
+	}
+	flightSeries.lastSweep = now
+	for idleFlightID, lastSeen := range flightSeries.lastSeen {
+		if now.Sub(lastSeen) >= flightSeriesIdle {
+			OrderScriptOutcomes.DeletePartialMatch(prometheus.Labels{"flight_id": idleFlightID})
+			delete(flightSeries.lastSeen, idleFlightID)
+		}
+	}
+}
+
+// Register: collect pool stats of database and redis
+func Register(db *sql.DB, rdb *redis.Client) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"), newRedisPoolCollector(rdb))
+}
+
+// Handler: exposition of default registry for /metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
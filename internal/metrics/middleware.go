@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/*
+*
+Instrument: observe latency and sizes of requests by gin route, requests matching no route share route "unmatched"
+*/
+func Instrument() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := ctx.Request.Method
+		HTTPRequestDuration.WithLabelValues(method, route, strconv.Itoa(ctx.Writer.Status())).Observe(time.Since(start).Seconds())
+		HTTPRequestSize.WithLabelValues(method, route).Observe(float64(max(ctx.Request.ContentLength, 0)))
+		HTTPResponseSize.WithLabelValues(method, route).Observe(float64(max(ctx.Writer.Size(), 0)))
+	}
+}
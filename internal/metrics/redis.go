@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPoolCollector: connection pool stats of redis client, read on every scrape
+type redisPoolCollector struct {
+	rdb        *redis.Client
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+func newRedisPoolCollector(rdb *redis.Client) *redisPoolCollector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "redis_pool", name), help, nil, nil)
+	}
+	return &redisPoolCollector{
+		rdb:        rdb,
+		hits:       desc("hits_total", "Times free connection was found in pool."),
+		misses:     desc("misses_total", "Times free connection was not found in pool."),
+		timeouts:   desc("timeouts_total", "Times waiting for connection timed out."),
+		totalConns: desc("connections", "Connections in pool."),
+		idleConns:  desc("idle_connections", "Idle connections in pool."),
+		staleConns: desc("stale_connections_total", "Stale connections removed from pool."),
+	}
+}
+
+func (collector *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.hits
+	ch <- collector.misses
+	ch <- collector.timeouts
+	ch <- collector.totalConns
+	ch <- collector.idleConns
+	ch <- collector.staleConns
+}
+
+func (collector *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := collector.rdb.PoolStats()
+	ch <- prometheus.MustNewConstMetric(collector.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(collector.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(collector.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(collector.totalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(collector.idleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(collector.staleConns, prometheus.CounterValue, float64(stats.StaleConns))
+}
@@ -0,0 +1,166 @@
+package abuse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// maxOrderHistory: orders kept per actor for unpaid_expired signal
+const maxOrderHistory = 100
+
+type CacheStore struct {
+	rdb *redis.Client
+}
+
+func NewCacheStore(rdb *redis.Client) *CacheStore {
+	return &CacheStore{
+		rdb: rdb,
+	}
+}
+
+func orderHistoryKey(actor string) string {
+	return fmt.Sprintf("abuse:orders:%s", actor)
+}
+
+/*
+*
+TrackAttempt: count order attempt for every actor and passenger list, return highest counts within window,
+every counter is its own key so scripts never span slots
+*/
+func (cache *CacheStore) TrackAttempt(ctx context.Context, attemptParam types.AbuseAttemptParam) (types.AbuseAttemptResult, error) {
+	window := attemptParam.Window.Milliseconds()
+	flightArgs := make([]interface{}, 0, 1+len(attemptParam.FlightIDs))
+	flightArgs = append(flightArgs, window)
+	for _, flightID := range attemptParam.FlightIDs {
+		flightArgs = append(flightArgs, flightID)
+	}
+	// scripts are sent with EVAL in pipeline, EVALSHA fallback of Run does not work there
+	pipe := cache.rdb.Pipeline()
+	attempts := make([]*redis.Cmd, 0, len(attemptParam.Actors))
+	flights := make([]*redis.Cmd, 0, len(attemptParam.Actors))
+	for _, actor := range attemptParam.Actors {
+		attempts = append(attempts, TrackWindowMembers.Eval(ctx, pipe,
+			[]string{fmt.Sprintf("abuse:attempts:%s", actor)}, window, attemptParam.AttemptID))
+		flights = append(flights, TrackWindowMembers.Eval(ctx, pipe,
+			[]string{fmt.Sprintf("abuse:flights:%s", actor)}, flightArgs...))
+	}
+	passengers := TrackWindowMembers.Eval(ctx, pipe,
+		[]string{fmt.Sprintf("abuse:passengers:%s", attemptParam.PassengersHash)}, window, attemptParam.AttemptID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return types.AbuseAttemptResult{}, fmt.Errorf("failed to track order attempt %w", err)
+	}
+	var result types.AbuseAttemptResult
+	for index := range attempts {
+		attemptCount, err := attempts[index].Int64()
+		if err != nil {
+			return types.AbuseAttemptResult{}, fmt.Errorf("failed to read order attempts %w", err)
+		}
+		flightCount, err := flights[index].Int64()
+		if err != nil {
+			return types.AbuseAttemptResult{}, fmt.Errorf("failed to read ordered flights %w", err)
+		}
+		result.Attempts = max(result.Attempts, attemptCount)
+		result.Flights = max(result.Flights, flightCount)
+	}
+	identical, err := passengers.Int64()
+	if err != nil {
+		return types.AbuseAttemptResult{}, fmt.Errorf("failed to read identical passengers %w", err)
+	}
+	result.IdenticalPassengers = identical
+	return result, nil
+}
+
+// AddOrders: remember accepted orders of actors for history, most recent maxOrderHistory orders are kept
+func (cache *CacheStore) AddOrders(ctx context.Context, actors []string, orderIDs []string, history time.Duration) error {
+	now := time.Now().UTC()
+	members := make([]redis.Z, 0, len(orderIDs))
+	for _, orderID := range orderIDs {
+		members = append(members, redis.Z{Score: float64(now.UnixMilli()), Member: orderID})
+	}
+	pipe := cache.rdb.Pipeline()
+	for _, actor := range actors {
+		key := orderHistoryKey(actor)
+		pipe.ZAdd(ctx, key, members...)
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-history).UnixMilli(), 10))
+		pipe.ZRemRangeByRank(ctx, key, 0, -maxOrderHistory-1)
+		pipe.Expire(ctx, key, history)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to add order history %w", err)
+	}
+	return nil
+}
+
+// GetOrders: orders of actors created before createdBefore, each order once
+func (cache *CacheStore) GetOrders(ctx context.Context, actors []string, createdBefore time.Time) ([]string, error) {
+	pipe := cache.rdb.Pipeline()
+	results := make([]*redis.StringSliceCmd, 0, len(actors))
+	for _, actor := range actors {
+		results = append(results, pipe.ZRangeByScore(ctx, orderHistoryKey(actor), &redis.ZRangeBy{
+			Min: "-inf",
+			Max: strconv.FormatInt(createdBefore.UnixMilli(), 10),
+		}))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get order history %w", err)
+	}
+	seen := make(map[string]bool)
+	orderIDs := make([]string, 0)
+	for _, result := range results {
+		for _, orderID := range result.Val() {
+			if seen[orderID] {
+				continue
+			}
+			seen[orderID] = true
+			orderIDs = append(orderIDs, orderID)
+		}
+	}
+	return orderIDs, nil
+}
+
+func (cache *CacheStore) IssueChallenge(ctx context.Context, challengeID string, bits int64, ttl time.Duration) error {
+	if err := cache.rdb.Set(ctx, fmt.Sprintf("abuse_challenge:%s", challengeID), bits, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to issue challenge %w", err)
+	}
+	return nil
+}
+
+/*
+*
+ConsumeChallenge: difficulty bits of challenge, challenge could be answered once, false when unknown or expired
+*/
+func (cache *CacheStore) ConsumeChallenge(ctx context.Context, challengeID string) (int64, bool, error) {
+	bits, err := cache.rdb.GetDel(ctx, fmt.Sprintf("abuse_challenge:%s", challengeID)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to consume challenge %w", err)
+	}
+	return bits, true, nil
+}
+
+/*
+*
+TrackWindowMembers: luascript for sliding window counter, members older than window are dropped
+input key: counter key, arguments: window_ms, then members added now
+return members within window
+*
+*/
+var TrackWindowMembers = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local window = tonumber(ARGV[1])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+for index = 2, #ARGV do
+	redis.call("ZADD", KEYS[1], now, ARGV[index])
+end
+redis.call("PEXPIRE", KEYS[1], window)
+return redis.call("ZCARD", KEYS[1])
+`)
@@ -0,0 +1,51 @@
+package abuse
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+const (
+	// ChallengeHeader: challenge issued with 428 as "<id>;bits=<n>"
+	ChallengeHeader = "X-Abuse-Challenge"
+	// ChallengeResponseHeader: answer "<id>:<nonce>" where sha256 of "<id>:<nonce>" starts with n zero bits
+	ChallengeResponseHeader = "X-Abuse-Challenge-Response"
+)
+
+func newChallengeID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate challenge id %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// parseChallengeResponse: challenge id and nonce of response header value
+func parseChallengeResponse(response string) (string, string, bool) {
+	challengeID, nonce, found := strings.Cut(strings.TrimSpace(response), ":")
+	if !found || len(challengeID) != 32 || nonce == "" {
+		return "", "", false
+	}
+	return challengeID, nonce, true
+}
+
+/*
+*
+solvesChallenge: sha256 of "<id>:<nonce>" has at least difficulty leading zero bits
+*/
+func solvesChallenge(challengeID string, nonce string, difficulty int64) bool {
+	sum := sha256.Sum256([]byte(challengeID + ":" + nonce))
+	var zeros int64
+	for _, b := range sum {
+		if b != 0 {
+			zeros += int64(bits.LeadingZeros8(b))
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
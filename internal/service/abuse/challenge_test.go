@@ -0,0 +1,50 @@
+package abuse
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"testing"
+)
+
+const testChallengeID = "0123456789abcdef0123456789abcdef"
+
+// nonceWithFirstByte: first nonce whose answer hash starts with byte matching match
+func nonceWithFirstByte(t *testing.T, match func(byte) bool) string {
+	t.Helper()
+	for index := 0; index < 1<<20; index++ {
+		nonce := strconv.Itoa(index)
+		if sum := sha256.Sum256([]byte(testChallengeID + ":" + nonce)); match(sum[0]) {
+			return nonce
+		}
+	}
+	t.Fatal("no nonce found")
+	return ""
+}
+
+func TestSolvesChallenge(t *testing.T) {
+	// first byte 0x08..0x0f has exactly 4 leading zero bits
+	fourZeros := nonceWithFirstByte(t, func(b byte) bool { return b >= 0x08 && b <= 0x0f })
+	// first byte 0x80 or above has no leading zero bit
+	noZeros := nonceWithFirstByte(t, func(b byte) bool { return b >= 0x80 })
+	// zero first byte counts on into second byte
+	fullByte := nonceWithFirstByte(t, func(b byte) bool { return b == 0 })
+	tests := []struct {
+		name       string
+		nonce      string
+		difficulty int64
+		want       bool
+	}{
+		{name: "no difficulty", nonce: noZeros, difficulty: 0, want: true},
+		{name: "no leading zero", nonce: noZeros, difficulty: 1, want: false},
+		{name: "exact difficulty", nonce: fourZeros, difficulty: 4, want: true},
+		{name: "one bit short", nonce: fourZeros, difficulty: 5, want: false},
+		{name: "whole zero byte", nonce: fullByte, difficulty: 8, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := solvesChallenge(testChallengeID, test.nonce, test.difficulty); got != test.want {
+				t.Errorf("solvesChallenge(%q, %d) = %v, want %v", test.nonce, test.difficulty, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package abuse
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+// maxDecisionsLimit: most decisions of one page
+const maxDecisionsLimit = 100
+
+type Handler struct {
+	store types.AbuseStore
+}
+
+func NewHandler(store types.AbuseStore) *Handler {
+	return &Handler{
+		store: store,
+	}
+}
+
+func (h *Handler) RegisterAdminRoute(router *gin.RouterGroup) {
+	router.GET("/abuse/decisions", h.GetDecisions)
+}
+
+/*
+*
+GetDecisions: logged decisions newest first, filtered by ?action=flag|challenge|block
+*/
+func (h *Handler) GetDecisions(ctx *gin.Context) {
+	query := ctx.Request.URL.Query()
+	action := query.Get("action")
+	switch action {
+	case "", types.AbuseActionFlag, types.AbuseActionChallenge, types.AbuseActionBlock:
+	default:
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("action must be one of flag, challenge, block"))
+		return
+	}
+	pagination := types.Pagination{
+		Offset: 0,
+		Limit:  20,
+	}
+	integerParams := []struct {
+		name  string
+		value *int64
+	}{
+		{"limit", &pagination.Limit},
+		{"offset", &pagination.Offset},
+	}
+	for _, param := range integerParams {
+		if !query.Has(param.name) {
+			continue
+		}
+		value, err := strconv.ParseInt(query.Get(param.name), 10, 64)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("%s parse err: %w", param.name, err))
+			return
+		}
+		*param.value = value
+	}
+	if pagination.Limit < 1 || pagination.Limit > maxDecisionsLimit || pagination.Offset < 0 {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("limit must be within 1 to %d and offset not negative", maxDecisionsLimit))
+		return
+	}
+	decisions, pagination, err := h.store.GetDecisions(ctx, action, pagination)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get abuse decisions %w", err))
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.AbuseDecisionsFetchResponse{
+		Decisions:  decisions,
+		Pagination: pagination,
+	}), "failed to response json")
+}
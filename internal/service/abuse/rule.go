@@ -0,0 +1,94 @@
+package abuse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+var ErrInvalidRule = errors.New("invalid abuse rule")
+
+// SignalRule: signal reaching Limit adds Weight to score, proportionally more above Limit
+type SignalRule struct {
+	Limit  int64
+	Weight int64
+}
+
+// Rules: scoring of order requests, action is the highest threshold score reaches
+type Rules struct {
+	Signals        map[string]SignalRule
+	Window         time.Duration
+	UnpaidHold     time.Duration
+	FlagScore      int64
+	ChallengeScore int64
+	BlockScore     int64
+	ChallengeBits  int64
+}
+
+/*
+*
+ParseSignalRules: signal rules of spec like "velocity:10:40,flight_spread:4:30"
+*/
+func ParseSignalRules(spec string) (map[string]SignalRule, error) {
+	signals := make(map[string]SignalRule)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fields := strings.Split(entry, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%w: %q must be signal:limit:weight", ErrInvalidRule, entry)
+		}
+		switch fields[0] {
+		case types.AbuseSignalVelocity, types.AbuseSignalFlightSpread,
+			types.AbuseSignalUnpaidExpired, types.AbuseSignalIdenticalPassengers:
+		default:
+			return nil, fmt.Errorf("%w: unknown signal %q", ErrInvalidRule, fields[0])
+		}
+		limit, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("%w: limit of %q must be a positive integer", ErrInvalidRule, entry)
+		}
+		weight, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("%w: weight of %q must not be negative", ErrInvalidRule, entry)
+		}
+		signals[fields[0]] = SignalRule{Limit: limit, Weight: weight}
+	}
+	return signals, nil
+}
+
+/*
+*
+Score: sum of signal scores, a signal scores weight * count / limit once count reaches limit, at most 3 * weight
+*/
+func (rules Rules) Score(signals map[string]int64) int64 {
+	var score int64
+	for signal, count := range signals {
+		rule, ok := rules.Signals[signal]
+		if !ok || count < rule.Limit {
+			continue
+		}
+		score += min(rule.Weight*count/rule.Limit, 3*rule.Weight)
+	}
+	return score
+}
+
+// Action: action of score, zero thresholds are disabled
+func (rules Rules) Action(score int64) string {
+	switch {
+	case rules.BlockScore > 0 && score >= rules.BlockScore:
+		return types.AbuseActionBlock
+	case rules.ChallengeScore > 0 && score >= rules.ChallengeScore:
+		return types.AbuseActionChallenge
+	case rules.FlagScore > 0 && score >= rules.FlagScore:
+		return types.AbuseActionFlag
+	default:
+		return types.AbuseActionAllow
+	}
+}
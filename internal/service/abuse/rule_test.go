@@ -0,0 +1,76 @@
+package abuse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+func TestRulesScore(t *testing.T) {
+	rules := Rules{Signals: map[string]SignalRule{
+		types.AbuseSignalVelocity:     {Limit: 10, Weight: 40},
+		types.AbuseSignalFlightSpread: {Limit: 4, Weight: 30},
+	}}
+	tests := []struct {
+		name    string
+		signals map[string]int64
+		want    int64
+	}{
+		{name: "no signals", signals: map[string]int64{}, want: 0},
+		{name: "below limit", signals: map[string]int64{types.AbuseSignalVelocity: 9}, want: 0},
+		{name: "at limit", signals: map[string]int64{types.AbuseSignalVelocity: 10}, want: 40},
+		{name: "above limit", signals: map[string]int64{types.AbuseSignalVelocity: 15}, want: 60},
+		{name: "capped at three weights", signals: map[string]int64{types.AbuseSignalVelocity: 100}, want: 120},
+		{name: "signal without rule", signals: map[string]int64{types.AbuseSignalUnpaidExpired: 50}, want: 0},
+		{name: "sum of signals", signals: map[string]int64{
+			types.AbuseSignalVelocity:     10,
+			types.AbuseSignalFlightSpread: 8,
+		}, want: 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rules.Score(test.signals); got != test.want {
+				t.Errorf("Score(%v) = %d, want %d", test.signals, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSignalRules(t *testing.T) {
+	signals, err := ParseSignalRules(" velocity:10:40, ,flight_spread:4:0,")
+	if err != nil {
+		t.Fatalf("ParseSignalRules() error = %v", err)
+	}
+	want := map[string]SignalRule{
+		types.AbuseSignalVelocity:     {Limit: 10, Weight: 40},
+		types.AbuseSignalFlightSpread: {Limit: 4, Weight: 0},
+	}
+	if len(signals) != len(want) {
+		t.Fatalf("ParseSignalRules() = %v, want %v", signals, want)
+	}
+	for signal, rule := range want {
+		if signals[signal] != rule {
+			t.Errorf("rule of %s = %v, want %v", signal, signals[signal], rule)
+		}
+	}
+
+	empty, err := ParseSignalRules("")
+	if err != nil || len(empty) != 0 {
+		t.Errorf("ParseSignalRules(\"\") = %v, %v, want no rules", empty, err)
+	}
+
+	for _, spec := range []string{
+		"velocity:10",
+		"velocity:10:40:1",
+		"unknown:10:40",
+		"velocity:0:40",
+		"velocity:ten:40",
+		"velocity:10:-1",
+		"velocity:10:heavy",
+	} {
+		if _, err := ParseSignalRules(spec); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("ParseSignalRules(%q) error = %v, want %v", spec, err, ErrInvalidRule)
+		}
+	}
+}
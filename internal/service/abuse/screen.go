@@ -0,0 +1,74 @@
+package abuse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/customer"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// DeviceHeader: device fingerprint of client, counted as its own actor when sent
+const DeviceHeader = "X-Device-Fingerprint"
+
+// passengerActor: document number of passenger hashed, ip and device header are chosen by client but travel documents are not
+func passengerActor(passenger types.PassengerRequest) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(passenger.DocumentNumber))))
+	return "passenger:" + hex.EncodeToString(sum[:16])
+}
+
+/*
+*
+Screen: evaluate order request before seats are reserved, requests of api keys and disabled screening pass,
+actors are client ip, signed in customer, device and every passenger, so rotating ip or device keeps velocity of passengers,
+ErrOrderBlocked or ErrChallengeRequired stop the order, redis or database failure lets the order through
+*/
+func Screen(ctx *gin.Context, service types.AbuseService, flightIDs []string, passengers []types.PassengerRequest) (types.AbuseEvaluation, error) {
+	if service == nil {
+		return types.AbuseEvaluation{}, nil
+	}
+	// agencies are held by quota and allotment of their api key
+	if _, ok := agency.CurrentAPIKey(ctx); ok {
+		return types.AbuseEvaluation{}, nil
+	}
+	actors := []string{"ip:" + ctx.ClientIP()}
+	if customerID, ok := customer.CustomerID(ctx); ok {
+		actors = append(actors, "customer:"+customerID.String())
+	}
+	if device := ctx.GetHeader(DeviceHeader); device != "" && len(device) <= 128 {
+		actors = append(actors, "device:"+device)
+	}
+	seen := make(map[string]bool, len(passengers))
+	for _, passenger := range passengers {
+		if actor := passengerActor(passenger); !seen[actor] {
+			seen[actor] = true
+			actors = append(actors, actor)
+		}
+	}
+	evaluation, err := service.Evaluate(ctx, types.AbuseRequest{
+		Actors:            actors,
+		FlightIDs:         flightIDs,
+		Passengers:        passengers,
+		ChallengeResponse: ctx.GetHeader(ChallengeResponseHeader),
+	})
+	if err != nil && !errors.Is(err, ErrOrderBlocked) && !errors.Is(err, ErrChallengeRequired) {
+		log.Printf("abuse screening skipped %v", err)
+		return types.AbuseEvaluation{}, nil
+	}
+	return evaluation, err
+}
+
+// RecordOrders: record accepted orders of screened request, failure is only logged as orders are already placed
+func RecordOrders(ctx *gin.Context, service types.AbuseService, evaluation types.AbuseEvaluation, orderIDs []string) {
+	if service == nil {
+		return
+	}
+	if err := service.Record(ctx, evaluation, orderIDs); err != nil {
+		log.Printf("failed to record abuse screened orders %v", err)
+	}
+}
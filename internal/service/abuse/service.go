@@ -0,0 +1,193 @@
+package abuse
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+const (
+	// challengeTTL: time to answer issued challenge
+	challengeTTL = 5 * time.Minute
+	// orderHistory: accepted orders of actors are remembered this long for unpaid_expired signal
+	orderHistory = 24 * time.Hour
+)
+
+var (
+	ErrOrderBlocked      = apperr.New(apperr.Forbidden, "order request blocked by abuse screening")
+	ErrChallengeRequired = apperr.New(apperr.PreconditionRequired, "order request needs challenge answer")
+)
+
+// score order requests by recent behaviour of their actors, block, challenge or flag suspicious ones
+type AbuseService struct {
+	store      types.AbuseStore
+	cacheStore types.AbuseCacheStore
+	rules      Rules
+}
+
+func NewAbuseService(store types.AbuseStore, cacheStore types.AbuseCacheStore, rules Rules) *AbuseService {
+	return &AbuseService{
+		store:      store,
+		cacheStore: cacheStore,
+		rules:      rules,
+	}
+}
+
+/*
+*
+Evaluate: count attempt of request and decide action, challenge decision with valid challenge answer is downgraded to flag,
+block and challenge decisions are stored here, flag decisions are stored by Record with their order
+*/
+func (service *AbuseService) Evaluate(ctx context.Context, request types.AbuseRequest) (types.AbuseEvaluation, error) {
+	decision := types.AbuseDecision{
+		ID:      uuid.New(),
+		Action:  types.AbuseActionAllow,
+		Signals: make(map[string]int64),
+		Actors:  request.Actors,
+	}
+	if len(request.Actors) == 0 {
+		return types.AbuseEvaluation{Decision: decision}, nil
+	}
+	attempt, err := service.cacheStore.TrackAttempt(ctx, types.AbuseAttemptParam{
+		AttemptID:      decision.ID.String(),
+		Actors:         request.Actors,
+		FlightIDs:      request.FlightIDs,
+		PassengersHash: passengersHash(request.Passengers),
+		Window:         service.rules.Window,
+	})
+	if err != nil {
+		return types.AbuseEvaluation{}, err
+	}
+	unpaidExpired, err := service.countUnpaidExpired(ctx, request.Actors)
+	if err != nil {
+		return types.AbuseEvaluation{}, err
+	}
+	decision.Signals[types.AbuseSignalVelocity] = attempt.Attempts
+	decision.Signals[types.AbuseSignalFlightSpread] = attempt.Flights
+	decision.Signals[types.AbuseSignalUnpaidExpired] = unpaidExpired
+	decision.Signals[types.AbuseSignalIdenticalPassengers] = attempt.IdenticalPassengers
+	decision.Score = service.rules.Score(decision.Signals)
+	decision.Action = service.rules.Action(decision.Score)
+	evaluation := types.AbuseEvaluation{Decision: decision}
+	if decision.Action == types.AbuseActionChallenge {
+		solved, err := service.verifyChallenge(ctx, request.ChallengeResponse)
+		if err != nil {
+			return types.AbuseEvaluation{}, err
+		}
+		if solved {
+			evaluation.Decision.Action = types.AbuseActionFlag
+		} else if evaluation.Challenge, err = service.issueChallenge(ctx); err != nil {
+			return types.AbuseEvaluation{}, err
+		}
+	}
+	if evaluation.Decision.Action != types.AbuseActionAllow {
+		log.Printf("abuse decision %s score %d signals %v actors %v", evaluation.Decision.Action,
+			evaluation.Decision.Score, evaluation.Decision.Signals, evaluation.Decision.Actors)
+	}
+	switch evaluation.Decision.Action {
+	case types.AbuseActionBlock:
+		if err := service.store.CreateDecision(ctx, evaluation.Decision); err != nil {
+			log.Printf("failed to store abuse decision %v", err)
+		}
+		return evaluation, fmt.Errorf("%w with score %d", ErrOrderBlocked, evaluation.Decision.Score)
+	case types.AbuseActionChallenge:
+		if err := service.store.CreateDecision(ctx, evaluation.Decision); err != nil {
+			log.Printf("failed to store abuse decision %v", err)
+		}
+		return evaluation, fmt.Errorf("%w, answer %s with %s", ErrChallengeRequired, ChallengeHeader, ChallengeResponseHeader)
+	}
+	return evaluation, nil
+}
+
+/*
+*
+Record: remember accepted orders of actors, flag decision is stored once per order
+*/
+func (service *AbuseService) Record(ctx context.Context, evaluation types.AbuseEvaluation, orderIDs []string) error {
+	if len(evaluation.Decision.Actors) == 0 || len(orderIDs) == 0 {
+		return nil
+	}
+	if err := service.cacheStore.AddOrders(ctx, evaluation.Decision.Actors, orderIDs, orderHistory); err != nil {
+		return err
+	}
+	if evaluation.Decision.Action != types.AbuseActionFlag {
+		return nil
+	}
+	for index, orderID := range orderIDs {
+		id, err := uuid.Parse(orderID)
+		if err != nil {
+			return fmt.Errorf("failed to parse order id %s %w", orderID, err)
+		}
+		decision := evaluation.Decision
+		decision.OrderID = uuid.NullUUID{UUID: id, Valid: true}
+		if index > 0 {
+			decision.ID = uuid.New()
+		}
+		if err := service.store.CreateDecision(ctx, decision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countUnpaidExpired: orders of actors still unpaid after unpaid hold time
+func (service *AbuseService) countUnpaidExpired(ctx context.Context, actors []string) (int64, error) {
+	createdBefore := time.Now().UTC().Add(-service.rules.UnpaidHold)
+	orders, err := service.cacheStore.GetOrders(ctx, actors, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	orderIDs := make([]uuid.UUID, 0, len(orders))
+	for _, order := range orders {
+		if id, err := uuid.Parse(order); err == nil {
+			orderIDs = append(orderIDs, id)
+		}
+	}
+	return service.store.CountUnpaidOrders(ctx, orderIDs, createdBefore)
+}
+
+func (service *AbuseService) issueChallenge(ctx context.Context) (string, error) {
+	challengeID, err := newChallengeID()
+	if err != nil {
+		return "", err
+	}
+	if err := service.cacheStore.IssueChallenge(ctx, challengeID, service.rules.ChallengeBits, challengeTTL); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s;bits=%d", challengeID, service.rules.ChallengeBits), nil
+}
+
+// verifyChallenge: response answers an issued challenge, the challenge is used up even by wrong answer
+func (service *AbuseService) verifyChallenge(ctx context.Context, response string) (bool, error) {
+	challengeID, nonce, ok := parseChallengeResponse(response)
+	if !ok {
+		return false, nil
+	}
+	difficulty, found, err := service.cacheStore.ConsumeChallenge(ctx, challengeID)
+	if err != nil || !found {
+		return false, err
+	}
+	return solvesChallenge(challengeID, nonce, difficulty), nil
+}
+
+// passengersHash: same passengers in any order hash alike
+func passengersHash(passengers []types.PassengerRequest) string {
+	entries := make([]string, 0, len(passengers))
+	for _, passenger := range passengers {
+		entries = append(entries, strings.ToLower(strings.Join([]string{
+			strings.TrimSpace(passenger.Name), passenger.DateOfBirth, strings.TrimSpace(passenger.DocumentNumber),
+		}, "|")))
+	}
+	sort.Strings(entries)
+	sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package abuse
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// decisionColumns: column order used by scanDecision
+const decisionColumns = "id, action, score, signals, actors, order_id, created_at"
+
+type AbuseStore struct {
+	db *sql.DB
+}
+
+func NewAbuseStore(db *sql.DB) *AbuseStore {
+	return &AbuseStore{db: db}
+}
+
+type decisionScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDecision(scanner decisionScanner) (types.AbuseDecision, error) {
+	var decision types.AbuseDecision
+	var signals []byte
+	err := scanner.Scan(
+		&decision.ID,
+		&decision.Action,
+		&decision.Score,
+		&signals,
+		pq.Array(&decision.Actors),
+		&decision.OrderID,
+		&decision.CreatedAt,
+	)
+	if err != nil {
+		return types.AbuseDecision{}, err
+	}
+	if err := json.Unmarshal(signals, &decision.Signals); err != nil {
+		return types.AbuseDecision{}, fmt.Errorf("unmarshal signals err %w", err)
+	}
+	return decision, nil
+}
+
+func (abuseStore *AbuseStore) CreateDecision(ctx context.Context, decision types.AbuseDecision) error {
+	signals, err := json.Marshal(decision.Signals)
+	if err != nil {
+		return fmt.Errorf("marshal signals err %w", err)
+	}
+	queryBuilder := sq.Insert("abuse_decisions").Columns("id", "action", "score", "signals", "actors", "order_id").
+		Values(decision.ID, decision.Action, decision.Score, signals, pq.Array(decision.Actors), decision.OrderID).
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to create query string %w", err)
+	}
+	if _, err := abuseStore.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert abuse decision %w", err)
+	}
+	return nil
+}
+
+/*
+*
+GetDecisions: decisions newest first, all actions when action is empty
+*/
+func (abuseStore *AbuseStore) GetDecisions(ctx context.Context, action string, pagination types.Pagination) ([]types.AbuseDecision, types.Pagination, error) {
+	queryBuilder := sq.Select(decisionColumns).From("abuse_decisions").
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(pagination.Limit + 1)).Offset(uint64(pagination.Offset)).
+		PlaceholderFormat(sq.Dollar)
+	if action != "" {
+		queryBuilder = queryBuilder.Where(sq.Eq{"action": action})
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, types.Pagination{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := abuseStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, types.Pagination{}, fmt.Errorf("failed to query abuse decisions %w", err)
+	}
+	defer rows.Close()
+	decisions := []types.AbuseDecision{}
+	for rows.Next() {
+		decision, err := scanDecision(rows)
+		if err != nil {
+			return nil, types.Pagination{}, fmt.Errorf("failed to scan abuse decision %w", err)
+		}
+		decisions = append(decisions, decision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, types.Pagination{}, fmt.Errorf("failed to iterate abuse decisions %w", err)
+	}
+	pagination.HasMore = int64(len(decisions)) > pagination.Limit
+	if pagination.HasMore {
+		decisions = decisions[:pagination.Limit]
+		pagination.NextOffset = pagination.Offset + pagination.Limit
+	}
+	return decisions, pagination, nil
+}
+
+// CountUnpaidOrders: orders among orderIDs created before createdBefore and still neither paid nor canceled
+func (abuseStore *AbuseStore) CountUnpaidOrders(ctx context.Context, orderIDs []uuid.UUID, createdBefore time.Time) (int64, error) {
+	if len(orderIDs) == 0 {
+		return 0, nil
+	}
+	queryBuilder := sq.Select("COUNT(*)").From("orders").
+		Where(sq.Eq{"id": orderIDs, "paid_at": nil, "canceled_at": nil}).
+		Where(sq.Lt{"created_at": createdBefore}).
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create query string %w", err)
+	}
+	var count int64
+	if err := abuseStore.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unpaid orders %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,100 @@
+package agency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+var ErrAllotmentExceeded = apperr.New(apperr.Conflict, "seat allotment of agency exceeded")
+
+/*
+*
+ReserveAllotment: take seats from allotment of agency of request on every flight,
+nothing is reserved for request without api key, ErrAllotmentExceeded when any flight is short
+*/
+func ReserveAllotment(ctx context.Context, cacheStore types.AgencyCacheStore, flights []types.Flight, seats int64) (types.AgencyAllotmentParam, error) {
+	agency, ok := CurrentAgency(ctx)
+	if !ok {
+		return types.AgencyAllotmentParam{}, nil
+	}
+	allotmentParam := types.AgencyAllotmentParam{
+		AgencyID:  agency.ID.String(),
+		FlightIDs: make([]string, 0, len(flights)),
+		Seats:     seats,
+		Allotment: int64(agency.SeatAllotment),
+	}
+	for _, flight := range flights {
+		allotmentParam.FlightIDs = append(allotmentParam.FlightIDs, flight.ID.String())
+		if flight.FlightDate.After(allotmentParam.ExpireAt) {
+			allotmentParam.ExpireAt = flight.FlightDate
+		}
+	}
+	isValid, err := cacheStore.ReserveAllotment(ctx, allotmentParam)
+	if err != nil {
+		return types.AgencyAllotmentParam{}, err
+	}
+	if !isValid {
+		return types.AgencyAllotmentParam{}, fmt.Errorf("%d seats per flight for agency %s %w",
+			agency.SeatAllotment, agency.Name, ErrAllotmentExceeded)
+	}
+	return allotmentParam, nil
+}
+
+// ReleaseAllotment: give back seats of ReserveAllotment, failure is only logged as booking already failed
+func ReleaseAllotment(ctx context.Context, cacheStore types.AgencyCacheStore, allotmentParam types.AgencyAllotmentParam) {
+	if allotmentParam.AgencyID == "" {
+		return
+	}
+	if err := cacheStore.ReleaseAllotment(ctx, allotmentParam); err != nil {
+		log.Printf("failed to release allotment %v", err)
+	}
+}
+
+/*
+*
+ReleaseOrderAllotment: give back seats of agency order leaving flights, e.g. canceled, expired or rebooked order,
+nothing is released for order not booked by agency
+*/
+func ReleaseOrderAllotment(ctx context.Context, cacheStore types.AgencyCacheStore, agencyID uuid.NullUUID, flightIDs []uuid.UUID, seats int64) {
+	if !agencyID.Valid || seats <= 0 {
+		return
+	}
+	allotmentParam := types.AgencyAllotmentParam{
+		AgencyID:  agencyID.UUID.String(),
+		FlightIDs: make([]string, 0, len(flightIDs)),
+		Seats:     seats,
+	}
+	for _, flightID := range flightIDs {
+		allotmentParam.FlightIDs = append(allotmentParam.FlightIDs, flightID.String())
+	}
+	ReleaseAllotment(ctx, cacheStore, allotmentParam)
+}
+
+/*
+*
+TransferOrderAllotment: move seats of agency order rebooked from flight fromID onto flight to,
+rebooking is never refused by allotment so seats on flight to are counted even above allotment
+*/
+func TransferOrderAllotment(ctx context.Context, cacheStore types.AgencyCacheStore, agencyID uuid.NullUUID, fromID uuid.UUID,
+	to types.Flight, seats int64) {
+	if !agencyID.Valid || seats <= 0 {
+		return
+	}
+	_, err := cacheStore.ReserveAllotment(ctx, types.AgencyAllotmentParam{
+		AgencyID:  agencyID.UUID.String(),
+		FlightIDs: []string{to.ID.String()},
+		Seats:     seats,
+		Allotment: math.MaxInt32,
+		ExpireAt:  to.FlightDate,
+	})
+	if err != nil {
+		log.Printf("failed to count %d rebooked seats of agency %s on flight %s %v", seats, agencyID.UUID, to.ID, err)
+	}
+	ReleaseOrderAllotment(ctx, cacheStore, agencyID, []uuid.UUID{fromID}, seats)
+}
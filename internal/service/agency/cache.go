@@ -0,0 +1,175 @@
+package agency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+type CacheStore struct {
+	rdb *redis.Client
+}
+
+func NewCacheStore(rdb *redis.Client) *CacheStore {
+	return &CacheStore{
+		rdb: rdb,
+	}
+}
+
+func credentialKey(prefix string) string {
+	return fmt.Sprintf("api_key:%s", prefix)
+}
+
+// allotmentKey: counters of one agency share hash tag so multi-flight scripts stay in one slot
+func allotmentKey(agencyID string, flightID string) string {
+	return fmt.Sprintf("agency_allotment:{%s}:%s", agencyID, flightID)
+}
+
+/*
+*
+GetCredential: cached api key of prefix, false when not cached
+*/
+func (cache *CacheStore) GetCredential(ctx context.Context, prefix string) (types.AgencyCredential, bool, error) {
+	resultBody, err := cache.rdb.Get(ctx, credentialKey(prefix)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return types.AgencyCredential{}, false, nil
+		}
+		return types.AgencyCredential{}, false, fmt.Errorf("failed to get api key %s %w", prefix, err)
+	}
+	var credential types.AgencyCredential
+	if err := json.Unmarshal(resultBody, &credential); err != nil {
+		return types.AgencyCredential{}, false, fmt.Errorf("failed to unmarshal api key %s %w", prefix, err)
+	}
+	return credential, true, nil
+}
+
+func (cache *CacheStore) SetCredential(ctx context.Context, credential types.AgencyCredential, ttl time.Duration) error {
+	jsonData, err := json.Marshal(credential)
+	if err != nil {
+		return fmt.Errorf("marshal api key err %w", err)
+	}
+	if err := cache.rdb.Set(ctx, credentialKey(credential.Key.Prefix), jsonData, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache api key %s %w", credential.Key.Prefix, err)
+	}
+	return nil
+}
+
+// DeleteCredential: drop cached api key so revocation takes effect immediately
+func (cache *CacheStore) DeleteCredential(ctx context.Context, prefix string) error {
+	if err := cache.rdb.Del(ctx, credentialKey(prefix)).Err(); err != nil {
+		return fmt.Errorf("failed to delete api key %s %w", prefix, err)
+	}
+	return nil
+}
+
+/*
+*
+IncrRequestCount: count request of api key on day, counter expires after ttl
+*/
+func (cache *CacheStore) IncrRequestCount(ctx context.Context, keyID string, day string, ttl time.Duration) (int64, error) {
+	count, err := IncrWithExpire.Run(ctx, cache.rdb, []string{fmt.Sprintf("api_quota:%s:%s", keyID, day)},
+		int64(ttl/time.Second)).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count request of api key %s %w", keyID, err)
+	}
+	return count, nil
+}
+
+/*
+*
+ReserveAllotment: take seats from allotment of agency on every flight or none, false when any flight is short
+*/
+func (cache *CacheStore) ReserveAllotment(ctx context.Context, allotmentParam types.AgencyAllotmentParam) (bool, error) {
+	keys := make([]string, 0, len(allotmentParam.FlightIDs))
+	for _, flightID := range allotmentParam.FlightIDs {
+		keys = append(keys, allotmentKey(allotmentParam.AgencyID, flightID))
+	}
+	isValid, err := ReserveAllotmentWithFlightIDs.Run(ctx, cache.rdb, keys,
+		allotmentParam.Seats, allotmentParam.Allotment, allotmentParam.ExpireAt.Unix()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve allotment of agency %s %w", allotmentParam.AgencyID, err)
+	}
+	return isValid == 1, nil
+}
+
+// ReleaseAllotment: give back seats taken by ReserveAllotment when booking is abandoned or order leaves flight
+func (cache *CacheStore) ReleaseAllotment(ctx context.Context, allotmentParam types.AgencyAllotmentParam) error {
+	keys := make([]string, 0, len(allotmentParam.FlightIDs))
+	for _, flightID := range allotmentParam.FlightIDs {
+		keys = append(keys, allotmentKey(allotmentParam.AgencyID, flightID))
+	}
+	if err := ReleaseAllotmentWithFlightIDs.Run(ctx, cache.rdb, keys, allotmentParam.Seats).Err(); err != nil {
+		return fmt.Errorf("failed to release allotment of agency %s %w", allotmentParam.AgencyID, err)
+	}
+	return nil
+}
+
+/*
+*
+IncrWithExpire: luascript for counter expiring ttl seconds after first increment
+input key: counter key, arguments: ttl
+return current count
+*
+*/
+var IncrWithExpire = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("EXPIRE", KEYS[1], tonumber(ARGV[1]))
+end
+return count
+`)
+
+/*
+*
+ReserveAllotmentWithFlightIDs: luascript for all-or-nothing seats on allotment counter of each flight
+input keys: allotment key of each flight, arguments: request, allotment, expire_at unix time
+return is_valid
+*
+*/
+var ReserveAllotmentWithFlightIDs = redis.NewScript(`
+local request = tonumber(ARGV[1])
+local allotment = tonumber(ARGV[2])
+local expire_at = tonumber(ARGV[3])
+if request <= 0 then
+	return 0
+end
+for _, key in ipairs(KEYS) do
+	local used = tonumber(redis.call("GET", key) or "0")
+	if used + request > allotment then
+		return 0
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call("INCRBY", key, request)
+	redis.call("EXPIREAT", key, expire_at)
+end
+return 1
+`)
+
+/*
+*
+ReleaseAllotmentWithFlightIDs: luascript for giving back seats on allotment counter of each flight, never below zero
+input keys: allotment key of each flight, arguments: request
+return 1
+*
+*/
+var ReleaseAllotmentWithFlightIDs = redis.NewScript(`
+local request = tonumber(ARGV[1])
+for _, key in ipairs(KEYS) do
+	local used = tonumber(redis.call("GET", key) or "0")
+	if used > 0 then
+		local remain = used - request
+		if remain < 0 then
+			remain = 0
+		end
+		redis.call("SET", key, remain, "KEEPTTL")
+	end
+end
+return 1
+`)
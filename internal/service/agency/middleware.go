@@ -0,0 +1,94 @@
+package agency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/auth"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+// APIKeyHeader: header carrying api key of agency
+const APIKeyHeader = "X-API-Key"
+
+// agencyKey, apiKeyKey: gin context keys of agency and its api key used by request
+const (
+	agencyKey = "agency"
+	apiKeyKey = "agency_api_key"
+)
+
+var ErrAmbiguousCredentials = apperr.New(apperr.BadRequest, "api key could not be used together with bearer token")
+
+/*
+*
+AuthenticateAPIKey: attach agency of X-API-Key and count request against daily quota of key,
+requests without api key pass through
+*/
+func AuthenticateAPIKey(agencyService types.AgencyService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		rawKey := ctx.GetHeader(APIKeyHeader)
+		if rawKey == "" {
+			ctx.Next()
+			return
+		}
+		if _, ok := auth.GetPrincipal(ctx); ok {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, ErrAmbiguousCredentials)
+			ctx.Abort()
+			return
+		}
+		credential, err := agencyService.Authenticate(ctx, rawKey)
+		if err != nil {
+			if errors.Is(err, ErrInvalidAPIKey) {
+				util.WriteError(ctx.Writer, http.StatusUnauthorized, err)
+				ctx.Abort()
+				return
+			}
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Header("X-Quota-Limit", strconv.Itoa(int(credential.Key.DailyRequestQuota)))
+		remain, err := agencyService.ConsumeRequestQuota(ctx, credential.Key)
+		if err != nil {
+			if errors.Is(err, ErrQuotaExceeded) {
+				ctx.Header("X-Quota-Remaining", "0")
+				ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(QuotaResetIn(time.Now()).Seconds()))))
+				util.WriteError(ctx.Writer, http.StatusTooManyRequests, fmt.Errorf("api key %s %w", credential.Key.Prefix, err))
+				ctx.Abort()
+				return
+			}
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Header("X-Quota-Remaining", strconv.FormatInt(remain, 10))
+		auth.SetPrincipal(ctx, auth.Principal{Subject: credential.Agency.ID.String(), Roles: []string{auth.RoleAgency}})
+		ctx.Set(agencyKey, credential.Agency)
+		ctx.Set(apiKeyKey, credential.Key)
+		ctx.Next()
+	}
+}
+
+/*
+*
+CurrentAgency: agency of api key of request, false for request without api key,
+services given the gin context of request read it as well
+*/
+func CurrentAgency(ctx context.Context) (types.Agency, bool) {
+	agency, ok := ctx.Value(agencyKey).(types.Agency)
+	return agency, ok
+}
+
+// CurrentAPIKey: api key of request, false for request without api key
+func CurrentAPIKey(ctx context.Context) (types.AgencyAPIKey, bool) {
+	apiKey, ok := ctx.Value(apiKeyKey).(types.AgencyAPIKey)
+	return apiKey, ok
+}
@@ -0,0 +1,210 @@
+package agency
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	agencyService types.AgencyService
+	agencyStore   types.AgencyStore
+}
+
+func NewHandler(agencyService types.AgencyService, agencyStore types.AgencyStore) *Handler {
+	return &Handler{
+		agencyService: agencyService,
+		agencyStore:   agencyStore,
+	}
+}
+
+func (h *Handler) RegisterAdminRoute(router *gin.RouterGroup) {
+	router.POST("/agencies", h.CreateAgency)
+	router.GET("/agencies", h.GetAgencies)
+	router.GET("/agencies/:id/api-keys", h.GetAPIKeys)
+	router.POST("/agencies/:id/api-keys", h.IssueAPIKey)
+	router.DELETE("/agencies/:id/api-keys/:keyId", h.RevokeAPIKey)
+	router.GET("/agencies/:id/commission", h.GetCommission)
+}
+
+func (h *Handler) CreateAgency(ctx *gin.Context) {
+	var createAgency types.CreateAgencyRequest
+	if err := util.ParseJSON(ctx.Request, &createAgency); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createAgency); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	agency, err := h.agencyService.CreateAgency(ctx, createAgency)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, agency), "failed to response json")
+}
+
+func (h *Handler) GetAgencies(ctx *gin.Context) {
+	agencies, err := h.agencyStore.GetAgencies(ctx)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, agencies), "failed to response json")
+}
+
+func (h *Handler) GetAPIKeys(ctx *gin.Context) {
+	agencyID, ok := parseID(ctx, "id")
+	if !ok {
+		return
+	}
+	apiKeys, err := h.agencyStore.GetAPIKeysByAgencyID(ctx, agencyID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	result := make([]types.AgencyAPIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		result = append(result, types.ConvertAgencyAPIKeyToResponse(apiKey))
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed to response json")
+}
+
+/*
+*
+IssueAPIKey: plaintext key is only in this response, it could not be read again
+*/
+func (h *Handler) IssueAPIKey(ctx *gin.Context) {
+	agencyID, ok := parseID(ctx, "id")
+	if !ok {
+		return
+	}
+	var createAPIKey types.CreateAgencyAPIKeyRequest
+	if err := util.ParseJSON(ctx.Request, &createAPIKey); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createAPIKey); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	apiKey, rawKey, err := h.agencyService.IssueAPIKey(ctx, agencyID, createAPIKey)
+	if err != nil {
+		if errors.Is(err, ErrAgencyNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	response := types.ConvertAgencyAPIKeyToResponse(apiKey)
+	response.Key = rawKey
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, response), "failed to response json")
+}
+
+func (h *Handler) RevokeAPIKey(ctx *gin.Context) {
+	agencyID, ok := parseID(ctx, "id")
+	if !ok {
+		return
+	}
+	keyID, ok := parseID(ctx, "keyId")
+	if !ok {
+		return
+	}
+	apiKey, err := h.agencyService.RevokeAPIKey(ctx, agencyID, keyID)
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertAgencyAPIKeyToResponse(apiKey)), "failed to response json")
+}
+
+/*
+*
+GetCommission: commission of orders paid within from to to, unix seconds,
+period defaults to current UTC month until now
+*/
+func (h *Handler) GetCommission(ctx *gin.Context) {
+	agencyID, ok := parseID(ctx, "id")
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now
+	timeParams := []struct {
+		name  string
+		value *time.Time
+	}{
+		{"from", &from},
+		{"to", &to},
+	}
+	for _, param := range timeParams {
+		if !ctx.Request.URL.Query().Has(param.name) {
+			continue
+		}
+		value, err := strconv.ParseInt(ctx.Query(param.name), 10, 64)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("%s parse err: %w", param.name, err))
+			return
+		}
+		*param.value = time.Unix(value, 0).UTC()
+	}
+	if !from.Before(to) {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("from must be before to"))
+		return
+	}
+	agency, err := h.agencyStore.GetAgencyById(ctx, agencyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("%s %w", agencyID, ErrAgencyNotFound))
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	commission, err := h.agencyStore.GetCommission(ctx, agencyID, from, to)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.AgencyCommissionResponse{
+		AgencyID:       agencyID.String(),
+		From:           from,
+		To:             to,
+		Orders:         commission.Orders,
+		Tickets:        commission.Tickets,
+		Revenue:        commission.Revenue,
+		CommissionRate: agency.CommissionRate,
+		Commission:     commission.Revenue * agency.CommissionRate,
+	}), "failed to response json")
+}
+
+func parseID(ctx *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(ctx.Param(name))
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse %s %s into uuid %w", name, ctx.Param(name), err))
+		return uuid.Nil, false
+	}
+	return id, true
+}
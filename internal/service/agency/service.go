@@ -0,0 +1,188 @@
+package agency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// keyScheme: api keys look like aos_<prefix>_<secret>
+const keyScheme = "aos"
+
+var (
+	ErrInvalidAPIKey  = apperr.New(apperr.Unauthorized, "invalid api key")
+	ErrQuotaExceeded  = apperr.New(apperr.TooManyRequests, "daily request quota of api key exceeded")
+	ErrAgencyNotFound = apperr.New(apperr.NotFound, "agency not found")
+	ErrAPIKeyNotFound = apperr.New(apperr.NotFound, "api key not found")
+)
+
+// issue and verify api keys of agencies, only sha256 of key is stored
+type AgencyService struct {
+	agencyStore   types.AgencyStore
+	cacheStore    types.AgencyCacheStore
+	credentialTTL time.Duration
+}
+
+func NewAgencyService(agencyStore types.AgencyStore, cacheStore types.AgencyCacheStore, credentialTTL time.Duration) *AgencyService {
+	return &AgencyService{
+		agencyStore:   agencyStore,
+		cacheStore:    cacheStore,
+		credentialTTL: credentialTTL,
+	}
+}
+
+func (service *AgencyService) CreateAgency(ctx context.Context, createAgency types.CreateAgencyRequest) (types.Agency, error) {
+	return service.agencyStore.CreateAgency(ctx, createAgency)
+}
+
+/*
+*
+IssueAPIKey: create api key of agency, plaintext key is only returned here
+*/
+func (service *AgencyService) IssueAPIKey(ctx context.Context, agencyID uuid.UUID, createAPIKey types.CreateAgencyAPIKeyRequest) (types.AgencyAPIKey, string, error) {
+	if _, err := service.agencyStore.GetAgencyById(ctx, agencyID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.AgencyAPIKey{}, "", fmt.Errorf("%s %w", agencyID, ErrAgencyNotFound)
+		}
+		return types.AgencyAPIKey{}, "", err
+	}
+	prefix, err := randomHex(8)
+	if err != nil {
+		return types.AgencyAPIKey{}, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return types.AgencyAPIKey{}, "", err
+	}
+	rawKey := fmt.Sprintf("%s_%s_%s", keyScheme, prefix, secret)
+	apiKey, err := service.agencyStore.CreateAPIKey(ctx, types.AgencyAPIKey{
+		ID:                uuid.New(),
+		AgencyID:          agencyID,
+		Prefix:            prefix,
+		KeyHash:           hashKey(rawKey),
+		Name:              createAPIKey.Name,
+		DailyRequestQuota: createAPIKey.DailyRequestQuota,
+	})
+	if err != nil {
+		return types.AgencyAPIKey{}, "", err
+	}
+	return apiKey, rawKey, nil
+}
+
+/*
+*
+RevokeAPIKey: revoke api key of agency and evict it from cache
+*/
+func (service *AgencyService) RevokeAPIKey(ctx context.Context, agencyID uuid.UUID, keyID uuid.UUID) (types.AgencyAPIKey, error) {
+	apiKey, err := service.agencyStore.RevokeAPIKey(ctx, agencyID, keyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.AgencyAPIKey{}, fmt.Errorf("%s %w", keyID, ErrAPIKeyNotFound)
+		}
+		return types.AgencyAPIKey{}, err
+	}
+	if err := service.cacheStore.DeleteCredential(ctx, apiKey.Prefix); err != nil {
+		return types.AgencyAPIKey{}, err
+	}
+	return apiKey, nil
+}
+
+/*
+*
+Authenticate: api key with its agency, ErrInvalidAPIKey when key is malformed, unknown, revoked or does not match
+*/
+func (service *AgencyService) Authenticate(ctx context.Context, rawKey string) (types.AgencyCredential, error) {
+	parts := strings.Split(rawKey, "_")
+	if len(parts) != 3 || parts[0] != keyScheme || parts[1] == "" || parts[2] == "" {
+		return types.AgencyCredential{}, ErrInvalidAPIKey
+	}
+	credential, err := service.loadCredential(ctx, parts[1])
+	if err != nil {
+		return types.AgencyCredential{}, err
+	}
+	if credential.Key.RevokedAt.Valid {
+		return types.AgencyCredential{}, fmt.Errorf("%w: revoked", ErrInvalidAPIKey)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(rawKey)), []byte(credential.Key.KeyHash)) != 1 {
+		return types.AgencyCredential{}, ErrInvalidAPIKey
+	}
+	return credential, nil
+}
+
+// loadCredential: api key of prefix from cache, database on cache miss
+func (service *AgencyService) loadCredential(ctx context.Context, prefix string) (types.AgencyCredential, error) {
+	credential, ok, err := service.cacheStore.GetCredential(ctx, prefix)
+	if err != nil {
+		log.Printf("failed to read cached api key %v", err)
+	}
+	if ok {
+		return credential, nil
+	}
+	apiKey, err := service.agencyStore.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.AgencyCredential{}, ErrInvalidAPIKey
+		}
+		return types.AgencyCredential{}, err
+	}
+	agency, err := service.agencyStore.GetAgencyById(ctx, apiKey.AgencyID)
+	if err != nil {
+		return types.AgencyCredential{}, err
+	}
+	credential = types.AgencyCredential{Key: apiKey, Agency: agency}
+	if err := service.cacheStore.SetCredential(ctx, credential, service.credentialTTL); err != nil {
+		log.Printf("failed to cache api key %v", err)
+	}
+	return credential, nil
+}
+
+/*
+*
+ConsumeRequestQuota: count request against daily quota of api key, quota resets at UTC midnight,
+return requests remaining today, ErrQuotaExceeded when quota is used up
+*/
+func (service *AgencyService) ConsumeRequestQuota(ctx context.Context, apiKey types.AgencyAPIKey) (int64, error) {
+	now := time.Now().UTC()
+	// counter outlives its day a little so late requests of the day are still counted
+	count, err := service.cacheStore.IncrRequestCount(ctx, apiKey.ID.String(), now.Format("20060102"),
+		QuotaResetIn(now)+time.Hour)
+	if err != nil {
+		return 0, err
+	}
+	remain := int64(apiKey.DailyRequestQuota) - count
+	if remain < 0 {
+		return 0, ErrQuotaExceeded
+	}
+	return remain, nil
+}
+
+// QuotaResetIn: time until daily quotas reset at next UTC midnight
+func QuotaResetIn(now time.Time) time.Duration {
+	now = now.UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
+
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api key %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,232 @@
+package agency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// agencyColumns: column order used by scanAgency
+const agencyColumns = "id, name, commission_rate, seat_allotment, created_at, updated_at"
+
+// apiKeyColumns: column order used by scanAPIKey
+const apiKeyColumns = "id, agency_id, prefix, key_hash, name, daily_request_quota, created_at, revoked_at"
+
+type AgencyStore struct {
+	db *sql.DB
+}
+
+func NewAgencyStore(db *sql.DB) *AgencyStore {
+	return &AgencyStore{db: db}
+}
+
+type agencyScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAgency(scanner agencyScanner) (types.Agency, error) {
+	var agency types.Agency
+	err := scanner.Scan(
+		&agency.ID,
+		&agency.Name,
+		&agency.CommissionRate,
+		&agency.SeatAllotment,
+		&agency.CreatedAt,
+		&agency.UpdatedAt,
+	)
+	if err != nil {
+		return types.Agency{}, err
+	}
+	return agency, nil
+}
+
+func scanAPIKey(scanner agencyScanner) (types.AgencyAPIKey, error) {
+	var apiKey types.AgencyAPIKey
+	err := scanner.Scan(
+		&apiKey.ID,
+		&apiKey.AgencyID,
+		&apiKey.Prefix,
+		&apiKey.KeyHash,
+		&apiKey.Name,
+		&apiKey.DailyRequestQuota,
+		&apiKey.CreatedAt,
+		&apiKey.RevokedAt,
+	)
+	if err != nil {
+		return types.AgencyAPIKey{}, err
+	}
+	return apiKey, nil
+}
+
+func (agencyStore *AgencyStore) CreateAgency(ctx context.Context, createParams types.CreateAgencyRequest) (types.Agency, error) {
+	queryBuilder := sq.Insert("agencies").Columns("id", "name", "commission_rate", "seat_allotment").
+		Values(uuid.New(), createParams.Name, createParams.CommissionRate, createParams.SeatAllotment).
+		Suffix("RETURNING " + agencyColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Agency{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	result, err := scanAgency(agencyStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.Agency{}, fmt.Errorf("failed to insert agency %w", err)
+	}
+	return result, nil
+}
+
+func (agencyStore *AgencyStore) GetAgencies(ctx context.Context) ([]types.Agency, error) {
+	queryBuilder := sq.Select(agencyColumns).From("agencies").OrderBy("name ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := agencyStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agencies %w", err)
+	}
+	defer rows.Close()
+	agencies := []types.Agency{}
+	for rows.Next() {
+		agency, err := scanAgency(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan agency %w", err)
+		}
+		agencies = append(agencies, agency)
+	}
+	return agencies, rows.Err()
+}
+
+/*
+*
+GetAgencyById: return sql.ErrNoRows when agency not found
+*/
+func (agencyStore *AgencyStore) GetAgencyById(ctx context.Context, agencyID uuid.UUID) (types.Agency, error) {
+	queryBuilder := sq.Select(agencyColumns).From("agencies").Where(sq.Eq{"id": agencyID}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Agency{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	agency, err := scanAgency(agencyStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Agency{}, fmt.Errorf("agency not found %w", err)
+		}
+		return types.Agency{}, fmt.Errorf("failed to query agency %w", err)
+	}
+	return agency, nil
+}
+
+func (agencyStore *AgencyStore) CreateAPIKey(ctx context.Context, apiKey types.AgencyAPIKey) (types.AgencyAPIKey, error) {
+	queryBuilder := sq.Insert("agency_api_keys").Columns("id", "agency_id", "prefix", "key_hash", "name", "daily_request_quota").
+		Values(apiKey.ID, apiKey.AgencyID, apiKey.Prefix, apiKey.KeyHash, apiKey.Name, apiKey.DailyRequestQuota).
+		Suffix("RETURNING " + apiKeyColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.AgencyAPIKey{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	result, err := scanAPIKey(agencyStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.AgencyAPIKey{}, fmt.Errorf("failed to insert api key %w", err)
+	}
+	return result, nil
+}
+
+// GetAPIKeysByAgencyID: api keys of agency including revoked ones, newest first
+func (agencyStore *AgencyStore) GetAPIKeysByAgencyID(ctx context.Context, agencyID uuid.UUID) ([]types.AgencyAPIKey, error) {
+	queryBuilder := sq.Select(apiKeyColumns).From("agency_api_keys").Where(sq.Eq{"agency_id": agencyID}).
+		OrderBy("created_at DESC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := agencyStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys %w", err)
+	}
+	defer rows.Close()
+	apiKeys := []types.AgencyAPIKey{}
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key %w", err)
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	return apiKeys, rows.Err()
+}
+
+/*
+*
+GetAPIKeyByPrefix: return sql.ErrNoRows when no key has prefix
+*/
+func (agencyStore *AgencyStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (types.AgencyAPIKey, error) {
+	queryBuilder := sq.Select(apiKeyColumns).From("agency_api_keys").Where(sq.Eq{"prefix": prefix}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.AgencyAPIKey{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	apiKey, err := scanAPIKey(agencyStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.AgencyAPIKey{}, fmt.Errorf("api key not found %w", err)
+		}
+		return types.AgencyAPIKey{}, fmt.Errorf("failed to query api key %w", err)
+	}
+	return apiKey, nil
+}
+
+/*
+*
+RevokeAPIKey: mark key of agency revoked, key already revoked keeps its revoked_at,
+return sql.ErrNoRows when agency has no such key
+*/
+func (agencyStore *AgencyStore) RevokeAPIKey(ctx context.Context, agencyID uuid.UUID, keyID uuid.UUID) (types.AgencyAPIKey, error) {
+	queryBuilder := sq.Update("agency_api_keys").
+		Set("revoked_at", sq.Expr("COALESCE(revoked_at, now())")).
+		Where(sq.Eq{"id": keyID, "agency_id": agencyID}).
+		Suffix("RETURNING " + apiKeyColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.AgencyAPIKey{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	apiKey, err := scanAPIKey(agencyStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.AgencyAPIKey{}, fmt.Errorf("api key not found %w", err)
+		}
+		return types.AgencyAPIKey{}, fmt.Errorf("failed to revoke api key %w", err)
+	}
+	return apiKey, nil
+}
+
+/*
+*
+GetCommission: paid and not canceled orders of agency paid within [from, to) at fare kept when booked,
+orders booked before fares were kept fall back to current flight prices, summed over segments of multi-segment order
+*/
+func (agencyStore *AgencyStore) GetCommission(ctx context.Context, agencyID uuid.UUID, from time.Time, to time.Time) (types.AgencyCommission, error) {
+	queryBuilder := sq.Select("COUNT(*)", "COALESCE(SUM(o.ticket_numbers), 0)", "COALESCE(SUM(o.ticket_numbers * COALESCE(o.fare, fare.price)), 0)").
+		From("orders o").
+		JoinClause(`JOIN LATERAL (SELECT COALESCE(
+			(SELECT SUM(f.price) FROM order_segments s JOIN flights f ON f.id = s.flight_id WHERE s.order_id = o.id),
+			(SELECT f.price FROM flights f WHERE f.id = o.flight_id)) AS price) fare ON true`).
+		Where(sq.Eq{"o.agency_id": agencyID, "o.canceled_at": nil}).
+		Where(sq.GtOrEq{"o.paid_at": from}).
+		Where(sq.Lt{"o.paid_at": to}).
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.AgencyCommission{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	var commission types.AgencyCommission
+	err = agencyStore.db.QueryRowContext(ctx, query, args...).Scan(&commission.Orders, &commission.Tickets, &commission.Revenue)
+	if err != nil {
+		return types.AgencyCommission{}, fmt.Errorf("failed to query commission %w", err)
+	}
+	return commission, nil
+}
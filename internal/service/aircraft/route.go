@@ -0,0 +1,72 @@
+package aircraft
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	aircraftTypeStore types.AircraftTypeStore
+}
+
+func NewHandler(aircraftTypeStore types.AircraftTypeStore) *Handler {
+	return &Handler{
+		aircraftTypeStore: aircraftTypeStore,
+	}
+}
+
+func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
+	router.POST("/", h.CreateAircraftType)
+	router.GET("/", h.GetAircraftTypes)
+	router.GET("/:code", h.GetAircraftTypeByCode)
+}
+
+func (h *Handler) CreateAircraftType(ctx *gin.Context) {
+	var createAircraftType types.CreateAircraftTypeRequest
+	if err := util.ParseJSON(ctx.Request, &createAircraftType); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createAircraftType); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	aircraftType, err := h.aircraftTypeStore.CreateAircraftType(ctx, createAircraftType)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, aircraftType), "failed to response json")
+}
+
+func (h *Handler) GetAircraftTypes(ctx *gin.Context) {
+	aircraftTypes, err := h.aircraftTypeStore.GetAircraftTypes(ctx)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, aircraftTypes), "failed to response json")
+}
+
+func (h *Handler) GetAircraftTypeByCode(ctx *gin.Context) {
+	aircraftType, err := h.aircraftTypeStore.GetAircraftTypeByCode(ctx, ctx.Param("code"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, aircraftType), "failed to response json")
+}
@@ -0,0 +1,105 @@
+package aircraft
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+const aircraftTypeColumns = "code, model, cabin_configuration, total_seats, seat_map_id, created_at, updated_at"
+
+type AircraftTypeStore struct {
+	db *sql.DB
+}
+
+func NewAircraftTypeStore(db *sql.DB) *AircraftTypeStore {
+	return &AircraftTypeStore{db: db}
+}
+
+type aircraftTypeScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAircraftType(scanner aircraftTypeScanner) (types.AircraftType, error) {
+	var aircraftType types.AircraftType
+	err := scanner.Scan(
+		&aircraftType.Code,
+		&aircraftType.Model,
+		&aircraftType.CabinConfiguration,
+		&aircraftType.TotalSeats,
+		&aircraftType.SeatMapID,
+		&aircraftType.CreatedAt,
+		&aircraftType.UpdatedAt,
+	)
+	if err != nil {
+		return types.AircraftType{}, err
+	}
+	return aircraftType, nil
+}
+
+func (aircraftTypeStore *AircraftTypeStore) CreateAircraftType(ctx context.Context, createParams types.CreateAircraftTypeRequest) (types.AircraftType, error) {
+	seatMapID := uuid.NullUUID{}
+	if createParams.SeatMapID != "" {
+		seatMapID = uuid.NullUUID{UUID: uuid.MustParse(createParams.SeatMapID), Valid: true}
+	}
+	queryBuilder := sq.Insert("aircraft_types").Columns("code", "model", "cabin_configuration", "total_seats", "seat_map_id").
+		Values(strings.ToUpper(createParams.Code), createParams.Model, createParams.CabinConfiguration,
+			createParams.TotalSeats, seatMapID).
+		Suffix("RETURNING " + aircraftTypeColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.AircraftType{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	aircraftType, err := scanAircraftType(aircraftTypeStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.AircraftType{}, fmt.Errorf("failed to insert aircraft type %w", err)
+	}
+	return aircraftType, nil
+}
+
+/*
+*
+GetAircraftTypeByCode: return sql.ErrNoRows when aircraft type not found
+*/
+func (aircraftTypeStore *AircraftTypeStore) GetAircraftTypeByCode(ctx context.Context, code string) (types.AircraftType, error) {
+	query, args, err := sq.Select(aircraftTypeColumns).From("aircraft_types").
+		Where(sq.Eq{"code": strings.ToUpper(code)}).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return types.AircraftType{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	aircraftType, err := scanAircraftType(aircraftTypeStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.AircraftType{}, fmt.Errorf("no aircraft type with code %s %w", code, err)
+		}
+		return types.AircraftType{}, fmt.Errorf("failed to query aircraft type %w", err)
+	}
+	return aircraftType, nil
+}
+
+func (aircraftTypeStore *AircraftTypeStore) GetAircraftTypes(ctx context.Context) ([]types.AircraftType, error) {
+	query, args, err := sq.Select(aircraftTypeColumns).From("aircraft_types").OrderBy("code ASC").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := aircraftTypeStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aircraft types %w", err)
+	}
+	defer rows.Close()
+	aircraftTypes := []types.AircraftType{}
+	for rows.Next() {
+		aircraftType, err := scanAircraftType(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan aircraft type failed %w", err)
+		}
+		aircraftTypes = append(aircraftTypes, aircraftType)
+	}
+	return aircraftTypes, rows.Err()
+}
@@ -0,0 +1,78 @@
+package airport
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+var ErrAirportNotFound = apperr.New(apperr.NotFound, "airport not found")
+
+// resolve airport timezones, locations are kept in memory since airports rarely change
+type Directory struct {
+	airportStore types.AirportStore
+	mu           sync.RWMutex
+	locations    map[string]*time.Location
+}
+
+func NewDirectory(airportStore types.AirportStore) *Directory {
+	return &Directory{
+		airportStore: airportStore,
+		locations:    map[string]*time.Location{},
+	}
+}
+
+/*
+*
+Location: timezone location of airport, ErrAirportNotFound when iata code unknown
+*/
+func (directory *Directory) Location(ctx context.Context, iataCode string) (*time.Location, error) {
+	iataCode = strings.ToUpper(iataCode)
+	directory.mu.RLock()
+	location, ok := directory.locations[iataCode]
+	directory.mu.RUnlock()
+	if ok {
+		return location, nil
+	}
+	airport, err := directory.airportStore.GetAirportByCode(ctx, iataCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s %w", iataCode, ErrAirportNotFound)
+		}
+		return nil, err
+	}
+	location, err = time.LoadLocation(airport.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %s of airport %s %w", airport.Timezone, iataCode, err)
+	}
+	directory.mu.Lock()
+	directory.locations[iataCode] = location
+	directory.mu.Unlock()
+	return location, nil
+}
+
+/*
+*
+LocalizeFlights: fill local departure time of flights,
+flights departing from unknown airports keep utc time only
+*/
+func (directory *Directory) LocalizeFlights(ctx context.Context, flights []types.FlightResponse) error {
+	for idx := range flights {
+		location, err := directory.Location(ctx, flights[idx].Departure)
+		if err != nil {
+			if errors.Is(err, ErrAirportNotFound) {
+				continue
+			}
+			return err
+		}
+		flights[idx].Localize(location)
+	}
+	return nil
+}
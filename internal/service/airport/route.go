@@ -0,0 +1,73 @@
+package airport
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	airportStore types.AirportStore
+}
+
+func NewHandler(airportStore types.AirportStore) *Handler {
+	return &Handler{
+		airportStore: airportStore,
+	}
+}
+
+func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
+	router.POST("/", h.CreateAirport)
+	router.GET("/", h.GetAirports)
+	router.GET("/:code", h.GetAirportByCode)
+}
+
+func (h *Handler) CreateAirport(ctx *gin.Context) {
+	var createAirport types.CreateAirportRequest
+	if err := util.ParseJSON(ctx.Request, &createAirport); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createAirport); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	airport, err := h.airportStore.CreateAirport(ctx, createAirport)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, airport), "failed to response json")
+}
+
+func (h *Handler) GetAirports(ctx *gin.Context) {
+	airports, err := h.airportStore.GetAirports(ctx)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, airports), "failed to response json")
+}
+
+func (h *Handler) GetAirportByCode(ctx *gin.Context) {
+	code := ctx.Param("code")
+	airport, err := h.airportStore.GetAirportByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, airport), "failed to response json")
+}
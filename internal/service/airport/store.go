@@ -0,0 +1,101 @@
+package airport
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+const airportColumns = "iata_code, name, city, country, timezone, created_at, updated_at"
+
+type AirportStore struct {
+	db *sql.DB
+}
+
+func NewAirportStore(db *sql.DB) *AirportStore {
+	return &AirportStore{db: db}
+}
+
+type airportScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAirport(scanner airportScanner) (types.Airport, error) {
+	var airport types.Airport
+	err := scanner.Scan(
+		&airport.IATACode,
+		&airport.Name,
+		&airport.City,
+		&airport.Country,
+		&airport.Timezone,
+		&airport.CreatedAt,
+		&airport.UpdatedAt,
+	)
+	if err != nil {
+		return types.Airport{}, err
+	}
+	return airport, nil
+}
+
+func (airportStore *AirportStore) CreateAirport(ctx context.Context, createParams types.CreateAirportRequest) (types.Airport, error) {
+	queryBuilder := sq.Insert("airports").Columns("iata_code", "name", "city", "country", "timezone").
+		Values(strings.ToUpper(createParams.IATACode), createParams.Name, createParams.City,
+			strings.ToUpper(createParams.Country), createParams.Timezone).
+		Suffix("RETURNING " + airportColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Airport{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	airport, err := scanAirport(airportStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.Airport{}, fmt.Errorf("failed to insert airport %w", err)
+	}
+	return airport, nil
+}
+
+/*
+*
+GetAirportByCode: return sql.ErrNoRows when iata code not found
+*/
+func (airportStore *AirportStore) GetAirportByCode(ctx context.Context, iataCode string) (types.Airport, error) {
+	queryBuilder := sq.Select(airportColumns).From("airports").
+		Where(sq.Eq{"iata_code": strings.ToUpper(iataCode)}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Airport{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	airport, err := scanAirport(airportStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Airport{}, fmt.Errorf("no airport with iata code %s %w", iataCode, err)
+		}
+		return types.Airport{}, fmt.Errorf("failed to query airport %w", err)
+	}
+	return airport, nil
+}
+
+func (airportStore *AirportStore) GetAirports(ctx context.Context) ([]types.Airport, error) {
+	query, args, err := sq.Select(airportColumns).From("airports").OrderBy("iata_code ASC").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := airportStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query airports %w", err)
+	}
+	defer rows.Close()
+	airports := []types.Airport{}
+	for rows.Next() {
+		airport, err := scanAirport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan airport failed %w", err)
+		}
+		airports = append(airports, airport)
+	}
+	return airports, rows.Err()
+}
@@ -0,0 +1,122 @@
+package boarding
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	deniedBoardingService types.DeniedBoardingService
+	boardingStore         types.BoardingStore
+	orderStore            types.OrderStore
+}
+
+func NewHandler(deniedBoardingService types.DeniedBoardingService, boardingStore types.BoardingStore,
+	orderStore types.OrderStore) *Handler {
+	return &Handler{
+		deniedBoardingService: deniedBoardingService,
+		boardingStore:         boardingStore,
+		orderStore:            orderStore,
+	}
+}
+
+func (h *Handler) RegisterOrderRoute(router *gin.RouterGroup) {
+	router.POST("/:id/volunteer", h.VolunteerOrder)
+}
+
+func (h *Handler) RegisterAdminRoute(router *gin.RouterGroup) {
+	router.POST("/flights/:id/gate-close", h.CloseGate)
+	router.GET("/flights/:id/denied-boardings", h.GetDeniedBoardings)
+}
+
+func (h *Handler) VolunteerOrder(ctx *gin.Context) {
+	orderID := ctx.Param("id")
+	id, err := uuid.Parse(orderID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", orderID, err))
+		return
+	}
+	var volunteer types.VolunteerRequest
+	if err := util.ParseJSON(ctx.Request, &volunteer); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(volunteer); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	order, err := h.orderStore.GetOrderById(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get order %w", err))
+		return
+	}
+	if order.ID == uuid.Nil {
+		util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("order %s not found", orderID))
+		return
+	}
+	if !order.PaidAt.Valid || order.CanceledAt.Valid || order.IsWaitlisted() {
+		util.WriteError(ctx.Writer, http.StatusConflict, fmt.Errorf("order %s must be paid, confirmed and not canceled", orderID))
+		return
+	}
+	passengerIDs := make([]uuid.UUID, 0, len(volunteer.PassengerIDs))
+	for _, passengerID := range volunteer.PassengerIDs {
+		passengerIDs = append(passengerIDs, uuid.MustParse(passengerID))
+	}
+	if _, err := h.orderStore.VolunteerPassengers(ctx, id, passengerIDs); err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to volunteer passengers %w", err))
+		return
+	}
+	passengers, err := h.orderStore.GetPassengersByOrderID(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get passengers %w", err))
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertOrderEntityToResponse(order, passengers)), "failed to response json")
+}
+
+func (h *Handler) CloseGate(ctx *gin.Context) {
+	flightID := ctx.Param("id")
+	id, err := uuid.Parse(flightID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", flightID, err))
+		return
+	}
+	closure, deniedBoardings, err := h.deniedBoardingService.CloseGate(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrFlightNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to close gate %w", err))
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.GateClosureResponse{
+		GateClosure:     closure,
+		DeniedBoardings: deniedBoardings,
+	}), "failed to response json")
+}
+
+func (h *Handler) GetDeniedBoardings(ctx *gin.Context) {
+	flightID := ctx.Param("id")
+	id, err := uuid.Parse(flightID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", flightID, err))
+		return
+	}
+	deniedBoardings, err := h.boardingStore.GetDeniedBoardingsByFlightID(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get denied boardings %w", err))
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, deniedBoardings), "failed to response json")
+}
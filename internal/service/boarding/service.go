@@ -0,0 +1,355 @@
+package boarding
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/broker"
+	"github.com/yuanyu90221/airline-order-system/internal/config"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/order"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+var ErrFlightNotFound = apperr.New(apperr.NotFound, "flight not found")
+
+// rebookSearchLimit: number of next flights on route tried for rebooking
+const rebookSearchLimit = 5
+
+// handle denied boarding at gate close
+type DeniedBoardingService struct {
+	boardingStore    types.BoardingStore
+	flightStore      types.FlightStore
+	seatMapStore     types.SeatMapStore
+	orderCacheStore  types.OrderCacheStore
+	flightCacheStore types.FlightCacheStore
+	agencyCacheStore types.AgencyCacheStore
+	mq               *broker.Broker
+	rules            types.DeniedBoardingRules
+}
+
+func NewDeniedBoardingService(boardingStore types.BoardingStore, flightStore types.FlightStore,
+	seatMapStore types.SeatMapStore, orderCacheStore types.OrderCacheStore, flightCacheStore types.FlightCacheStore,
+	agencyCacheStore types.AgencyCacheStore, mq *broker.Broker, rules types.DeniedBoardingRules) *DeniedBoardingService {
+	return &DeniedBoardingService{
+		boardingStore:    boardingStore,
+		flightStore:      flightStore,
+		seatMapStore:     seatMapStore,
+		orderCacheStore:  orderCacheStore,
+		flightCacheStore: flightCacheStore,
+		agencyCacheStore: agencyCacheStore,
+		mq:               mq,
+		rules:            rules,
+	}
+}
+
+/*
+*
+CloseGate: compare checked-in passengers against capacity, deny boarding on overflow and rebook them
+gate closure is claimed before any rebooking so concurrent or repeated calls never rebook twice,
+repeated calls return the recorded result and retry rebooking of denied passengers still without rebooked order
+*/
+func (service *DeniedBoardingService) CloseGate(ctx context.Context, flightID uuid.UUID) (types.GateClosure, []types.DeniedBoarding, error) {
+	flight, err := service.flightStore.GetFlightById(ctx, flightID)
+	if err != nil {
+		return types.GateClosure{}, nil, err
+	}
+	if flight.ID == uuid.Nil {
+		return types.GateClosure{}, nil, fmt.Errorf("flight %s %w", flightID, ErrFlightNotFound)
+	}
+	candidates, err := service.boardingStore.GetBoardingCandidates(ctx, flightID)
+	if err != nil {
+		return types.GateClosure{}, nil, err
+	}
+	closure, err := service.boardingStore.GetGateClosure(ctx, flightID)
+	if errors.Is(err, sql.ErrNoRows) {
+		closure, err = service.claimGateClosure(ctx, flight, candidates)
+	}
+	if err != nil {
+		return types.GateClosure{}, nil, err
+	}
+	deniedBoardings, err := service.boardingStore.GetDeniedBoardingsByFlightID(ctx, flightID)
+	if err != nil {
+		return types.GateClosure{}, nil, err
+	}
+	if !service.rebook(ctx, flight, candidates, deniedBoardings) {
+		return closure, deniedBoardings, nil
+	}
+	deniedBoardings, err = service.boardingStore.GetDeniedBoardingsByFlightID(ctx, flightID)
+	if err != nil {
+		return types.GateClosure{}, nil, err
+	}
+	return closure, deniedBoardings, nil
+}
+
+// claimGateClosure: record gate closure with denied passengers before rebooking, closure of concurrent call wins
+func (service *DeniedBoardingService) claimGateClosure(ctx context.Context, flight types.FlightResponse,
+	candidates []types.BoardingCandidate) (types.GateClosure, error) {
+	capacity, err := service.physicalCapacity(ctx, flight)
+	if err != nil {
+		return types.GateClosure{}, err
+	}
+	deniedBoardings := []types.DeniedBoarding{}
+	overflow := len(candidates) - int(capacity)
+	if overflow > 0 {
+		for _, candidate := range SelectDeniedCandidates(candidates, overflow, service.rules.SelectionOrder) {
+			denied := types.DeniedBoarding{
+				ID:          uuid.New(),
+				FlightID:    flight.ID,
+				OrderID:     candidate.Passenger.OrderID,
+				PassengerID: candidate.Passenger.ID,
+				IsVoluntary: candidate.Passenger.VolunteeredAt.Valid,
+			}
+			denied.Compensation = service.compensation(denied, 0)
+			deniedBoardings = append(deniedBoardings, denied)
+		}
+	}
+	closure, claimed, err := service.boardingStore.CloseGate(ctx, types.GateClosure{
+		FlightID:  flight.ID,
+		Capacity:  capacity,
+		CheckedIn: int32(len(candidates)),
+		Denied:    int32(len(deniedBoardings)),
+	}, deniedBoardings)
+	if err != nil {
+		return types.GateClosure{}, err
+	}
+	if !claimed {
+		return service.boardingStore.GetGateClosure(ctx, flight.ID)
+	}
+	return closure, nil
+}
+
+// physicalCapacity: flight capacity, limited by bookable seats when flight has seat map
+func (service *DeniedBoardingService) physicalCapacity(ctx context.Context, flight types.FlightResponse) (int32, error) {
+	capacity := flight.Capacity
+	seats, err := service.seatMapStore.GetFlightSeats(ctx, flight.ID)
+	if err != nil {
+		return 0, err
+	}
+	if len(seats) == 0 {
+		return capacity, nil
+	}
+	bookable := int32(0)
+	for _, seat := range seats {
+		if !seat.IsBlocked {
+			bookable++
+		}
+	}
+	if bookable < capacity {
+		return bookable, nil
+	}
+	return capacity, nil
+}
+
+// rebook: group denied passengers without rebooked order by order and rebook each group onto next flight on same route,
+// false when no passenger is left to rebook
+func (service *DeniedBoardingService) rebook(ctx context.Context, flight types.FlightResponse,
+	candidates []types.BoardingCandidate, deniedBoardings []types.DeniedBoarding) bool {
+	passengers := make(map[uuid.UUID]types.Passenger, len(candidates))
+	orders := make(map[uuid.UUID]types.BoardingCandidate, len(candidates))
+	for _, candidate := range candidates {
+		passengers[candidate.Passenger.ID] = candidate.Passenger
+		orders[candidate.Passenger.OrderID] = candidate
+	}
+	groups := map[uuid.UUID][]types.Passenger{}
+	orderIDs := []uuid.UUID{}
+	for _, denied := range deniedBoardings {
+		passenger, ok := passengers[denied.PassengerID]
+		if denied.RebookedOrderID.Valid || !ok {
+			continue
+		}
+		if _, ok := groups[denied.OrderID]; !ok {
+			orderIDs = append(orderIDs, denied.OrderID)
+		}
+		groups[denied.OrderID] = append(groups[denied.OrderID], passenger)
+	}
+	if len(orderIDs) == 0 {
+		return false
+	}
+	nextFlights, err := service.flightStore.GetNextFlightsOnRoute(ctx, flight.Departure, flight.Destination, flight.FlightDate, rebookSearchLimit)
+	if err != nil {
+		log.Printf("failed to find next flights of flight %s %v", flight.ID, err)
+		return false
+	}
+	for _, orderID := range orderIDs {
+		rebookedFlight, err := service.rebookGroup(ctx, flight, nextFlights, orders[orderID], groups[orderID])
+		if err != nil {
+			// passengers stay denied without rebooking, handled by ground staff or next gate close call
+			log.Printf("failed to rebook order %s from flight %s %v", orderID, flight.ID, err)
+			continue
+		}
+		// rebooked passengers leave flight, their seats count against allotment of agency on rebooked flight instead
+		agency.TransferOrderAllotment(ctx, service.agencyCacheStore, orders[orderID].OrderAgencyID, flight.ID, rebookedFlight,
+			int64(len(groups[orderID])))
+	}
+	return true
+}
+
+/*
+*
+rebookGroup: reserve confirmed seats with lua counter on first next flight having enough seats, claim denied passengers of order
+for rebooked order, then publish prepaid order, seats and claim are given back when order could not be published,
+flights only able to waitlist the group are skipped since denied passengers must fly on a confirmed seat
+*/
+func (service *DeniedBoardingService) rebookGroup(ctx context.Context, flight types.FlightResponse, nextFlights []types.Flight,
+	original types.BoardingCandidate, group []types.Passenger) (types.Flight, error) {
+	orderID := original.Passenger.OrderID
+	for _, nextFlight := range nextFlights {
+		flightInfo, err := service.flightCacheStore.GetFlightCacheInfo(ctx, nextFlight.ID.String())
+		if err != nil {
+			flightInfo = nextFlight
+		}
+		cacheParam := types.OrderCacheParam{
+			FlightID:         nextFlight.ID.String(),
+			CurrentTotal:     int64(flightInfo.AvailableSeats),
+			CurrentWait:      int64(flightInfo.WaitSeats),
+			CurrentWaitOrder: int64(flightInfo.NextWaitOrder),
+		}
+		result, err := service.orderCacheStore.CreateOrder(ctx, types.OrderCacheCreateParam{
+			OrderCacheParam: cacheParam,
+			TicketNumbers:   int64(len(group)),
+		})
+		if err != nil {
+			return types.Flight{}, err
+		}
+		if !result.IsValid {
+			continue
+		}
+		if result.IsWait {
+			service.releaseSeats(ctx, cacheParam, int64(len(group)), result.IsWait)
+			continue
+		}
+		rebookedOrderID := uuid.New()
+		rebooking := types.DeniedBoardingRebooking{
+			FlightID:         flight.ID,
+			OrderID:          orderID,
+			RebookedFlightID: uuid.NullUUID{UUID: nextFlight.ID, Valid: true},
+			RebookedOrderID:  uuid.NullUUID{UUID: rebookedOrderID, Valid: true},
+		}
+		rebooking.Compensation = service.compensation(types.DeniedBoarding{RebookedFlightID: rebooking.RebookedFlightID},
+			nextFlight.FlightDate.Sub(flight.FlightDate))
+		claimed, err := service.boardingStore.UpdateRebooking(ctx, rebooking, uuid.NullUUID{})
+		if err != nil || !claimed {
+			service.releaseSeats(ctx, cacheParam, int64(len(group)), result.IsWait)
+			return types.Flight{}, err
+		}
+		if err := service.publishRebooking(ctx, nextFlight, rebookedOrderID, original, result, group); err != nil {
+			service.releaseSeats(ctx, cacheParam, int64(len(group)), result.IsWait)
+			_, resetErr := service.boardingStore.UpdateRebooking(ctx, types.DeniedBoardingRebooking{
+				FlightID:     flight.ID,
+				OrderID:      orderID,
+				Compensation: service.compensation(types.DeniedBoarding{}, 0),
+			}, rebooking.RebookedOrderID)
+			if resetErr != nil {
+				log.Printf("failed to reset rebooking of order %s %v", orderID, resetErr)
+			}
+			return types.Flight{}, err
+		}
+		return nextFlight, nil
+	}
+	return types.Flight{}, fmt.Errorf("no next flight with %d seats left", len(group))
+}
+
+// publishRebooking: send prepaid order of rebooked passengers to order queue,
+// rebooked order keeps customer, agency and fare of original order
+func (service *DeniedBoardingService) publishRebooking(ctx context.Context, nextFlight types.Flight, rebookedOrderID uuid.UUID,
+	original types.BoardingCandidate, result types.OrderCacheResult, group []types.Passenger) error {
+	pnr, err := order.ReservePNR(ctx, service.orderCacheStore, rebookedOrderID)
+	if err != nil {
+		return err
+	}
+	passengers := make([]types.PassengerRequest, 0, len(group))
+	for _, passenger := range group {
+		passengers = append(passengers, types.PassengerRequest{
+			Name:           passenger.Name,
+			DateOfBirth:    passenger.DateOfBirth.Format(time.DateOnly),
+			DocumentNumber: passenger.DocumentNumber,
+		})
+	}
+	requestEvent := types.CreateOrderEvent{
+		ID:             rebookedOrderID.String(),
+		FlightID:       nextFlight.ID.String(),
+		TicketNumbers:  int64(len(group)),
+		AvailableSeats: result.CurrentTotal,
+		WaitOrder:      -1,
+		WaitSeats:      result.CurrentWait,
+		PNR:            pnr,
+		Passengers:     passengers,
+		Prepaid:        true,
+		Fare:           original.OrderFare,
+	}
+	if original.OrderCustomerID.Valid {
+		requestEvent.CustomerID = original.OrderCustomerID.UUID.String()
+	}
+	if original.OrderAgencyID.Valid {
+		requestEvent.AgencyID = original.OrderAgencyID.UUID.String()
+	}
+	data, err := json.Marshal(requestEvent)
+	if err != nil {
+		order.ReleasePNR(ctx, service.orderCacheStore, pnr, rebookedOrderID)
+		return fmt.Errorf("marshal data error %w", err)
+	}
+	if err := service.mq.SendMessageToQueue(ctx, config.AppConfig.OrderQueueName, data); err != nil {
+		order.ReleasePNR(ctx, service.orderCacheStore, pnr, rebookedOrderID)
+		return fmt.Errorf("send rabbitmq error %w", err)
+	}
+	return nil
+}
+
+// releaseSeats: give back seats reserved for rebooking which was not published
+func (service *DeniedBoardingService) releaseSeats(ctx context.Context, cacheParam types.OrderCacheParam, ticketNumbers int64, isWait bool) {
+	_, err := service.orderCacheStore.ReleaseOrder(ctx, types.OrderCacheReleaseParam{
+		OrderCacheParam: cacheParam,
+		TicketNumbers:   ticketNumbers,
+		IsWait:          isWait,
+	})
+	if err != nil {
+		log.Printf("failed to release %d seats on flight %s %v", ticketNumbers, cacheParam.FlightID, err)
+	}
+}
+
+// compensation: volunteers get agreed amount, others by delay of rebooked flight
+func (service *DeniedBoardingService) compensation(denied types.DeniedBoarding, delay time.Duration) float64 {
+	if denied.IsVoluntary {
+		return service.rules.VolunteerCompensation
+	}
+	if denied.RebookedFlightID.Valid && delay <= service.rules.LongDelay {
+		return service.rules.ShortDelayCompensation
+	}
+	return service.rules.LongDelayCompensation
+}
+
+/*
+*
+SelectDeniedCandidates: pick count passengers to deny boarding
+volunteers first, then passengers without seat, then lowest priority by selection order
+*/
+func SelectDeniedCandidates(candidates []types.BoardingCandidate, count int, selectionOrder string) []types.BoardingCandidate {
+	sorted := make([]types.BoardingCandidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		left, right := sorted[i].Passenger, sorted[j].Passenger
+		if left.VolunteeredAt.Valid != right.VolunteeredAt.Valid {
+			return left.VolunteeredAt.Valid
+		}
+		if left.SeatNumber.Valid != right.SeatNumber.Valid {
+			return !left.SeatNumber.Valid
+		}
+		if selectionOrder == types.SelectionLastBooking {
+			return sorted[i].OrderCreatedAt.After(sorted[j].OrderCreatedAt)
+		}
+		return left.CheckinSequence.Int32 > right.CheckinSequence.Int32
+	})
+	if count > len(sorted) {
+		count = len(sorted)
+	}
+	return sorted[:count]
+}
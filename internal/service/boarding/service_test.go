@@ -0,0 +1,82 @@
+package boarding
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// candidate: checked-in passenger named name, seat and volunteer flags as given
+func candidate(name string, sequence int32, bookedAt time.Time, seated bool, volunteered bool) types.BoardingCandidate {
+	passenger := types.Passenger{
+		ID:              uuid.New(),
+		Name:            name,
+		CheckinSequence: sql.NullInt32{Int32: sequence, Valid: true},
+	}
+	if seated {
+		passenger.SeatNumber = sql.NullString{String: "1A", Valid: true}
+	}
+	if volunteered {
+		passenger.VolunteeredAt = sql.NullTime{Time: bookedAt, Valid: true}
+	}
+	return types.BoardingCandidate{Passenger: passenger, OrderCreatedAt: bookedAt}
+}
+
+func TestSelectDeniedCandidates(t *testing.T) {
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	early := candidate("early", 1, day.Add(3*time.Hour), true, false)
+	middle := candidate("middle", 2, day.Add(1*time.Hour), true, false)
+	late := candidate("late", 3, day.Add(2*time.Hour), true, false)
+	unseated := candidate("unseated", 1, day, false, false)
+	volunteer := candidate("volunteer", 1, day, true, true)
+	tests := []struct {
+		name           string
+		candidates     []types.BoardingCandidate
+		count          int
+		selectionOrder string
+		want           []string
+	}{
+		{name: "last check-in first", candidates: []types.BoardingCandidate{early, middle, late},
+			count: 2, selectionOrder: types.SelectionLastCheckin, want: []string{"late", "middle"}},
+		{name: "last booking first", candidates: []types.BoardingCandidate{early, middle, late},
+			count: 2, selectionOrder: types.SelectionLastBooking, want: []string{"early", "late"}},
+		{name: "without seat before seated", candidates: []types.BoardingCandidate{late, unseated},
+			count: 1, selectionOrder: types.SelectionLastCheckin, want: []string{"unseated"}},
+		{name: "volunteers before without seat", candidates: []types.BoardingCandidate{late, unseated, volunteer},
+			count: 2, selectionOrder: types.SelectionLastCheckin, want: []string{"volunteer", "unseated"}},
+		{name: "count over candidates", candidates: []types.BoardingCandidate{early, late},
+			count: 5, selectionOrder: types.SelectionLastCheckin, want: []string{"late", "early"}},
+		{name: "nothing to deny", candidates: []types.BoardingCandidate{early, late},
+			count: 0, selectionOrder: types.SelectionLastCheckin, want: []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected := SelectDeniedCandidates(test.candidates, test.count, test.selectionOrder)
+			got := make([]string, 0, len(selected))
+			for _, selectedCandidate := range selected {
+				got = append(got, selectedCandidate.Passenger.Name)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("SelectDeniedCandidates() = %v, want %v", got, test.want)
+			}
+			for idx := range got {
+				if got[idx] != test.want[idx] {
+					t.Errorf("SelectDeniedCandidates() = %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSelectDeniedCandidatesKeepsInput(t *testing.T) {
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	candidates := []types.BoardingCandidate{candidate("first", 1, day, true, false), candidate("second", 2, day, true, false)}
+	SelectDeniedCandidates(candidates, 1, types.SelectionLastCheckin)
+	if candidates[0].Passenger.Name != "first" || candidates[1].Passenger.Name != "second" {
+		t.Errorf("SelectDeniedCandidates() reordered input to %s, %s", candidates[0].Passenger.Name, candidates[1].Passenger.Name)
+	}
+}
@@ -0,0 +1,251 @@
+package boarding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// pendingGateCloseLookback: flights departed longer than this are not closed by worker
+const pendingGateCloseLookback = 24 * time.Hour
+
+type BoardingStore struct {
+	db *sql.DB
+}
+
+func NewBoardingStore(db *sql.DB) *BoardingStore {
+	return &BoardingStore{db: db}
+}
+
+/*
+*
+GetBoardingCandidates: checked-in passengers of paid and not canceled orders on flight_id,
+fare of multi-segment order covers all segments so flight price is taken for them
+*/
+func (boardingStore *BoardingStore) GetBoardingCandidates(ctx context.Context, flightID uuid.UUID) ([]types.BoardingCandidate, error) {
+	queryBuilder := sq.Select("p.id", "p.order_id", "p.name", "p.date_of_birth", "p.document_number", "p.seat_number",
+		"p.checked_in_at", "p.checkin_sequence", "p.volunteered_at", "o.created_at", "o.customer_id", "o.agency_id",
+		"CASE WHEN EXISTS (SELECT 1 FROM order_segments s WHERE s.order_id = o.id) THEN f.price ELSE COALESCE(o.fare, f.price) END").
+		From("passengers p").Join("orders o ON o.id = p.order_id").Join("flights f ON f.id = o.flight_id").
+		Where(sq.Eq{"o.flight_id": flightID, "o.canceled_at": nil}).
+		Where(sq.NotEq{"o.paid_at": nil, "p.checked_in_at": nil}).
+		OrderBy("p.checkin_sequence ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := boardingStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query boarding candidates %w", err)
+	}
+	defer rows.Close()
+	candidates := []types.BoardingCandidate{}
+	for rows.Next() {
+		var candidate types.BoardingCandidate
+		err := rows.Scan(
+			&candidate.Passenger.ID,
+			&candidate.Passenger.OrderID,
+			&candidate.Passenger.Name,
+			&candidate.Passenger.DateOfBirth,
+			&candidate.Passenger.DocumentNumber,
+			&candidate.Passenger.SeatNumber,
+			&candidate.Passenger.CheckedInAt,
+			&candidate.Passenger.CheckinSequence,
+			&candidate.Passenger.VolunteeredAt,
+			&candidate.OrderCreatedAt,
+			&candidate.OrderCustomerID,
+			&candidate.OrderAgencyID,
+			&candidate.OrderFare,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan boarding candidate failed %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
+
+/*
+*
+GetFlightsPendingGateClose: flights departing before departBefore without gate closure record
+*/
+func (boardingStore *BoardingStore) GetFlightsPendingGateClose(ctx context.Context, departBefore time.Time) ([]uuid.UUID, error) {
+	queryBuilder := sq.Select("f.id").From("flights f").
+		LeftJoin("gate_closures g ON g.flight_id = f.id").
+		Where(sq.Eq{"g.flight_id": nil}).
+		Where(sq.LtOrEq{"f.flight_date": departBefore}).
+		Where(sq.Gt{"f.flight_date": departBefore.Add(-pendingGateCloseLookback)}).
+		OrderBy("f.flight_date ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := boardingStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending gate close flights %w", err)
+	}
+	defer rows.Close()
+	flightIDs := []uuid.UUID{}
+	for rows.Next() {
+		var flightID uuid.UUID
+		if err := rows.Scan(&flightID); err != nil {
+			return nil, fmt.Errorf("scan flight id failed %w", err)
+		}
+		flightIDs = append(flightIDs, flightID)
+	}
+	return flightIDs, rows.Err()
+}
+
+func (boardingStore *BoardingStore) GetGateClosure(ctx context.Context, flightID uuid.UUID) (types.GateClosure, error) {
+	queryBuilder := sq.Select("flight_id", "capacity", "checked_in", "denied", "closed_at").From("gate_closures").
+		Where(sq.Eq{"flight_id": flightID}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.GateClosure{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	var closure types.GateClosure
+	err = boardingStore.db.QueryRowContext(ctx, query, args...).Scan(
+		&closure.FlightID,
+		&closure.Capacity,
+		&closure.CheckedIn,
+		&closure.Denied,
+		&closure.ClosedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.GateClosure{}, fmt.Errorf("no gate closure with flight id %s %w", flightID, err)
+		}
+		return types.GateClosure{}, fmt.Errorf("failed to query gate closure %w", err)
+	}
+	return closure, nil
+}
+
+/*
+*
+CloseGate: claim gate closure of flight and record denied boardings in one transaction,
+false when gate of flight was already closed by another call
+*/
+func (boardingStore *BoardingStore) CloseGate(ctx context.Context, closure types.GateClosure,
+	deniedBoardings []types.DeniedBoarding) (types.GateClosure, bool, error) {
+	tx, err := boardingStore.db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.GateClosure{}, false, fmt.Errorf("create db tx failed %w", err)
+	}
+	queryBuilder := sq.Insert("gate_closures").Columns("flight_id", "capacity", "checked_in", "denied").
+		Values(closure.FlightID, closure.Capacity, closure.CheckedIn, closure.Denied).
+		Suffix("ON CONFLICT (flight_id) DO NOTHING RETURNING flight_id, capacity, checked_in, denied, closed_at;").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		tx.Rollback()
+		return types.GateClosure{}, false, fmt.Errorf("gate closure query builder failed %w", err)
+	}
+	var result types.GateClosure
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&result.FlightID,
+		&result.Capacity,
+		&result.CheckedIn,
+		&result.Denied,
+		&result.ClosedAt,
+	)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.GateClosure{}, false, nil
+		}
+		return types.GateClosure{}, false, fmt.Errorf("insert gate closure failed %w", err)
+	}
+	if len(deniedBoardings) > 0 {
+		insertBuilder := sq.Insert("denied_boardings").Columns("id", "flight_id", "order_id", "passenger_id",
+			"is_voluntary", "compensation", "rebooked_flight_id", "rebooked_order_id")
+		for _, denied := range deniedBoardings {
+			insertBuilder = insertBuilder.Values(denied.ID, denied.FlightID, denied.OrderID, denied.PassengerID,
+				denied.IsVoluntary, denied.Compensation, denied.RebookedFlightID, denied.RebookedOrderID)
+		}
+		query, args, err := insertBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			tx.Rollback()
+			return types.GateClosure{}, false, fmt.Errorf("denied boardings query builder failed %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			tx.Rollback()
+			return types.GateClosure{}, false, fmt.Errorf("insert denied boardings failed %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return types.GateClosure{}, false, err
+	}
+	return result, true, nil
+}
+
+/*
+*
+UpdateRebooking: set rebooked flight, order and compensation of denied passengers of order when rebooked order is still
+expectedOrderID, invalid expectedOrderID claims passengers not rebooked yet, false when another call changed them first,
+volunteers keep agreed compensation
+*/
+func (boardingStore *BoardingStore) UpdateRebooking(ctx context.Context, rebooking types.DeniedBoardingRebooking,
+	expectedOrderID uuid.NullUUID) (bool, error) {
+	condition := sq.Eq{"flight_id": rebooking.FlightID, "order_id": rebooking.OrderID, "rebooked_order_id": nil}
+	if expectedOrderID.Valid {
+		condition["rebooked_order_id"] = expectedOrderID.UUID
+	}
+	queryBuilder := sq.Update("denied_boardings").
+		Set("rebooked_flight_id", rebooking.RebookedFlightID).
+		Set("rebooked_order_id", rebooking.RebookedOrderID).
+		Set("compensation", sq.Expr("CASE WHEN is_voluntary THEN compensation ELSE ? END", rebooking.Compensation)).
+		Where(condition).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("update rebooking query builder failed %w", err)
+	}
+	result, err := boardingStore.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("update rebooking of order %s failed %w", rebooking.OrderID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (boardingStore *BoardingStore) GetDeniedBoardingsByFlightID(ctx context.Context, flightID uuid.UUID) ([]types.DeniedBoarding, error) {
+	queryBuilder := sq.Select("id", "flight_id", "order_id", "passenger_id", "is_voluntary", "compensation",
+		"rebooked_flight_id", "rebooked_order_id", "created_at").From("denied_boardings").
+		Where(sq.Eq{"flight_id": flightID}).OrderBy("created_at ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := boardingStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query denied boardings %w", err)
+	}
+	defer rows.Close()
+	deniedBoardings := []types.DeniedBoarding{}
+	for rows.Next() {
+		var denied types.DeniedBoarding
+		err := rows.Scan(
+			&denied.ID,
+			&denied.FlightID,
+			&denied.OrderID,
+			&denied.PassengerID,
+			&denied.IsVoluntary,
+			&denied.Compensation,
+			&denied.RebookedFlightID,
+			&denied.RebookedOrderID,
+			&denied.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan denied boarding failed %w", err)
+		}
+		deniedBoardings = append(deniedBoardings, denied)
+	}
+	return deniedBoardings, rows.Err()
+}
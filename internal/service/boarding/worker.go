@@ -0,0 +1,61 @@
+package boarding
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// close gates for flights reaching gate close time
+type GateCloseWorker struct {
+	deniedBoardingService types.DeniedBoardingService
+	boardingStore         types.BoardingStore
+	gateCloseBefore       time.Duration
+	interval              time.Duration
+}
+
+func NewGateCloseWorker(deniedBoardingService types.DeniedBoardingService, boardingStore types.BoardingStore,
+	gateCloseBefore time.Duration, interval time.Duration) *GateCloseWorker {
+	return &GateCloseWorker{
+		deniedBoardingService: deniedBoardingService,
+		boardingStore:         boardingStore,
+		gateCloseBefore:       gateCloseBefore,
+		interval:              interval,
+	}
+}
+
+func (worker *GateCloseWorker) Run(ctx context.Context) error {
+	log.Println("gate close worker start")
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("gate close worker end")
+			return nil
+		case <-ticker.C:
+			worker.closeDueGates(ctx)
+		}
+	}
+}
+
+func (worker *GateCloseWorker) closeDueGates(ctx context.Context) {
+	flightIDs, err := worker.boardingStore.GetFlightsPendingGateClose(ctx, time.Now().UTC().Add(worker.gateCloseBefore))
+	if err != nil {
+		log.Printf("failed to get flights pending gate close %v", err)
+		return
+	}
+	for _, flightID := range flightIDs {
+		closure, _, err := worker.deniedBoardingService.CloseGate(ctx, flightID)
+		if err != nil {
+			log.Printf("failed to close gate for flight %s %v", flightID, err)
+			continue
+		}
+		if closure.Denied > 0 {
+			log.Printf("flight %s gate closed, checked in %d, capacity %d, denied %d",
+				flightID, closure.CheckedIn, closure.Capacity, closure.Denied)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package checkin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/pdf417"
+)
+
+// BCBPData: mandatory items of IATA bar coded boarding pass (Resolution 792), single leg
+type BCBPData struct {
+	PassengerName string
+	PNR           string
+	From          string
+	To            string
+	CarrierCode   string
+	FlightNumber  string
+	// FlightDate: departure in timezone of From airport, julian date is taken from its local date
+	FlightDate      time.Time
+	Compartment     byte
+	SeatNumber      string
+	CheckinSequence int32
+}
+
+// EncodeBCBP: encode data into 60 characters M1 format string
+func EncodeBCBP(data BCBPData) string {
+	var builder strings.Builder
+	builder.WriteString("M1")
+	builder.WriteString(fixedWidth(formatBCBPName(data.PassengerName), 20))
+	builder.WriteString("E")
+	builder.WriteString(fixedWidth(strings.ToUpper(data.PNR), 7))
+	builder.WriteString(fixedWidth(strings.ToUpper(data.From), 3))
+	builder.WriteString(fixedWidth(strings.ToUpper(data.To), 3))
+	builder.WriteString(fixedWidth(strings.ToUpper(data.CarrierCode), 3))
+	builder.WriteString(formatFlightNumber(data.FlightNumber))
+	builder.WriteString(fmt.Sprintf("%03d", data.FlightDate.YearDay()))
+	builder.WriteByte(data.Compartment)
+	builder.WriteString(formatSeatNumber(data.SeatNumber))
+	builder.WriteString(fmt.Sprintf("%04d ", data.CheckinSequence%10000))
+	// passenger status 1: checked in, no variable size field
+	builder.WriteString("100")
+	return builder.String()
+}
+
+// RenderBCBPPNG: render bcbp string as PDF417 png, base64 encoded
+func RenderBCBPPNG(bcbp string) (string, error) {
+	code, err := pdf417.Encode(bcbp, 4)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode pdf417 %w", err)
+	}
+	bounds := code.Bounds()
+	code, err = barcode.Scale(code, bounds.Dx()*2, bounds.Dy()*2)
+	if err != nil {
+		return "", fmt.Errorf("failed to scale pdf417 %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code); err != nil {
+		return "", fmt.Errorf("failed to encode png %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// CompartmentOf: map cabin name to compartment code
+func CompartmentOf(cabin string) byte {
+	switch strings.ToLower(cabin) {
+	case "first":
+		return 'F'
+	case "business":
+		return 'J'
+	case "premium", "premium_economy":
+		return 'W'
+	default:
+		return 'Y'
+	}
+}
+
+// formatBCBPName: "John Smith" -> "SMITH/JOHN"
+func formatBCBPName(name string) string {
+	fields := strings.Fields(strings.ToUpper(name))
+	if len(fields) < 2 {
+		return strings.Join(fields, "")
+	}
+	return fields[len(fields)-1] + "/" + strings.Join(fields[:len(fields)-1], " ")
+}
+
+// formatFlightNumber: "123" -> "0123 ", "123A" -> "0123A"
+func formatFlightNumber(flightNumber string) string {
+	digits := strings.TrimRightFunc(flightNumber, func(r rune) bool { return r < '0' || r > '9' })
+	suffix := strings.TrimPrefix(flightNumber, digits)
+	if digits == "" {
+		return "     "
+	}
+	digits = strings.TrimLeft(digits, "0")
+	return zeroPad(digits, 4) + fixedWidth(suffix, 1)
+}
+
+// formatSeatNumber: "12A" -> "012A", empty seat keeps blank
+func formatSeatNumber(seatNumber string) string {
+	if seatNumber == "" {
+		return "    "
+	}
+	row := seatNumber[:len(seatNumber)-1]
+	letter := seatNumber[len(seatNumber)-1:]
+	return zeroPad(row, 3) + strings.ToUpper(letter)
+}
+
+func fixedWidth(value string, width int) string {
+	if len(value) > width {
+		return value[:width]
+	}
+	return value + strings.Repeat(" ", width-len(value))
+}
+
+func zeroPad(value string, width int) string {
+	if len(value) >= width {
+		return value[len(value)-width:]
+	}
+	return strings.Repeat("0", width-len(value)) + value
+}
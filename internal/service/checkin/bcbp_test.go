@@ -0,0 +1,104 @@
+package checkin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodeBCBP(t *testing.T) {
+	taipei := time.FixedZone("UTC+8", 8*60*60)
+	base := BCBPData{
+		PassengerName:   "John Smith",
+		PNR:             "abc123",
+		From:            "tpe",
+		To:              "nrt",
+		CarrierCode:     "ZZ",
+		FlightNumber:    "123",
+		FlightDate:      time.Date(2024, 2, 1, 9, 30, 0, 0, taipei),
+		Compartment:     'Y',
+		SeatNumber:      "12A",
+		CheckinSequence: 7,
+	}
+	tests := []struct {
+		name   string
+		modify func(data *BCBPData)
+		want   string
+	}{
+		{
+			name:   "mandatory items",
+			modify: func(data *BCBPData) {},
+			want:   "M1SMITH/JOHN          EABC123 TPENRTZZ 0123 032Y012A0007 100",
+		},
+		{
+			name: "local date after midnight of utc date",
+			modify: func(data *BCBPData) {
+				data.FlightDate = time.Date(2024, 3, 31, 16, 30, 0, 0, time.UTC).In(taipei)
+			},
+			want: "M1SMITH/JOHN          EABC123 TPENRTZZ 0123 092Y012A0007 100",
+		},
+		{
+			name: "long name truncated to 20",
+			modify: func(data *BCBPData) {
+				data.PassengerName = "Maria Fernanda Alexandra Montgomery-Wellington"
+			},
+			want: "M1MONTGOMERY-WELLINGTOEABC123 TPENRTZZ 0123 032Y012A0007 100",
+		},
+		{
+			name: "single name",
+			modify: func(data *BCBPData) {
+				data.PassengerName = "madonna"
+			},
+			want: "M1MADONNA             EABC123 TPENRTZZ 0123 032Y012A0007 100",
+		},
+		{
+			name: "flight number with suffix",
+			modify: func(data *BCBPData) {
+				data.FlightNumber = "0123A"
+			},
+			want: "M1SMITH/JOHN          EABC123 TPENRTZZ 0123A032Y012A0007 100",
+		},
+		{
+			name: "short flight number",
+			modify: func(data *BCBPData) {
+				data.FlightNumber = "7"
+			},
+			want: "M1SMITH/JOHN          EABC123 TPENRTZZ 0007 032Y012A0007 100",
+		},
+		{
+			name: "without seat",
+			modify: func(data *BCBPData) {
+				data.SeatNumber = ""
+			},
+			want: "M1SMITH/JOHN          EABC123 TPENRTZZ 0123 032Y    0007 100",
+		},
+		{
+			name: "sequence over four digits",
+			modify: func(data *BCBPData) {
+				data.CheckinSequence = 12345
+			},
+			want: "M1SMITH/JOHN          EABC123 TPENRTZZ 0123 032Y012A2345 100",
+		},
+		{
+			name: "three letter carrier and business seat",
+			modify: func(data *BCBPData) {
+				data.CarrierCode = "zzz"
+				data.Compartment = CompartmentOf("Business")
+				data.SeatNumber = "3k"
+			},
+			want: "M1SMITH/JOHN          EABC123 TPENRTZZZ0123 032J003K0007 100",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := base
+			test.modify(&data)
+			got := EncodeBCBP(data)
+			if len(got) != 60 {
+				t.Errorf("EncodeBCBP() length = %d, want 60", len(got))
+			}
+			if got != test.want {
+				t.Errorf("EncodeBCBP() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package checkin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type CacheStore struct {
+	rdb *redis.Client
+}
+
+func NewCacheStore(rdb *redis.Client) *CacheStore {
+	return &CacheStore{
+		rdb: rdb,
+	}
+}
+
+/*
+*
+IncrCheckedIn: add count checked-in passengers on flight_id after they are saved, return current checked-in total
+*/
+func (cache *CacheStore) IncrCheckedIn(ctx context.Context, flightID string, count int64) (int64, error) {
+	total, err := cache.rdb.IncrBy(ctx, fmt.Sprintf("%s:checked_in", flightID), count).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to incr checked_in with flightId: %s, %w", flightID, err)
+	}
+	return total, nil
+}
+
+/*
+*
+GetCheckedInCount: get current checked-in passengers on flight_id
+*/
+func (cache *CacheStore) GetCheckedInCount(ctx context.Context, flightID string) (int64, error) {
+	total, err := cache.rdb.Get(ctx, fmt.Sprintf("%s:checked_in", flightID)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get checked_in with flightId: %s, %w", flightID, err)
+	}
+	return total, nil
+}
@@ -0,0 +1,118 @@
+package checkin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/service/airport"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	checkinService   types.CheckinService
+	seatMapStore     types.SeatMapStore
+	airportDirectory types.AirportDirectory
+	carrierCode      string
+}
+
+func NewHandler(checkinService types.CheckinService, seatMapStore types.SeatMapStore, airportDirectory types.AirportDirectory,
+	carrierCode string) *Handler {
+	return &Handler{
+		checkinService:   checkinService,
+		seatMapStore:     seatMapStore,
+		airportDirectory: airportDirectory,
+		carrierCode:      carrierCode,
+	}
+}
+
+func (h *Handler) RegisterOrderRoute(router *gin.RouterGroup) {
+	router.POST("/:id/checkin", h.CheckinOrder)
+}
+
+func (h *Handler) CheckinOrder(ctx *gin.Context) {
+	orderID := ctx.Param("id")
+	id, err := uuid.Parse(orderID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", orderID, err))
+		return
+	}
+	order, flight, passengers, err := h.checkinService.CheckinOrder(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOrderNotFound):
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+		case errors.Is(err, ErrOrderNotEligible), errors.Is(err, ErrCheckinWindowClose), errors.Is(err, ErrNoSeatsLeft):
+			util.WriteError(ctx.Writer, http.StatusConflict, err)
+		default:
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to check-in order %w", err))
+		}
+		return
+	}
+	seats, err := h.seatMapStore.GetFlightSeats(ctx, flight.ID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	cabins := make(map[string]string, len(seats))
+	for _, seat := range seats {
+		cabins[seat.SeatNumber] = seat.Cabin
+	}
+	response := types.CheckinResponse{
+		OrderID:        order.ID.String(),
+		PNR:            order.PNR.String,
+		BoardingPasses: make([]types.BoardingPassResponse, 0, len(passengers)),
+	}
+	// boarding pass carries date of departure at departure airport, flights from unknown airports keep utc date
+	location, err := h.airportDirectory.Location(ctx, flight.Departure)
+	if err != nil {
+		if !errors.Is(err, airport.ErrAirportNotFound) {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			return
+		}
+		location = time.UTC
+	}
+	// flights created before flight numbers were introduced fall back to configured carrier
+	carrierCode := flight.CarrierCode
+	if carrierCode == "" {
+		carrierCode = h.carrierCode
+	}
+	for _, passenger := range passengers {
+		boardingPass := types.BoardingPassResponse{
+			PassengerID:      passenger.ID.String(),
+			PassengerName:    passenger.Name,
+			PNR:              order.PNR.String,
+			TicketNumber:     passenger.TicketNumber.String,
+			FlightID:         flight.ID.String(),
+			FlightDesignator: flight.FlightDesignator,
+			Departure:        flight.Departure,
+			Destination:      flight.Destination,
+			FlightDate:       flight.FlightDate,
+			SeatNumber:       passenger.SeatNumber.String,
+			CheckinSequence:  passenger.CheckinSequence.Int32,
+		}
+		boardingPass.Barcode = EncodeBCBP(BCBPData{
+			PassengerName:   passenger.Name,
+			PNR:             order.PNR.String,
+			From:            flight.Departure,
+			To:              flight.Destination,
+			CarrierCode:     carrierCode,
+			FlightNumber:    flight.FlightNumber,
+			FlightDate:      flight.FlightDate.In(location),
+			Compartment:     CompartmentOf(cabins[passenger.SeatNumber.String]),
+			SeatNumber:      passenger.SeatNumber.String,
+			CheckinSequence: passenger.CheckinSequence.Int32,
+		})
+		boardingPass.BarcodePNG, err = RenderBCBPPNG(boardingPass.Barcode)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			return
+		}
+		response.BoardingPasses = append(response.BoardingPasses, boardingPass)
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, response), "failed to response json")
+}
@@ -0,0 +1,194 @@
+package checkin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+var (
+	ErrOrderNotFound      = apperr.New(apperr.NotFound, "order not found")
+	ErrOrderNotEligible   = apperr.New(apperr.Conflict, "order is not eligible for check-in")
+	ErrCheckinWindowClose = apperr.New(apperr.Conflict, "check-in window is not open")
+	ErrNoSeatsLeft        = apperr.New(apperr.Conflict, "seats changed during auto assignment")
+)
+
+// seatAssignMaxAttempts: max retry when auto assigned seat taken by others
+const seatAssignMaxAttempts = 3
+
+// handle online check-in
+type CheckinService struct {
+	orderStore        types.OrderStore
+	flightStore       types.FlightStore
+	seatMapStore      types.SeatMapStore
+	seatCacheStore    types.SeatCacheStore
+	checkinCacheStore types.CheckinCacheStore
+	openBefore        time.Duration
+	closeBefore       time.Duration
+}
+
+func NewCheckinService(orderStore types.OrderStore, flightStore types.FlightStore,
+	seatMapStore types.SeatMapStore, seatCacheStore types.SeatCacheStore,
+	checkinCacheStore types.CheckinCacheStore, openBefore time.Duration, closeBefore time.Duration) *CheckinService {
+	return &CheckinService{
+		orderStore:        orderStore,
+		flightStore:       flightStore,
+		seatMapStore:      seatMapStore,
+		seatCacheStore:    seatCacheStore,
+		checkinCacheStore: checkinCacheStore,
+		openBefore:        openBefore,
+		closeBefore:       closeBefore,
+	}
+}
+
+/*
+*
+CheckinOrder: validate order and window, assign missing seats and mark passengers checked in
+*/
+func (checkinService *CheckinService) CheckinOrder(ctx context.Context, orderID uuid.UUID) (types.Order, types.FlightResponse, []types.Passenger, error) {
+	order, err := checkinService.orderStore.GetOrderById(ctx, orderID)
+	if err != nil {
+		return types.Order{}, types.FlightResponse{}, nil, err
+	}
+	if order.ID == uuid.Nil {
+		return types.Order{}, types.FlightResponse{}, nil, fmt.Errorf("order %s %w", orderID, ErrOrderNotFound)
+	}
+	if !order.PaidAt.Valid || order.CanceledAt.Valid || order.IsWaitlisted() {
+		return types.Order{}, types.FlightResponse{}, nil, fmt.Errorf("order %s must be paid, confirmed and not canceled %w", orderID, ErrOrderNotEligible)
+	}
+	flight, err := checkinService.flightStore.GetFlightById(ctx, order.FlightID)
+	if err != nil {
+		return types.Order{}, types.FlightResponse{}, nil, err
+	}
+	now := time.Now().UTC()
+	openAt := flight.FlightDate.Add(-checkinService.openBefore)
+	closeAt := flight.FlightDate.Add(-checkinService.closeBefore)
+	if now.Before(openAt) || now.After(closeAt) {
+		return types.Order{}, types.FlightResponse{}, nil, fmt.Errorf("check-in for flight %s opens at %s and closes at %s %w",
+			flight.ID, openAt, closeAt, ErrCheckinWindowClose)
+	}
+	passengers, err := checkinService.orderStore.GetPassengersByOrderID(ctx, orderID)
+	if err != nil {
+		return types.Order{}, types.FlightResponse{}, nil, err
+	}
+	if err := checkinService.assignMissingSeats(ctx, order, passengers); err != nil {
+		return types.Order{}, types.FlightResponse{}, nil, err
+	}
+	pending := []uuid.UUID{}
+	for _, passenger := range passengers {
+		if !passenger.CheckedInAt.Valid {
+			pending = append(pending, passenger.ID)
+		}
+	}
+	if len(pending) > 0 {
+		// database owns check-in sequence, checked_in counter only follows passengers actually checked in
+		checkedIn, err := checkinService.orderStore.CheckinPassengers(ctx, order.FlightID, pending)
+		if err != nil {
+			return types.Order{}, types.FlightResponse{}, nil, err
+		}
+		if len(checkedIn) > 0 {
+			if _, err := checkinService.checkinCacheStore.IncrCheckedIn(ctx, order.FlightID.String(), int64(len(checkedIn))); err != nil {
+				log.Printf("failed to count %d checked-in passengers of flight %s %v", len(checkedIn), order.FlightID, err)
+			}
+		}
+	}
+	passengers, err = checkinService.orderStore.GetPassengersByOrderID(ctx, orderID)
+	if err != nil {
+		return types.Order{}, types.FlightResponse{}, nil, err
+	}
+	return order, flight, passengers, nil
+}
+
+// assignMissingSeats: pick free seats for passengers without seat, flights without seat map skip
+// when flight is oversold, passengers left without seat still check in and wait for gate close
+func (checkinService *CheckinService) assignMissingSeats(ctx context.Context, order types.Order, passengers []types.Passenger) error {
+	missing := []uuid.UUID{}
+	for _, passenger := range passengers {
+		if !passenger.SeatNumber.Valid {
+			missing = append(missing, passenger.ID)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	seats, err := checkinService.seatMapStore.GetFlightSeats(ctx, order.FlightID)
+	if err != nil {
+		return err
+	}
+	if len(seats) == 0 {
+		return nil
+	}
+	for attempt := 0; attempt < seatAssignMaxAttempts; attempt++ {
+		occupied, err := checkinService.seatCacheStore.GetOccupiedSeats(ctx, order.FlightID.String())
+		if err != nil {
+			return err
+		}
+		freeSeats := pickFreeSeats(seats, occupied, len(missing))
+		if len(freeSeats) == 0 {
+			// oversold flight, passengers without seat are resolved at gate close
+			return nil
+		}
+		assignments := make([]types.PassengerSeatParam, 0, len(freeSeats))
+		for idx, seatNumber := range freeSeats {
+			assignments = append(assignments, types.PassengerSeatParam{
+				PassengerID: missing[idx],
+				SeatNumber:  seatNumber,
+			})
+		}
+		result, err := checkinService.seatCacheStore.AssignSeats(ctx, types.SeatCacheAssignParam{
+			FlightID:    order.FlightID.String(),
+			Assignments: assignments,
+		})
+		if err != nil {
+			return err
+		}
+		if result.IsValid {
+			if _, err := checkinService.orderStore.UpdatePassengerSeats(ctx, assignments); err != nil {
+				checkinService.releaseSeats(ctx, order.FlightID.String(), assignments)
+				return err
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("flight %s %w", order.FlightID, ErrNoSeatsLeft)
+}
+
+// releaseSeats: free seats auto assigned to passengers without seat when they could not be saved
+func (checkinService *CheckinService) releaseSeats(ctx context.Context, flightID string, assignments []types.PassengerSeatParam) {
+	restores := make([]types.PassengerSeatParam, 0, len(assignments))
+	for _, assignment := range assignments {
+		restores = append(restores, types.PassengerSeatParam{PassengerID: assignment.PassengerID})
+	}
+	if err := checkinService.seatCacheStore.RestoreSeats(ctx, types.SeatCacheAssignParam{
+		FlightID:    flightID,
+		Assignments: restores,
+	}); err != nil {
+		log.Printf("failed to release auto assigned seats of flight %s %v", flightID, err)
+	}
+}
+
+// pickFreeSeats: prefer seats outside exit rows, exit rows used last
+func pickFreeSeats(seats []types.FlightSeat, occupied map[string]string, count int) []string {
+	freeSeats := []string{}
+	exitSeats := []string{}
+	for _, seat := range seats {
+		if _, ok := occupied[seat.SeatNumber]; ok || seat.IsBlocked {
+			continue
+		}
+		if seat.IsExitRow {
+			exitSeats = append(exitSeats, seat.SeatNumber)
+			continue
+		}
+		freeSeats = append(freeSeats, seat.SeatNumber)
+	}
+	freeSeats = append(freeSeats, exitSeats...)
+	if len(freeSeats) > count {
+		return freeSeats[:count]
+	}
+	return freeSeats
+}
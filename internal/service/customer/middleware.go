@@ -0,0 +1,92 @@
+package customer
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/auth"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+var (
+	ErrCustomerRequired = apperr.New(apperr.Unauthorized, "customer credentials required")
+	ErrBearerRequired   = apperr.New(apperr.Unauthorized, "customer bearer token required, password is only accepted by token endpoint")
+)
+
+/*
+*
+RequireCustomer: reject request without customer bearer token
+*/
+func RequireCustomer(customerService types.CustomerService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := auth.GetPrincipal(ctx); !ok {
+			unauthorized(ctx, ErrCustomerRequired)
+			return
+		}
+		requireKnownCustomer(ctx, customerService)
+	}
+}
+
+/*
+*
+IdentifyCustomer: customer of bearer token must be registered, other principals and anonymous request pass through,
+password credentials are rejected so password hashing only runs on token endpoint
+*/
+func IdentifyCustomer(customerService types.CustomerService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, _, ok := ctx.Request.BasicAuth(); ok {
+			unauthorized(ctx, ErrBearerRequired)
+			return
+		}
+		if _, ok := CustomerID(ctx); ok {
+			requireKnownCustomer(ctx, customerService)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// requireKnownCustomer: customer of bearer token must be registered, orders reference customers
+func requireKnownCustomer(ctx *gin.Context, customerService types.CustomerService) {
+	customerID, ok := CustomerID(ctx)
+	if !ok {
+		util.WriteError(ctx.Writer, http.StatusForbidden, fmt.Errorf("%w: %v", auth.ErrForbidden, ErrCustomerRequired))
+		ctx.Abort()
+		return
+	}
+	if _, err := customerService.GetCustomer(ctx, customerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			unauthorized(ctx, fmt.Errorf("customer %s not registered", customerID))
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		ctx.Abort()
+		return
+	}
+	ctx.Next()
+}
+
+func unauthorized(ctx *gin.Context, err error) {
+	ctx.Header("WWW-Authenticate", `Bearer realm="customers"`)
+	util.WriteError(ctx.Writer, http.StatusUnauthorized, err)
+	ctx.Abort()
+}
+
+// CustomerID: customer of request principal, false for anonymous request or principal without customer role
+func CustomerID(ctx *gin.Context) (uuid.UUID, bool) {
+	principal, ok := auth.GetPrincipal(ctx)
+	if !ok || !principal.HasRole(auth.RoleCustomer) {
+		return uuid.Nil, false
+	}
+	customerID, err := uuid.Parse(principal.Subject)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return customerID, true
+}
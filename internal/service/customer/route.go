@@ -0,0 +1,236 @@
+package customer
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/yuanyu90221/airline-order-system/internal/auth"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+// maxOrdersLimit: upper bound of orders per page
+const maxOrdersLimit = 100
+
+type Handler struct {
+	customerService types.CustomerService
+	orderStore      types.OrderStore
+	tokenIssuer     types.TokenIssuer
+}
+
+func NewHandler(customerService types.CustomerService, orderStore types.OrderStore, tokenIssuer types.TokenIssuer) *Handler {
+	return &Handler{
+		customerService: customerService,
+		orderStore:      orderStore,
+		tokenIssuer:     tokenIssuer,
+	}
+}
+
+func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
+	router.POST("/", h.Register)
+	router.POST("/token", h.CreateToken)
+}
+
+// RegisterMeRoute: routes of signed in customer
+func (h *Handler) RegisterMeRoute(router *gin.RouterGroup) {
+	router.Use(RequireCustomer(h.customerService))
+	router.GET("/", h.GetProfile)
+	router.PATCH("/", h.UpdateProfile)
+	router.GET("/orders", h.GetMyOrders)
+}
+
+func (h *Handler) Register(ctx *gin.Context) {
+	var createCustomer types.CreateCustomerRequest
+	if err := util.ParseJSON(ctx.Request, &createCustomer); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createCustomer); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	customer, err := h.customerService.Register(ctx, createCustomer)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEmailTaken):
+			util.WriteError(ctx.Writer, http.StatusConflict, err)
+		case errors.Is(err, ErrPasswordTooLong):
+			util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		default:
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, types.ConvertCustomerToResponse(customer)), "failed to response json")
+}
+
+/*
+*
+CreateToken: bearer token of customer for email and password
+*/
+func (h *Handler) CreateToken(ctx *gin.Context) {
+	var createToken types.CreateTokenRequest
+	if err := util.ParseJSON(ctx.Request, &createToken); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createToken); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	customer, err := h.customerService.Authenticate(ctx, createToken.Email, createToken.Password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			util.WriteError(ctx.Writer, http.StatusUnauthorized, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	token, ttl, err := h.tokenIssuer.IssueToken(customer.ID.String(), []string{auth.RoleCustomer})
+	if err != nil {
+		if errors.Is(err, auth.ErrTokenIssuingUnavailable) {
+			util.WriteError(ctx.Writer, http.StatusNotImplemented, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, types.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl / time.Second),
+	}), "failed to response json")
+}
+
+func (h *Handler) GetProfile(ctx *gin.Context) {
+	customerID, _ := CustomerID(ctx)
+	customer, err := h.customerService.GetCustomer(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertCustomerToResponse(customer)), "failed to response json")
+}
+
+func (h *Handler) UpdateProfile(ctx *gin.Context) {
+	var updateCustomer types.UpdateCustomerRequest
+	if err := util.ParseJSON(ctx.Request, &updateCustomer); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(updateCustomer); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	customerID, _ := CustomerID(ctx)
+	customer, err := h.customerService.UpdateProfile(ctx, customerID, updateCustomer)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+		case errors.Is(err, ErrPasswordTooLong):
+			util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		default:
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertCustomerToResponse(customer)), "failed to response json")
+}
+
+func (h *Handler) GetMyOrders(ctx *gin.Context) {
+	query := ctx.Request.URL.Query()
+	pagination := types.Pagination{
+		Offset: 0,
+		Limit:  20,
+		Cursor: query.Get("cursor"),
+	}
+	queryParams := types.QueryCustomerOrdersRequest{
+		Status: query.Get("status"),
+	}
+	integerParams := []struct {
+		name  string
+		value *int64
+	}{
+		{"limit", &pagination.Limit},
+		{"offset", &pagination.Offset},
+		{"created_from", &queryParams.CreatedFrom},
+		{"created_to", &queryParams.CreatedTo},
+	}
+	for _, param := range integerParams {
+		if !query.Has(param.name) {
+			continue
+		}
+		value, err := strconv.ParseInt(query.Get(param.name), 10, 64)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("%s parse err: %w", param.name, err))
+			return
+		}
+		*param.value = value
+	}
+	if pagination.Limit < 1 || pagination.Limit > maxOrdersLimit || pagination.Offset < 0 {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("limit must be within 1 to %d and offset not negative", maxOrdersLimit))
+		return
+	}
+	if pagination.Cursor != "" && pagination.Offset > 0 {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("cursor and offset could not be used together"))
+		return
+	}
+	if err := util.Validdate.Struct(queryParams); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid query:%w", valErrs))
+		}
+		return
+	}
+	customerID, _ := CustomerID(ctx)
+	orders, pagination, err := h.orderStore.GetOrdersByCustomerID(ctx, customerID, queryParams, pagination)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidCursor) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get orders %w", err))
+		return
+	}
+	result := types.CustomerOrdersFetchResponse{
+		Orders:     make([]types.QueryOrderResponse, 0, len(orders)),
+		Pagination: pagination,
+	}
+	for _, customerOrder := range orders {
+		passengers, err := h.orderStore.GetPassengersByOrderID(ctx, customerOrder.ID)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get passengers %w", err))
+			return
+		}
+		segments, err := h.orderStore.GetOrderSegments(ctx, customerOrder.ID)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get segments %w", err))
+			return
+		}
+		response := types.ConvertOrderEntityToResponse(customerOrder, passengers)
+		response.Segments = types.ConvertOrderSegmentsToResponse(segments)
+		result.Orders = append(result.Orders, response)
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed to response json")
+}
@@ -0,0 +1,120 @@
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrEmailTaken         = apperr.New(apperr.Conflict, "email already registered")
+	ErrInvalidCredentials = apperr.New(apperr.Unauthorized, "invalid email or password")
+	ErrPasswordTooLong    = apperr.New(apperr.BadRequest, "password longer than 72 bytes")
+)
+
+// dummyPasswordHash: compared for unknown emails so response time does not reveal registered emails
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("airline-order-system"), bcrypt.DefaultCost)
+
+// register and authenticate customers, passwords are kept as bcrypt hash only
+type CustomerService struct {
+	customerStore types.CustomerStore
+}
+
+func NewCustomerService(customerStore types.CustomerStore) *CustomerService {
+	return &CustomerService{
+		customerStore: customerStore,
+	}
+}
+
+/*
+*
+Register: create customer with hashed password, return ErrEmailTaken when email already registered
+*/
+func (service *CustomerService) Register(ctx context.Context, createCustomer types.CreateCustomerRequest) (types.Customer, error) {
+	passwordHash, err := hashPassword(createCustomer.Password)
+	if err != nil {
+		return types.Customer{}, err
+	}
+	customer, err := service.customerStore.CreateCustomer(ctx, types.Customer{
+		ID:           uuid.New(),
+		Email:        strings.ToLower(createCustomer.Email),
+		PasswordHash: passwordHash,
+		Name:         createCustomer.Name,
+		Phone:        sql.NullString{String: createCustomer.Phone, Valid: createCustomer.Phone != ""},
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return types.Customer{}, fmt.Errorf("%s %w", createCustomer.Email, ErrEmailTaken)
+		}
+		return types.Customer{}, err
+	}
+	return customer, nil
+}
+
+/*
+*
+Authenticate: customer of email when password matches, ErrInvalidCredentials otherwise
+*/
+func (service *CustomerService) Authenticate(ctx context.Context, email string, password string) (types.Customer, error) {
+	customer, err := service.customerStore.GetCustomerByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+			return types.Customer{}, ErrInvalidCredentials
+		}
+		return types.Customer{}, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(customer.PasswordHash), []byte(password)); err != nil {
+		return types.Customer{}, ErrInvalidCredentials
+	}
+	return customer, nil
+}
+
+// GetCustomer: customer of id, sql.ErrNoRows when not found
+func (service *CustomerService) GetCustomer(ctx context.Context, customerID uuid.UUID) (types.Customer, error) {
+	return service.customerStore.GetCustomerById(ctx, customerID)
+}
+
+/*
+*
+UpdateProfile: change name, phone or password, empty fields are left unchanged
+*/
+func (service *CustomerService) UpdateProfile(ctx context.Context, customerID uuid.UUID, updateCustomer types.UpdateCustomerRequest) (types.Customer, error) {
+	customer, err := service.customerStore.GetCustomerById(ctx, customerID)
+	if err != nil {
+		return types.Customer{}, err
+	}
+	if updateCustomer.Name != "" {
+		customer.Name = updateCustomer.Name
+	}
+	if updateCustomer.Phone != "" {
+		customer.Phone = sql.NullString{String: updateCustomer.Phone, Valid: true}
+	}
+	if updateCustomer.Password != "" {
+		customer.PasswordHash, err = hashPassword(updateCustomer.Password)
+		if err != nil {
+			return types.Customer{}, err
+		}
+	}
+	return service.customerStore.UpdateCustomer(ctx, customer)
+}
+
+func hashPassword(password string) (string, error) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return "", ErrPasswordTooLong
+		}
+		return "", fmt.Errorf("failed to hash password %w", err)
+	}
+	return string(passwordHash), nil
+}
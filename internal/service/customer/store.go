@@ -0,0 +1,112 @@
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// customerColumns: column order used by scanCustomer
+const customerColumns = "id, email, password_hash, name, phone, created_at, updated_at"
+
+type CustomerStore struct {
+	db *sql.DB
+}
+
+func NewCustomerStore(db *sql.DB) *CustomerStore {
+	return &CustomerStore{db: db}
+}
+
+type customerScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCustomer(scanner customerScanner) (types.Customer, error) {
+	var customer types.Customer
+	err := scanner.Scan(
+		&customer.ID,
+		&customer.Email,
+		&customer.PasswordHash,
+		&customer.Name,
+		&customer.Phone,
+		&customer.CreatedAt,
+		&customer.UpdatedAt,
+	)
+	if err != nil {
+		return types.Customer{}, err
+	}
+	return customer, nil
+}
+
+func (customerStore *CustomerStore) CreateCustomer(ctx context.Context, customer types.Customer) (types.Customer, error) {
+	queryBuilder := sq.Insert("customers").Columns("id", "email", "password_hash", "name", "phone").
+		Values(customer.ID, strings.ToLower(customer.Email), customer.PasswordHash, customer.Name, customer.Phone).
+		Suffix("RETURNING " + customerColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Customer{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	result, err := scanCustomer(customerStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.Customer{}, fmt.Errorf("failed to insert customer %w", err)
+	}
+	return result, nil
+}
+
+/*
+*
+GetCustomerById: return sql.ErrNoRows when customer not found
+*/
+func (customerStore *CustomerStore) GetCustomerById(ctx context.Context, customerID uuid.UUID) (types.Customer, error) {
+	return customerStore.getCustomer(ctx, sq.Eq{"id": customerID})
+}
+
+/*
+*
+GetCustomerByEmail: email is matched case-insensitively, return sql.ErrNoRows when customer not found
+*/
+func (customerStore *CustomerStore) GetCustomerByEmail(ctx context.Context, email string) (types.Customer, error) {
+	return customerStore.getCustomer(ctx, sq.Eq{"email": strings.ToLower(email)})
+}
+
+func (customerStore *CustomerStore) getCustomer(ctx context.Context, condition sq.Sqlizer) (types.Customer, error) {
+	queryBuilder := sq.Select(customerColumns).From("customers").Where(condition).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Customer{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	customer, err := scanCustomer(customerStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Customer{}, fmt.Errorf("customer not found %w", err)
+		}
+		return types.Customer{}, fmt.Errorf("failed to query customer %w", err)
+	}
+	return customer, nil
+}
+
+// UpdateCustomer: update profile and password hash of customer
+func (customerStore *CustomerStore) UpdateCustomer(ctx context.Context, customer types.Customer) (types.Customer, error) {
+	queryBuilder := sq.Update("customers").
+		Set("name", customer.Name).
+		Set("phone", customer.Phone).
+		Set("password_hash", customer.PasswordHash).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": customer.ID}).
+		Suffix("RETURNING " + customerColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Customer{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	result, err := scanCustomer(customerStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.Customer{}, fmt.Errorf("failed to update customer %w", err)
+	}
+	return result, nil
+}
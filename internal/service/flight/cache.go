@@ -41,9 +41,30 @@ func (cacheStore *CacheStore) UpdateFlight(ctx context.Context, flightInfo types
 	if err != nil {
 		return types.Flight{}, fmt.Errorf("failed to exec update : %w", err)
 	}
+	// cached search pages keep seats of search time, search overlays live seat counters and never caches remain filters
 	return flightInfo, nil
 }
 
+/*
+*
+FillFlightCacheInfo: backfill flight loaded from database, record written by UpdateFlight meanwhile is kept
+*/
+func (cacheStore *CacheStore) FillFlightCacheInfo(ctx context.Context, flightInfo types.Flight) error {
+	expiredDuration := flightInfo.FlightDate.Sub(time.Now().UTC())
+	// departed flights are not cached
+	if expiredDuration <= 0 {
+		return nil
+	}
+	jsonData, err := json.Marshal(flightInfo)
+	if err != nil {
+		return fmt.Errorf("marshal flightInfo err %w", err)
+	}
+	if err := cacheStore.rdb.SetNX(ctx, flightInfo.ID.String(), jsonData, expiredDuration).Err(); err != nil {
+		return fmt.Errorf("failed to fill flight %s %w", flightInfo.ID, err)
+	}
+	return nil
+}
+
 func (cacheStore *CacheStore) GetFlightCacheInfo(ctx context.Context, flightID string) (types.Flight, error) {
 	result := cacheStore.rdb.Get(ctx, flightID)
 	var flightInfo types.Flight
@@ -60,3 +81,134 @@ func (cacheStore *CacheStore) GetFlightCacheInfo(ctx context.Context, flightID s
 	}
 	return flightInfo, nil
 }
+
+/*
+*
+GetFareCalendar: cached fare calendar, false when missing or expired
+*/
+func (cacheStore *CacheStore) GetFareCalendar(ctx context.Context, key string) (types.FareCalendarResponse, bool, error) {
+	resultBody, err := cacheStore.rdb.Get(ctx, fareCalendarKey(key)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return types.FareCalendarResponse{}, false, nil
+		}
+		return types.FareCalendarResponse{}, false, fmt.Errorf("get fare calendar error %w", err)
+	}
+	var calendar types.FareCalendarResponse
+	if err := json.Unmarshal(resultBody, &calendar); err != nil {
+		return types.FareCalendarResponse{}, false, fmt.Errorf("unmarshal fare calendar error %w", err)
+	}
+	return calendar, true, nil
+}
+
+// fareCalendarMinTTL: ttl 0 keeps a key in redis forever, shorter ttl is raised to it so calendars always expire
+const fareCalendarMinTTL = time.Second
+
+func (cacheStore *CacheStore) SetFareCalendar(ctx context.Context, key string, calendar types.FareCalendarResponse, ttl time.Duration) error {
+	if ttl < fareCalendarMinTTL {
+		ttl = fareCalendarMinTTL
+	}
+	jsonData, err := json.Marshal(calendar)
+	if err != nil {
+		return fmt.Errorf("marshal fare calendar err %w", err)
+	}
+	if err := cacheStore.rdb.Set(ctx, fareCalendarKey(key), jsonData, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set fare calendar %w", err)
+	}
+	return nil
+}
+
+func fareCalendarKey(key string) string {
+	return fmt.Sprintf("fare_calendar:%s", key)
+}
+
+/*
+*
+GetFlightSearch: cached search page, false when missing or expired
+*/
+func (cacheStore *CacheStore) GetFlightSearch(ctx context.Context, key string) (types.FlightsFetchResponse, bool, error) {
+	resultBody, err := cacheStore.rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return types.FlightsFetchResponse{}, false, nil
+		}
+		return types.FlightsFetchResponse{}, false, fmt.Errorf("get flight search error %w", err)
+	}
+	var result types.FlightsFetchResponse
+	if err := json.Unmarshal(resultBody, &result); err != nil {
+		return types.FlightsFetchResponse{}, false, fmt.Errorf("unmarshal flight search error %w", err)
+	}
+	return result, true, nil
+}
+
+/*
+*
+SetFlightSearch: cache search page and index it under its route, departure or destination is empty when not filtered
+*/
+func (cacheStore *CacheStore) SetFlightSearch(ctx context.Context, departure string, destination string, key string,
+	result types.FlightsFetchResponse, ttl time.Duration) error {
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshal flight search err %w", err)
+	}
+	indexKey := flightSearchIndexKey(departure, destination)
+	tx := cacheStore.rdb.TxPipeline()
+	tx.Set(ctx, key, jsonData, ttl)
+	tx.SAdd(ctx, indexKey, key)
+	// index lives as long as its newest page
+	tx.Expire(ctx, indexKey, ttl)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set flight search %w", err)
+	}
+	return nil
+}
+
+/*
+*
+InvalidateFlightSearch: drop cached search pages which could contain flights from departure to destination,
+pages of searches filtered by route, by departure only, by destination only or unfiltered
+*/
+func (cacheStore *CacheStore) InvalidateFlightSearch(ctx context.Context, departure string, destination string) error {
+	routes := [][2]string{{departure, destination}, {departure, ""}, {"", destination}, {"", ""}}
+	for _, route := range routes {
+		indexKey := flightSearchIndexKey(route[0], route[1])
+		pages, err := cacheStore.rdb.SMembers(ctx, indexKey).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get flight search index %s %w", indexKey, err)
+		}
+		// pages share hash tag of their index, pages indexed meanwhile stay in index
+		for start := 0; start < len(pages); start += flightSearchDeleteBatch {
+			batch := pages[start:min(start+flightSearchDeleteBatch, len(pages))]
+			members := make([]interface{}, 0, len(batch))
+			for _, page := range batch {
+				members = append(members, page)
+			}
+			tx := cacheStore.rdb.TxPipeline()
+			tx.Del(ctx, batch...)
+			tx.SRem(ctx, indexKey, members...)
+			if _, err := tx.Exec(ctx); err != nil {
+				return fmt.Errorf("failed to invalidate flight search %s %w", indexKey, err)
+			}
+		}
+	}
+	return nil
+}
+
+// flightSearchDeleteBatch: pages deleted per command when invalidating an index
+const flightSearchDeleteBatch = 500
+
+// flightSearchRoute: hash tag shared by search pages of route and their index, * for any airport
+func flightSearchRoute(departure string, destination string) string {
+	if departure == "" {
+		departure = "*"
+	}
+	if destination == "" {
+		destination = "*"
+	}
+	return fmt.Sprintf("{%s:%s}", departure, destination)
+}
+
+// flightSearchIndexKey: set of cached search pages of route
+func flightSearchIndexKey(departure string, destination string) string {
+	return "flight_search_index:" + flightSearchRoute(departure, destination)
+}
@@ -0,0 +1,69 @@
+package flight
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+/*
+*
+FareCalendarDates: first and last local date of calendar, whole month or date with flex days around
+*/
+func FareCalendarDates(queryParams types.QueryFareCalendarRequest) (time.Time, time.Time, error) {
+	if queryParams.Month != "" {
+		firstDate, err := time.Parse("2006-01", queryParams.Month)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to parse month %w", err)
+		}
+		return firstDate, firstDate.AddDate(0, 1, -1), nil
+	}
+	date, err := time.Parse(time.DateOnly, queryParams.Date)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse date %w", err)
+	}
+	flexDays := int(queryParams.FlexDays)
+	return date.AddDate(0, 0, -flexDays), date.AddDate(0, 0, flexDays), nil
+}
+
+// localMidnight: start of date in location, date carries year, month and day only
+func localMidnight(date time.Time, location *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+}
+
+/*
+*
+BuildFareCalendar: one day per date from firstDate to lastDate, days without bookable flights have no lowest price
+*/
+func BuildFareCalendar(queryParams types.QueryFareCalendarRequest, location *time.Location,
+	firstDate time.Time, lastDate time.Time, entries []types.FareCalendarEntry) types.FareCalendarResponse {
+	entriesByDate := make(map[string]types.FareCalendarEntry, len(entries))
+	for _, entry := range entries {
+		entriesByDate[entry.Date.Format(time.DateOnly)] = entry
+	}
+	calendar := types.FareCalendarResponse{
+		Departure:   queryParams.Departure,
+		Destination: queryParams.Destination,
+		Timezone:    location.String(),
+		From:        firstDate.Format(time.DateOnly),
+		To:          lastDate.Format(time.DateOnly),
+		Days:        []types.FareCalendarDay{},
+	}
+	var cheapest *float64
+	for date := firstDate; !date.After(lastDate); date = date.AddDate(0, 0, 1) {
+		day := types.FareCalendarDay{Date: date.Format(time.DateOnly)}
+		if entry, ok := entriesByDate[day.Date]; ok {
+			lowestPrice := entry.LowestPrice
+			day.LowestPrice = &lowestPrice
+			day.Flights = entry.Flights
+			day.AvailableSeats = entry.AvailableSeats
+			if cheapest == nil || lowestPrice < *cheapest {
+				cheapest = day.LowestPrice
+				calendar.CheapestDate = day.Date
+			}
+		}
+		calendar.Days = append(calendar.Days, day)
+	}
+	return calendar
+}
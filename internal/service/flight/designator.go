@@ -0,0 +1,37 @@
+package flight
+
+import (
+	"strings"
+
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+/*
+*
+ParseFlightDesignator: split "ZZ100" into ("ZZ", "100") and "100" into ("", "100"),
+flight number is empty when designator is invalid
+*/
+func ParseFlightDesignator(designator string) (string, string) {
+	designator = strings.ToUpper(strings.TrimSpace(designator))
+	if util.IsFlightNumber(designator) {
+		return "", NormalizeFlightNumber(designator)
+	}
+	if len(designator) > 2 && util.IsCarrierCode(designator[:2]) && util.IsFlightNumber(designator[2:]) {
+		return designator[:2], NormalizeFlightNumber(designator[2:])
+	}
+	return "", ""
+}
+
+// NormalizeFlightNumber: upper case without leading zeros, "0100a" -> "100A"
+func NormalizeFlightNumber(flightNumber string) string {
+	if flightNumber == "" {
+		return ""
+	}
+	flightNumber = strings.ToUpper(flightNumber)
+	trimmed := strings.TrimLeft(flightNumber, "0")
+	if trimmed == "" || trimmed[0] < '0' || trimmed[0] > '9' {
+		// keep one zero for flight number 0
+		return "0" + trimmed
+	}
+	return trimmed
+}
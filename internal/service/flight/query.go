@@ -0,0 +1,235 @@
+package flight
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+// maxSearchLimit: upper bound of flights per page
+const maxSearchLimit = 100
+
+// maxDepartWindowDays: longest date range searched with departure time-of-day window, one utc range per day
+const maxDepartWindowDays = 92
+
+/*
+*
+ParseFlightQuery: search filters, sorting and pagination from query string, all parameters are validated
+*/
+func ParseFlightQuery(query url.Values) (types.QueryFlightRequest, types.Pagination, error) {
+	pagination := types.Pagination{
+		Offset: 0,
+		Limit:  10,
+		Cursor: query.Get("cursor"),
+	}
+	queryParams := types.QueryFlightRequest{
+		Departure:    strings.ToUpper(query.Get("departure")),
+		Destination:  strings.ToUpper(query.Get("destination")),
+		CarrierCode:  strings.ToUpper(query.Get("carrier_code")),
+		DepartAfter:  query.Get("depart_after"),
+		DepartBefore: query.Get("depart_before"),
+		SortBy:       query.Get("sort_by"),
+		SortOrder:    strings.ToLower(query.Get("sort_order")),
+	}
+	// flignt_date is the misspelled name accepted before flight_date
+	if !query.Has("flight_date") && query.Has("flignt_date") {
+		query.Set("flight_date", query.Get("flignt_date"))
+	}
+	integerParams := []struct {
+		name  string
+		value *int64
+	}{
+		{"limit", &pagination.Limit},
+		{"offset", &pagination.Offset},
+		{"flight_date", &queryParams.FlightDate},
+		{"date_from", &queryParams.DateFrom},
+		{"date_to", &queryParams.DateTo},
+		{"min_remain", &queryParams.MinRemain},
+	}
+	for _, param := range integerParams {
+		if !query.Has(param.name) {
+			continue
+		}
+		value, err := strconv.ParseInt(query.Get(param.name), 10, 64)
+		if err != nil {
+			return types.QueryFlightRequest{}, types.Pagination{}, fmt.Errorf("%s parse err: %w", param.name, err)
+		}
+		*param.value = value
+	}
+	floatParams := []struct {
+		name  string
+		value *float64
+	}{
+		{"price_min", &queryParams.PriceMin},
+		{"price_max", &queryParams.PriceMax},
+	}
+	for _, param := range floatParams {
+		if !query.Has(param.name) {
+			continue
+		}
+		value, err := strconv.ParseFloat(query.Get(param.name), 64)
+		if err != nil {
+			return types.QueryFlightRequest{}, types.Pagination{}, fmt.Errorf("%s parse err: %w", param.name, err)
+		}
+		*param.value = value
+	}
+	if query.Has("flight_number") {
+		// accept designator ZZ100 or number 100
+		carrierCode, flightNumber := ParseFlightDesignator(query.Get("flight_number"))
+		if flightNumber == "" {
+			return types.QueryFlightRequest{}, types.Pagination{}, fmt.Errorf("invalid flight_number %s", query.Get("flight_number"))
+		}
+		if carrierCode != "" {
+			queryParams.CarrierCode = carrierCode
+		}
+		queryParams.FlightNumber = flightNumber
+	}
+	if pagination.Limit < 1 || pagination.Limit > maxSearchLimit {
+		return types.QueryFlightRequest{}, types.Pagination{}, fmt.Errorf("limit must be within 1 to %d", maxSearchLimit)
+	}
+	if pagination.Offset < 0 {
+		return types.QueryFlightRequest{}, types.Pagination{}, fmt.Errorf("offset must not be negative")
+	}
+	if err := util.Validdate.Struct(queryParams); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			return types.QueryFlightRequest{}, types.Pagination{}, fmt.Errorf("invalid query:%w", valErrs)
+		}
+		return types.QueryFlightRequest{}, types.Pagination{}, err
+	}
+	if queryParams.DepartAfter != "" || queryParams.DepartBefore != "" {
+		from := max(time.Now().Unix(), queryParams.FlightDate, queryParams.DateFrom)
+		if time.Unix(queryParams.DateTo, 0).Sub(time.Unix(from, 0)) > maxDepartWindowDays*24*time.Hour {
+			return types.QueryFlightRequest{}, types.Pagination{}, fmt.Errorf("date_to must be within %d days with depart_after or depart_before", maxDepartWindowDays)
+		}
+	}
+	if pagination.Cursor != "" {
+		if pagination.Offset > 0 {
+			return types.QueryFlightRequest{}, types.Pagination{}, fmt.Errorf("cursor and offset could not be used together")
+		}
+		if _, err := decodeFlightCursor(pagination.Cursor, queryParams); err != nil {
+			return types.QueryFlightRequest{}, types.Pagination{}, err
+		}
+	}
+	return queryParams, pagination, nil
+}
+
+/*
+*
+flightSortColumns: order by clause of sort_by, flight_date and id break ties so pages are stable
+*/
+func flightSortColumns(sortBy string, sortOrder string) []string {
+	direction := "ASC"
+	if sortOrder == "desc" {
+		direction = "DESC"
+	}
+	switch sortBy {
+	case "price":
+		return []string{"price " + direction, "flight_date ASC", "id ASC"}
+	case "remain":
+		return []string{"(available_seats + wait_seats) " + direction, "flight_date ASC", "id ASC"}
+	default:
+		return []string{"flight_date " + direction, "id ASC"}
+	}
+}
+
+// flightCursor: position of last flight on page, sort value is price or remain of sort_by
+type flightCursor struct {
+	SortBy     string    `json:"s,omitempty"`
+	SortOrder  string    `json:"o,omitempty"`
+	SortValue  float64   `json:"v,omitempty"`
+	FlightDate time.Time `json:"d"`
+	ID         uuid.UUID `json:"i"`
+}
+
+// encodeFlightCursor: cursor after flight for sorting of queryParams
+func encodeFlightCursor(flight types.Flight, queryParams types.QueryFlightRequest) (string, error) {
+	cursor := flightCursor{
+		SortBy:     queryParams.SortBy,
+		SortOrder:  queryParams.SortOrder,
+		FlightDate: flight.FlightDate,
+		ID:         flight.ID,
+	}
+	switch queryParams.SortBy {
+	case "price":
+		cursor.SortValue = flight.Price
+	case "remain":
+		cursor.SortValue = float64(flight.AvailableSeats + flight.WaitSeats)
+	}
+	return util.EncodeCursor(cursor)
+}
+
+/*
+*
+decodeFlightCursor: position of cursor, cursor issued for another sorting is rejected
+*/
+func decodeFlightCursor(token string, queryParams types.QueryFlightRequest) (flightCursor, error) {
+	var cursor flightCursor
+	if err := util.DecodeCursor(token, &cursor); err != nil {
+		return flightCursor{}, err
+	}
+	if cursor.SortBy != queryParams.SortBy || cursor.SortOrder != queryParams.SortOrder {
+		return flightCursor{}, fmt.Errorf("%w: issued for sort_by %q sort_order %q", util.ErrInvalidCursor, cursor.SortBy, cursor.SortOrder)
+	}
+	if cursor.ID == uuid.Nil || cursor.FlightDate.IsZero() {
+		return flightCursor{}, fmt.Errorf("%w: missing position", util.ErrInvalidCursor)
+	}
+	return cursor, nil
+}
+
+/*
+*
+flightKeysetCondition: flights after cursor in order of flightSortColumns
+*/
+func flightKeysetCondition(cursor flightCursor) sq.Sqlizer {
+	// flight_date ASC, id ASC tie-breakers
+	afterTieBreak := sq.Expr("(flight_date, id) > (?, ?)", cursor.FlightDate, cursor.ID)
+	comparison := ">"
+	if cursor.SortOrder == "desc" {
+		comparison = "<"
+	}
+	var sortExpr string
+	switch cursor.SortBy {
+	case "price":
+		sortExpr = "price"
+	case "remain":
+		sortExpr = "(available_seats + wait_seats)"
+	default:
+		if cursor.SortOrder != "desc" {
+			return afterTieBreak
+		}
+		return sq.Or{sq.Lt{"flight_date": cursor.FlightDate},
+			sq.And{sq.Eq{"flight_date": cursor.FlightDate}, sq.Gt{"id": cursor.ID}}}
+	}
+	return sq.Or{sq.Expr(sortExpr+" "+comparison+" ?", cursor.SortValue),
+		sq.And{sq.Expr(sortExpr+" = ?", cursor.SortValue), afterTieBreak}}
+}
+
+/*
+*
+flightSearchCacheKey: cache key of normalized search, equal searches written differently share one key
+*/
+func flightSearchCacheKey(queryParams types.QueryFlightRequest, pagination types.Pagination) (string, error) {
+	normalized, err := json.Marshal(struct {
+		Query      types.QueryFlightRequest `json:"q"`
+		Pagination types.Pagination         `json:"p"`
+	}{queryParams, pagination})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal search key %w", err)
+	}
+	digest := sha256.Sum256(normalized)
+	return fmt.Sprintf("flight_search:%s:%s", flightSearchRoute(queryParams.Departure, queryParams.Destination),
+		hex.EncodeToString(digest[:])), nil
+}
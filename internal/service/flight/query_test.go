@@ -0,0 +1,143 @@
+package flight
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+func TestFlightCursorRoundTrip(t *testing.T) {
+	flight := types.Flight{
+		ID:             uuid.MustParse("7b0c1e4e-2d7f-4c3a-9a53-3f5d8c1b2a10"),
+		FlightDate:     time.Date(2024, 7, 1, 1, 30, 0, 0, time.UTC),
+		Price:          1250.5,
+		AvailableSeats: 12,
+		WaitSeats:      3,
+	}
+	tests := []struct {
+		name          string
+		sortBy        string
+		sortOrder     string
+		wantSortValue float64
+	}{
+		{name: "departure time asc", sortBy: "", sortOrder: "asc"},
+		{name: "departure time desc", sortBy: "", sortOrder: "desc"},
+		{name: "price asc", sortBy: "price", sortOrder: "asc", wantSortValue: 1250.5},
+		{name: "price desc", sortBy: "price", sortOrder: "desc", wantSortValue: 1250.5},
+		{name: "remain asc", sortBy: "remain", sortOrder: "asc", wantSortValue: 15},
+		{name: "remain desc", sortBy: "remain", sortOrder: "desc", wantSortValue: 15},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queryParams := types.QueryFlightRequest{SortBy: test.sortBy, SortOrder: test.sortOrder}
+			token, err := encodeFlightCursor(flight, queryParams)
+			if err != nil {
+				t.Fatalf("encodeFlightCursor() error = %v", err)
+			}
+			cursor, err := decodeFlightCursor(token, queryParams)
+			if err != nil {
+				t.Fatalf("decodeFlightCursor() error = %v", err)
+			}
+			if cursor.SortValue != test.wantSortValue || cursor.ID != flight.ID || !cursor.FlightDate.Equal(flight.FlightDate) {
+				t.Errorf("decodeFlightCursor() = %+v, want sort value %v at %s %s", cursor, test.wantSortValue, flight.FlightDate, flight.ID)
+			}
+			// cursor only continues the sorting it was issued for
+			other := types.QueryFlightRequest{SortBy: test.sortBy, SortOrder: "desc"}
+			if test.sortOrder == "desc" {
+				other.SortOrder = "asc"
+			}
+			if _, err := decodeFlightCursor(token, other); !errors.Is(err, util.ErrInvalidCursor) {
+				t.Errorf("decodeFlightCursor() with sort_order %s error = %v, want %v", other.SortOrder, err, util.ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestDecodeFlightCursorInvalid(t *testing.T) {
+	missingPosition, err := util.EncodeCursor(flightCursor{SortBy: "price", SortValue: 100})
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "%%%"},
+		{name: "not json", token: "bm90IGpzb24"},
+		{name: "missing position", token: missingPosition},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := decodeFlightCursor(test.token, types.QueryFlightRequest{SortBy: "price"}); !errors.Is(err, util.ErrInvalidCursor) {
+				t.Errorf("decodeFlightCursor(%q) error = %v, want %v", test.token, err, util.ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestFlightKeysetCondition(t *testing.T) {
+	flightDate := time.Date(2024, 7, 1, 1, 30, 0, 0, time.UTC)
+	id := uuid.MustParse("7b0c1e4e-2d7f-4c3a-9a53-3f5d8c1b2a10")
+	tests := []struct {
+		name     string
+		cursor   flightCursor
+		wantSql  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "departure time asc",
+			cursor:   flightCursor{SortOrder: "asc", FlightDate: flightDate, ID: id},
+			wantSql:  "(flight_date, id) > (?, ?)",
+			wantArgs: []interface{}{flightDate, id},
+		},
+		{
+			name:     "departure time desc",
+			cursor:   flightCursor{SortOrder: "desc", FlightDate: flightDate, ID: id},
+			wantSql:  "(flight_date < ? OR (flight_date = ? AND id > ?))",
+			wantArgs: []interface{}{flightDate, flightDate, id.String()},
+		},
+		{
+			name:     "price asc",
+			cursor:   flightCursor{SortBy: "price", SortOrder: "asc", SortValue: 1250.5, FlightDate: flightDate, ID: id},
+			wantSql:  "(price > ? OR (price = ? AND (flight_date, id) > (?, ?)))",
+			wantArgs: []interface{}{1250.5, 1250.5, flightDate, id},
+		},
+		{
+			name:     "price desc",
+			cursor:   flightCursor{SortBy: "price", SortOrder: "desc", SortValue: 1250.5, FlightDate: flightDate, ID: id},
+			wantSql:  "(price < ? OR (price = ? AND (flight_date, id) > (?, ?)))",
+			wantArgs: []interface{}{1250.5, 1250.5, flightDate, id},
+		},
+		{
+			name:     "remain asc",
+			cursor:   flightCursor{SortBy: "remain", SortOrder: "asc", SortValue: 15, FlightDate: flightDate, ID: id},
+			wantSql:  "((available_seats + wait_seats) > ? OR ((available_seats + wait_seats) = ? AND (flight_date, id) > (?, ?)))",
+			wantArgs: []interface{}{15.0, 15.0, flightDate, id},
+		},
+		{
+			name:     "remain desc",
+			cursor:   flightCursor{SortBy: "remain", SortOrder: "desc", SortValue: 15, FlightDate: flightDate, ID: id},
+			wantSql:  "((available_seats + wait_seats) < ? OR ((available_seats + wait_seats) = ? AND (flight_date, id) > (?, ?)))",
+			wantArgs: []interface{}{15.0, 15.0, flightDate, id},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sql, args, err := flightKeysetCondition(test.cursor).ToSql()
+			if err != nil {
+				t.Fatalf("flightKeysetCondition().ToSql() error = %v", err)
+			}
+			if sql != test.wantSql {
+				t.Errorf("flightKeysetCondition() sql = %q, want %q", sql, test.wantSql)
+			}
+			if !reflect.DeepEqual(args, test.wantArgs) {
+				t.Errorf("flightKeysetCondition() args = %#v, want %#v", args, test.wantArgs)
+			}
+		})
+	}
+}
@@ -1,15 +1,22 @@
 package flight
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	bloomfilter "github.com/alovn/go-bloomfilter"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/yuanyu90221/airline-order-system/internal/config"
+	"github.com/yuanyu90221/airline-order-system/internal/service/airport"
 	"github.com/yuanyu90221/airline-order-system/internal/types"
 	"github.com/yuanyu90221/airline-order-system/internal/util"
 )
@@ -19,20 +26,26 @@ type Handler struct {
 	flightCacheStore types.FlightCacheStore
 	flightStore      types.FlightStore
 	bFilter          bloomfilter.BloomFilter
+	flightService    types.FlightService
+	airportDirectory types.AirportDirectory
 }
 
 func NewHandler(orderCacheStore types.OrderCacheStore, flightCacheStore types.FlightCacheStore,
-	flightStore types.FlightStore, bFilter bloomfilter.BloomFilter) *Handler {
+	flightStore types.FlightStore, bFilter bloomfilter.BloomFilter, flightService types.FlightService,
+	airportDirectory types.AirportDirectory) *Handler {
 	return &Handler{
 		orderCacheStore:  orderCacheStore,
 		flightCacheStore: flightCacheStore,
 		flightStore:      flightStore,
 		bFilter:          bFilter,
+		flightService:    flightService,
+		airportDirectory: airportDirectory,
 	}
 }
 func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
 	router.POST("/", h.CreateFlight)
 	router.GET("/", h.GetFlightsByCriteria)
+	router.GET("/calendar", h.GetFareCalendar)
 	router.GET("/:id", h.GetFlightById)
 }
 func (h *Handler) CreateFlight(ctx *gin.Context) {
@@ -41,80 +54,199 @@ func (h *Handler) CreateFlight(ctx *gin.Context) {
 		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
 		return
 	}
+	// iata codes are stored upper case
+	createFlight.Departure = strings.ToUpper(createFlight.Departure)
+	createFlight.Destination = strings.ToUpper(createFlight.Destination)
+	createFlight.CarrierCode = strings.ToUpper(createFlight.CarrierCode)
+	createFlight.FlightNumber = NormalizeFlightNumber(createFlight.FlightNumber)
+	createFlight.AircraftType = strings.ToUpper(createFlight.AircraftType)
 	if err := util.Validdate.Struct(createFlight); err != nil {
 		var valErrs validator.ValidationErrors
 		if errors.As(err, &valErrs) {
-			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%v", valErrs))
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
 		}
 		return
 	}
-	// log.Println("createFlight", createFlight)
-	flight, err := h.flightStore.CreateFlight(ctx, createFlight)
+	flight, err := h.flightService.CreateFlight(ctx, createFlight)
 	if err != nil {
+		if errors.Is(err, airport.ErrAirportNotFound) || errors.Is(err, ErrSeatMapNotFound) || errors.Is(err, ErrAircraftTypeNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, ErrSeatMapTooSmall) || errors.Is(err, ErrAircraftTypeTooSmall) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, ErrFlightNumberTaken) {
+			util.WriteError(ctx.Writer, http.StatusConflict, err)
+			return
+		}
 		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
 		return
 	}
-	binaryUUID, err := flight.ID.MarshalBinary()
-	if err != nil {
-		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("uuid marshal binnary err %w", err))
+	result := []types.FlightResponse{types.ConvertFlightToRespone(flight)}
+	if err := h.airportDirectory.LocalizeFlights(ctx, result); err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
 		return
 	}
-	if err := h.bFilter.Put(binaryUUID); err != nil {
-		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("bloom filter put err %w", err))
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, result[0]), "failed to response json")
+}
+
+func (h *Handler) GetFlightsByCriteria(ctx *gin.Context) {
+	// get params from query
+	queryParams, pagination, err := ParseFlightQuery(ctx.Request.URL.Query())
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
 		return
 	}
-	_, err = h.flightCacheStore.UpdateFlight(ctx, flight)
+	// time-of-day window is local time of departure airport, resolved once for the whole search
+	var location *time.Location
+	if queryParams.DepartAfter != "" || queryParams.DepartBefore != "" {
+		location, err = h.airportDirectory.Location(ctx, queryParams.Departure)
+		if err != nil {
+			if errors.Is(err, airport.ErrAirportNotFound) {
+				util.WriteError(ctx.Writer, http.StatusNotFound, err)
+				return
+			}
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	result, err := h.searchFlights(ctx, queryParams, location, pagination)
 	if err != nil {
-		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to update flight err %w", err))
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
 		return
 	}
-	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, flight), "failed to response json")
+	// cached pages keep seats of search time, remain comes from live seat counters
+	if err := h.overlayLiveRemain(ctx, result.Flights); err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.airportDirectory.LocalizeFlights(ctx, result.Flights); err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed on response json")
 }
 
-func (h *Handler) GetFlightsByCriteria(ctx *gin.Context) {
-	// get params from query
-	pagination := types.Pagination{
-		Offset: 0,
-		Limit:  10,
+/*
+*
+searchFlights: search page from search cache, database on miss and page is cached for FlightSearchCacheSeconds,
+searches filtered or sorted by remain always go to database since bookings change remain without invalidating pages,
+cache is best effort so its failures are logged and page is served from database
+*/
+func (h *Handler) searchFlights(ctx context.Context, queryParams types.QueryFlightRequest, location *time.Location,
+	pagination types.Pagination) (types.FlightsFetchResponse, error) {
+	ttl := time.Duration(config.AppConfig.FlightSearchCacheSeconds) * time.Second
+	if ttl <= 0 || queryParams.MinRemain > 0 || queryParams.SortBy == "remain" {
+		return h.flightStore.GetFlightsByCriteria(ctx, queryParams, location, pagination)
+	}
+	cacheKey, err := flightSearchCacheKey(queryParams, pagination)
+	if err != nil {
+		return types.FlightsFetchResponse{}, err
+	}
+	result, ok, err := h.flightCacheStore.GetFlightSearch(ctx, cacheKey)
+	if err != nil {
+		log.Printf("failed to get flight search %s %v", cacheKey, err)
+	}
+	if ok {
+		return result, nil
+	}
+	result, err = h.flightStore.GetFlightsByCriteria(ctx, queryParams, location, pagination)
+	if err != nil {
+		return types.FlightsFetchResponse{}, err
+	}
+	if err := h.flightCacheStore.SetFlightSearch(ctx, queryParams.Departure, queryParams.Destination,
+		cacheKey, result, ttl); err != nil {
+		log.Printf("failed to cache flight search %s %v", cacheKey, err)
+	}
+	return result, nil
+}
+
+// overlayLiveRemain: replace remain of flights with seat counters in order cache, read in one round trip
+func (h *Handler) overlayLiveRemain(ctx context.Context, flights []types.FlightResponse) error {
+	getRemainParams := make([]types.OrderCacheParam, 0, len(flights))
+	for _, flight := range flights {
+		getRemainParams = append(getRemainParams, types.OrderCacheParam{
+			FlightID:         flight.ID.String(),
+			CurrentTotal:     int64(flight.AvailableSeats),
+			CurrentWait:      int64(flight.WaitSeats),
+			CurrentWaitOrder: int64(flight.NextWaitOrder),
+		})
 	}
+	remains, err := h.orderCacheStore.GetCurrentRemains(ctx, getRemainParams)
+	if err != nil {
+		return err
+	}
+	for index := range flights {
+		flights[index].Remain = int(remains[index].CurrentRemain)
+	}
+	return nil
+}
+
+func (h *Handler) GetFareCalendar(ctx *gin.Context) {
 	query := ctx.Request.URL.Query()
-	if query.Has("limit") {
-		limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
-		if err != nil {
-			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("limit parse err: %w", err))
-			return
-		}
-		pagination.Limit = limit
+	queryParams := types.QueryFareCalendarRequest{
+		Departure:   strings.ToUpper(query.Get("departure")),
+		Destination: strings.ToUpper(query.Get("destination")),
+		Month:       query.Get("month"),
+		Date:        query.Get("date"),
+		FlexDays:    3,
 	}
-	if query.Has("offset") {
-		offset, err := strconv.ParseInt(query.Get("offset"), 10, 64)
+	if query.Has("flex_days") {
+		flexDays, err := strconv.ParseInt(query.Get("flex_days"), 10, 64)
 		if err != nil {
-			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("offset parse err: %w", err))
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("flex_days parse err: %w", err))
 			return
 		}
-		pagination.Offset = offset
+		queryParams.FlexDays = flexDays
 	}
-	var queryParams types.QueryFlightRequest
-	if query.Has("flignt_date") {
-		flignt_date, err := strconv.ParseInt(query.Get("flignt_date"), 10, 64)
-		if err != nil {
-			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("flignt_date parse err: %w", err))
-			return
+	if err := util.Validdate.Struct(queryParams); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid query:%w", valErrs))
 		}
-		queryParams.FlightDate = flignt_date
+		return
+	}
+	firstDate, lastDate, err := FareCalendarDates(queryParams)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
 	}
-	if query.Has("destination") {
-		queryParams.Destination = query.Get("destination")
+	cacheKey := fmt.Sprintf("%s:%s:%s:%s", queryParams.Departure, queryParams.Destination,
+		firstDate.Format(time.DateOnly), lastDate.Format(time.DateOnly))
+	calendar, ok, err := h.flightCacheStore.GetFareCalendar(ctx, cacheKey)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
 	}
-	if query.Has("departure") {
-		queryParams.Departure = query.Get("departure")
+	if ok {
+		util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, calendar), "failed to response json")
+		return
 	}
-	result, err := h.flightStore.GetFlightsByCriteria(ctx, queryParams, pagination)
+	// calendar days are local dates of departure airport
+	location, err := h.airportDirectory.Location(ctx, queryParams.Departure)
 	if err != nil {
+		if errors.Is(err, airport.ErrAirportNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
 		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
 		return
 	}
-	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed on response json")
+	entries, err := h.flightStore.GetFareCalendar(ctx, queryParams.Departure, queryParams.Destination, location.String(),
+		localMidnight(firstDate, location), localMidnight(lastDate.AddDate(0, 0, 1), location))
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	calendar = BuildFareCalendar(queryParams, location, firstDate, lastDate, entries)
+	// calendar is built already, a failed cache write only costs next request a query
+	if err := h.flightCacheStore.SetFareCalendar(ctx, cacheKey, calendar,
+		time.Duration(config.AppConfig.FareCalendarCacheSeconds)*time.Second); err != nil {
+		log.Printf("failed to cache fare calendar %s %v", cacheKey, err)
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, calendar), "failed to response json")
 }
 
 func (h *Handler) GetFlightById(ctx *gin.Context) {
@@ -128,10 +260,60 @@ func (h *Handler) GetFlightById(ctx *gin.Context) {
 		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", flightID, err))
 		return
 	}
-	result, err := h.flightStore.GetFlightById(ctx, id)
+	flight, err := h.readFlight(ctx, id)
 	if err != nil {
-		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get flight by id"))
+		if errors.Is(err, ErrFlightNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get flight by id %w", err))
+		return
+	}
+	flights := []types.FlightResponse{types.ConvertFlightToRespone(flight)}
+	if err := h.overlayLiveRemain(ctx, flights); err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
 		return
 	}
-	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed to response json")
+	if err := h.airportDirectory.LocalizeFlights(ctx, flights); err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, flights[0]), "failed to response json")
+}
+
+/*
+*
+readFlight: read-through flight, bloomfilter rejects unknown id, then flight cache, then database with cache backfill
+*/
+func (h *Handler) readFlight(ctx context.Context, id uuid.UUID) (types.Flight, error) {
+	binaryFlightID, err := id.MarshalBinary()
+	if err != nil {
+		return types.Flight{}, fmt.Errorf("failed to format FlightID to uuid binary %w", err)
+	}
+	isExist, err := h.bFilter.MightContain(binaryFlightID)
+	if err != nil {
+		return types.Flight{}, fmt.Errorf("failed to check FlightID in bloomfilter %w", err)
+	}
+	if !isExist {
+		return types.Flight{}, fmt.Errorf("flight %s %w", id, ErrFlightNotFound)
+	}
+	flight, err := h.flightCacheStore.GetFlightCacheInfo(ctx, id.String())
+	if err == nil {
+		return flight, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return types.Flight{}, err
+	}
+	flights, err := h.flightStore.GetFlightsByIds(ctx, []uuid.UUID{id})
+	if err != nil {
+		return types.Flight{}, err
+	}
+	// bloomfilter false positive
+	if len(flights) == 0 {
+		return types.Flight{}, fmt.Errorf("flight %s %w", id, ErrFlightNotFound)
+	}
+	if err := h.flightCacheStore.FillFlightCacheInfo(ctx, flights[0]); err != nil {
+		return types.Flight{}, err
+	}
+	return flights[0], nil
 }
@@ -0,0 +1,189 @@
+package flight
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	bloomfilter "github.com/alovn/go-bloomfilter"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/service/overbooking"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+var (
+	ErrSeatMapNotFound = apperr.New(apperr.NotFound, "seat map not found")
+	ErrSeatMapTooSmall = apperr.New(apperr.BadRequest, "seat map smaller than capacity")
+	ErrFlightExists    = apperr.New(apperr.Conflict, "flight already exists")
+	// same carrier and flight number already operates on that date
+	ErrFlightNumberTaken    = apperr.New(apperr.Conflict, "flight number already used on operating date")
+	ErrAircraftTypeNotFound = apperr.New(apperr.NotFound, "aircraft type not found")
+	ErrAircraftTypeTooSmall = apperr.New(apperr.BadRequest, "aircraft type smaller than capacity")
+	ErrFlightNotFound       = apperr.New(apperr.NotFound, "flight not found")
+)
+
+// flightNumberDateIndex: unique index on (carrier_code, flight_number, operating_date)
+const flightNumberDateIndex = "flight_number_date"
+
+// create flight rows together with seats, bloom filter and cache entries
+type FlightService struct {
+	flightStore        types.FlightStore
+	flightCacheStore   types.FlightCacheStore
+	bFilter            bloomfilter.BloomFilter
+	seatMapStore       types.SeatMapStore
+	overbookingService types.OverbookingService
+	airportDirectory   types.AirportDirectory
+	aircraftTypeStore  types.AircraftTypeStore
+	carrierCode        string
+}
+
+func NewFlightService(flightStore types.FlightStore, flightCacheStore types.FlightCacheStore, bFilter bloomfilter.BloomFilter,
+	seatMapStore types.SeatMapStore, overbookingService types.OverbookingService, airportDirectory types.AirportDirectory,
+	aircraftTypeStore types.AircraftTypeStore, carrierCode string) *FlightService {
+	return &FlightService{
+		flightStore:        flightStore,
+		flightCacheStore:   flightCacheStore,
+		bFilter:            bFilter,
+		seatMapStore:       seatMapStore,
+		overbookingService: overbookingService,
+		airportDirectory:   airportDirectory,
+		aircraftTypeStore:  aircraftTypeStore,
+		carrierCode:        carrierCode,
+	}
+}
+
+/*
+*
+CreateFlight: validate airports and seat map, default carrier code, capacity and seat map from aircraft type,
+oversell by overbooking policy when wait_seats not given,
+then insert flight, instantiate seat map, put into bloom filter and cache
+return ErrFlightExists when scheduled flight already generated, after finishing steps an earlier run did not reach
+*/
+func (service *FlightService) CreateFlight(ctx context.Context, createFlight types.CreateFlightRequest) (types.Flight, error) {
+	for _, iataCode := range []string{createFlight.Departure, createFlight.Destination} {
+		if _, err := service.airportDirectory.Location(ctx, iataCode); err != nil {
+			return types.Flight{}, err
+		}
+	}
+	if createFlight.CarrierCode == "" {
+		createFlight.CarrierCode = service.carrierCode
+	}
+	if createFlight.AircraftType != "" {
+		aircraftType, err := service.aircraftTypeStore.GetAircraftTypeByCode(ctx, createFlight.AircraftType)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return types.Flight{}, fmt.Errorf("%s %w", createFlight.AircraftType, ErrAircraftTypeNotFound)
+			}
+			return types.Flight{}, err
+		}
+		createFlight.AircraftType = aircraftType.Code
+		if createFlight.Capacity == 0 {
+			createFlight.Capacity = int64(aircraftType.TotalSeats)
+		}
+		if createFlight.AvailableSeats == 0 {
+			createFlight.AvailableSeats = createFlight.Capacity
+		}
+		if createFlight.Capacity > int64(aircraftType.TotalSeats) {
+			return types.Flight{}, fmt.Errorf("aircraft type %s has %d seats, capacity %d %w",
+				aircraftType.Code, aircraftType.TotalSeats, createFlight.Capacity, ErrAircraftTypeTooSmall)
+		}
+		if createFlight.SeatMapID == "" && aircraftType.SeatMapID.Valid {
+			createFlight.SeatMapID = aircraftType.SeatMapID.UUID.String()
+		}
+	}
+	// physical capacity defaults to available_seats
+	capacity := createFlight.Capacity
+	if capacity == 0 {
+		capacity = createFlight.AvailableSeats
+	}
+	createFlight.WaitSeatsOverride = createFlight.WaitSeats != nil
+	if createFlight.WaitSeats == nil {
+		// wait_seats not given, oversell by overbooking policy
+		waitSeats, err := service.overbookingService.ComputeWaitSeats(ctx, createFlight.Departure, createFlight.Destination,
+			time.Unix(createFlight.FlightDate, 0).UTC(), capacity)
+		if err != nil && !errors.Is(err, overbooking.ErrPolicyNotFound) {
+			return types.Flight{}, err
+		}
+		createFlight.WaitSeats = &waitSeats
+	}
+	var seatMap types.SeatMapTemplate
+	if createFlight.SeatMapID != "" {
+		template, err := service.seatMapStore.GetTemplateById(ctx, uuid.MustParse(createFlight.SeatMapID))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return types.Flight{}, fmt.Errorf("%s %w", createFlight.SeatMapID, ErrSeatMapNotFound)
+			}
+			return types.Flight{}, err
+		}
+		if int64(template.Layout.BookableSeats()) < capacity {
+			return types.Flight{}, fmt.Errorf("seat map %s has %d bookable seats, capacity %d %w",
+				createFlight.SeatMapID, template.Layout.BookableSeats(), capacity, ErrSeatMapTooSmall)
+		}
+		seatMap = template
+	}
+	flight, err := service.flightStore.CreateFlight(ctx, createFlight)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Flight{}, service.provisionExistingFlight(ctx, createFlight, seatMap)
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == flightNumberDateIndex {
+			return types.Flight{}, fmt.Errorf("%s%s %w", createFlight.CarrierCode, createFlight.FlightNumber, ErrFlightNumberTaken)
+		}
+		return types.Flight{}, err
+	}
+	if err := service.provisionFlight(ctx, flight, seatMap); err != nil {
+		return types.Flight{}, err
+	}
+	return flight, nil
+}
+
+/*
+*
+provisionExistingFlight: scheduled flight row exists from earlier run, which may have stopped before seats, bloom filter or cache,
+redo those steps, every one of them is idempotent, and report ErrFlightExists
+*/
+func (service *FlightService) provisionExistingFlight(ctx context.Context, createFlight types.CreateFlightRequest,
+	seatMap types.SeatMapTemplate) error {
+	if !createFlight.ScheduleID.Valid {
+		return ErrFlightExists
+	}
+	flight, err := service.flightStore.GetFlightByScheduleDate(ctx, createFlight.ScheduleID.UUID, time.Unix(createFlight.FlightDate, 0).UTC())
+	if err != nil {
+		return err
+	}
+	if err := service.provisionFlight(ctx, flight, seatMap); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s %w", flight.ID, ErrFlightExists)
+}
+
+// provisionFlight: instantiate seat map, put flight into bloom filter and cache
+func (service *FlightService) provisionFlight(ctx context.Context, flight types.Flight, seatMap types.SeatMapTemplate) error {
+	if seatMap.ID != uuid.Nil {
+		// instantiate seat map template for flight, seats already created are skipped
+		if err := service.seatMapStore.CreateFlightSeats(ctx, seatMap.Layout.Seats(flight.ID)); err != nil {
+			return fmt.Errorf("failed to create flight seats err %w", err)
+		}
+	}
+	binaryUUID, err := flight.ID.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("uuid marshal binnary err %w", err)
+	}
+	if err := service.bFilter.Put(binaryUUID); err != nil {
+		return fmt.Errorf("bloom filter put err %w", err)
+	}
+	if _, err := service.flightCacheStore.UpdateFlight(ctx, flight); err != nil {
+		return fmt.Errorf("failed to update flight err %w", err)
+	}
+	// cached search pages of route miss new flight, when they could not be dropped it shows up once they expire
+	if err := service.flightCacheStore.InvalidateFlightSearch(ctx, flight.Departure, flight.Destination); err != nil {
+		log.Printf("failed to invalidate flight search of flight %s %v", flight.ID, err)
+	}
+	return nil
+}
@@ -25,16 +25,28 @@ func NewFlightStore(db *sql.DB) *FlightStore {
 func (flightStore *FlightStore) CreateFlight(ctx context.Context, createParams types.CreateFlightRequest) (types.Flight, error) {
 	// generate uuid
 	flightID := uuid.New()
-	queryBuilder, err := flightStore.db.Prepare("INSERT INTO flights(id,price,destination,departure,available_seats, wait_seats,flight_date) VALUES($1,$2,$3,$4,$5,$6,$7) RETURNING *;")
+	// physical capacity defaults to available_seats
+	capacity := createParams.Capacity
+	if capacity == 0 {
+		capacity = createParams.AvailableSeats
+	}
+	// scheduled flight generated twice for same flight_date is skipped, scan returns sql.ErrNoRows
+	// operating_date is flight_date in departure airport timezone
+	queryBuilder, err := flightStore.db.Prepare("INSERT INTO flights(id,price,destination,departure,available_seats, wait_seats,flight_date,capacity,wait_capacity,wait_capacity_override,schedule_id,carrier_code,flight_number,aircraft_type,operating_date,arrival_date) " +
+		"VALUES($1,$2,$3,$4,$5,$6,$7,$8,$6,$14,$9,$10,NULLIF($11, ''),NULLIF($12, ''),(SELECT ($7::timestamptz AT TIME ZONE timezone)::date FROM airports WHERE iata_code = $4),$13) " +
+		"ON CONFLICT (schedule_id, flight_date) DO NOTHING RETURNING " + flightColumns + ";")
 	if err != nil {
 		return types.Flight{}, fmt.Errorf("prepare statement flights: %w", err)
 	}
 	defer queryBuilder.Close()
-	var result types.Flight
 
-	err = queryBuilder.QueryRowContext(ctx, flightID, createParams.Price, createParams.Destination, createParams.Departure,
-		createParams.AvailableSeats, createParams.WaitSeats, time.Unix(createParams.FlightDate, 0).UTC()).Scan(&result.ID, &result.Departure, &result.Destination, &result.Price, &result.FlightDate, &result.AvailableSeats, &result.WaitSeats,
-		&result.NextWaitOrder, &result.CreatedAt, &result.UpdatedAt)
+	var arrivalDate sql.NullTime
+	if createParams.ArrivalDate > 0 {
+		arrivalDate = sql.NullTime{Time: time.Unix(createParams.ArrivalDate, 0).UTC(), Valid: true}
+	}
+	result, err := scanFlight(queryBuilder.QueryRowContext(ctx, flightID, createParams.Price, createParams.Destination, createParams.Departure,
+		createParams.AvailableSeats, *createParams.WaitSeats, time.Unix(createParams.FlightDate, 0).UTC(), capacity, createParams.ScheduleID,
+		createParams.CarrierCode, createParams.FlightNumber, createParams.AircraftType, arrivalDate, createParams.WaitSeatsOverride))
 	if err != nil {
 		return types.Flight{}, fmt.Errorf("could not insert flights: %w", err)
 	}
@@ -43,9 +55,10 @@ func (flightStore *FlightStore) CreateFlight(ctx context.Context, createParams t
 
 func (flightStore *FlightStore) GetFlightsByCriteria(ctx context.Context,
 	queryParams types.QueryFlightRequest,
+	location *time.Location,
 	pageInfo types.Pagination) (types.FlightsFetchResponse, error) {
 	// original sql
-	queryBuilder := sq.Select("id", "price", "departure", "destination", "flight_date", "available_seats", "wait_seats", "next_wait_order", "created_at", "updated_at").From("flights").PlaceholderFormat(sq.Dollar)
+	queryBuilder := sq.Select(flightColumns).From("flights").PlaceholderFormat(sq.Dollar)
 	// fligt_date >= time.Now()
 	whereCondition := []sq.Sqlizer{sq.GtOrEq{"flight_date": time.Now().UTC()},
 		sq.Or{sq.NotEq{"available_seats": 0}, sq.NotEq{"wait_seats": 0}}}
@@ -59,17 +72,52 @@ func (flightStore *FlightStore) GetFlightsByCriteria(ctx context.Context,
 	if queryParams.Destination != "" {
 		whereCondition = append(whereCondition, sq.Eq{"destination": queryParams.Destination})
 	}
+	if queryParams.CarrierCode != "" {
+		whereCondition = append(whereCondition, sq.Eq{"carrier_code": queryParams.CarrierCode})
+	}
+	if queryParams.FlightNumber != "" {
+		whereCondition = append(whereCondition, sq.Eq{"flight_number": queryParams.FlightNumber})
+	}
+	if queryParams.DateFrom > 0 {
+		whereCondition = append(whereCondition, sq.GtOrEq{"flight_date": time.Unix(queryParams.DateFrom, 0)})
+	}
+	if queryParams.DateTo > 0 {
+		whereCondition = append(whereCondition, sq.Lt{"flight_date": time.Unix(queryParams.DateTo, 0)})
+	}
+	if queryParams.PriceMin > 0 {
+		whereCondition = append(whereCondition, sq.GtOrEq{"price": queryParams.PriceMin})
+	}
+	if queryParams.PriceMax > 0 {
+		whereCondition = append(whereCondition, sq.LtOrEq{"price": queryParams.PriceMax})
+	}
+	if queryParams.MinRemain > 0 {
+		whereCondition = append(whereCondition, sq.Expr("available_seats + wait_seats >= ?", queryParams.MinRemain))
+	}
+	if location != nil && (queryParams.DepartAfter != "" || queryParams.DepartBefore != "") {
+		from := max(time.Now().Unix(), queryParams.FlightDate, queryParams.DateFrom)
+		whereCondition = append(whereCondition, departureTimeWindow(queryParams.DepartAfter, queryParams.DepartBefore,
+			location, time.Unix(from, 0), time.Unix(queryParams.DateTo, 0)))
+	}
+	// keyset mode continues after cursor instead of skipping offset rows
+	if pageInfo.Cursor != "" {
+		cursor, err := decodeFlightCursor(pageInfo.Cursor, queryParams)
+		if err != nil {
+			return types.FlightsFetchResponse{}, err
+		}
+		whereCondition = append(whereCondition, flightKeysetCondition(cursor))
+	}
 	queryBuilder = queryBuilder.Where(sq.And(whereCondition))
 	// get pagenation info
 	offset := uint64(pageInfo.Offset)
 	limit := uint64(pageInfo.Limit)
-	if pageInfo.Offset > 0 {
+	if pageInfo.Cursor == "" && pageInfo.Offset > 0 {
 		queryBuilder = queryBuilder.Offset(offset)
 	}
 	if limit > 0 {
-		queryBuilder = queryBuilder.Limit(limit)
+		// one more row tells whether next page exists
+		queryBuilder = queryBuilder.Limit(limit + 1)
 	}
-	queryBuilder = queryBuilder.OrderBy("flight_date ASC")
+	queryBuilder = queryBuilder.OrderBy(flightSortColumns(queryParams.SortBy, queryParams.SortOrder)...)
 
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -79,35 +127,43 @@ func (flightStore *FlightStore) GetFlightsByCriteria(ctx context.Context,
 	if err != nil {
 		return types.FlightsFetchResponse{}, err
 	}
-	result := types.FlightsFetchResponse{}
+	defer rows.Close()
+	flights := []types.Flight{}
 	for rows.Next() {
-		var flight types.Flight
-		err = rows.Scan(&flight.ID,
-			&flight.Price,
-			&flight.Departure,
-			&flight.Destination,
-			&flight.FlightDate,
-			&flight.AvailableSeats,
-			&flight.WaitSeats,
-			&flight.NextWaitOrder,
-			&flight.CreatedAt,
-			&flight.UpdatedAt,
-		)
+		flight, err := scanFlight(rows)
 		if err != nil {
 			return types.FlightsFetchResponse{}, err
 		}
-		result.Flights = append(result.Flights, types.ConvertFlightToRespone(flight))
+		flights = append(flights, flight)
+	}
+	if err := rows.Err(); err != nil {
+		return types.FlightsFetchResponse{}, err
 	}
+	result := types.FlightsFetchResponse{}
 	result.Limit = pageInfo.Limit
 	result.Offset = pageInfo.Offset
-	if len(result.Flights) > 0 {
-		result.NextOffset = int64(offset + limit)
+	result.Cursor = pageInfo.Cursor
+	if limit > 0 && uint64(len(flights)) > limit {
+		flights = flights[:limit]
+		result.HasMore = true
+	}
+	for _, flight := range flights {
+		result.Flights = append(result.Flights, types.ConvertFlightToRespone(flight))
+	}
+	if result.HasMore {
+		result.NextCursor, err = encodeFlightCursor(flights[len(flights)-1], queryParams)
+		if err != nil {
+			return types.FlightsFetchResponse{}, err
+		}
+		if pageInfo.Cursor == "" {
+			result.NextOffset = int64(offset + limit)
+		}
 	}
 	return result, nil
 }
 
 func (flightStore *FlightStore) GetFlightById(ctx context.Context, flightID uuid.UUID) (types.FlightResponse, error) {
-	queryBuilder := sq.Select("id", "price", "departure", "destination", "flight_date", "available_seats", "wait_seats", "next_wait_order", "created_at", "updated_at").From("flights").PlaceholderFormat(sq.Dollar)
+	queryBuilder := sq.Select(flightColumns).From("flights").PlaceholderFormat(sq.Dollar)
 	queryBuilder = queryBuilder.Where(sq.Eq{"id": flightID})
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -120,20 +176,10 @@ func (flightStore *FlightStore) GetFlightById(ctx context.Context, flightID uuid
 		}
 		return types.FlightResponse{}, fmt.Errorf("failed to executed %w", err)
 	}
+	defer rows.Close()
 	var result types.FlightResponse
 	for rows.Next() {
-		var flight types.Flight
-		err = rows.Scan(&flight.ID,
-			&flight.Price,
-			&flight.Departure,
-			&flight.Destination,
-			&flight.FlightDate,
-			&flight.AvailableSeats,
-			&flight.WaitSeats,
-			&flight.NextWaitOrder,
-			&flight.CreatedAt,
-			&flight.UpdatedAt,
-		)
+		flight, err := scanFlight(rows)
 		if err != nil {
 			return types.FlightResponse{}, err
 		}
@@ -142,6 +188,190 @@ func (flightStore *FlightStore) GetFlightById(ctx context.Context, flightID uuid
 	return result, nil
 }
 
+/*
+*
+departureTimeWindow: local departure time within [after, before) at location as utc ranges of flight_date,
+one range per local date from the day before from until to, window wraps past midnight when after is later than before
+*/
+func departureTimeWindow(after string, before string, location *time.Location, from time.Time, to time.Time) sq.Sqlizer {
+	afterClock, _ := time.Parse("15:04", after)
+	beforeClock, _ := time.Parse("15:04", before)
+	first := from.In(location)
+	windows := sq.Or{}
+	// day before from keeps flights of a window wrapping past midnight into first day
+	for day := time.Date(first.Year(), first.Month(), first.Day()-1, 0, 0, 0, 0, location); day.Before(to); day = day.AddDate(0, 0, 1) {
+		start := time.Date(day.Year(), day.Month(), day.Day(), afterClock.Hour(), afterClock.Minute(), 0, 0, location)
+		end := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, location)
+		if before != "" {
+			end = time.Date(day.Year(), day.Month(), day.Day(), beforeClock.Hour(), beforeClock.Minute(), 0, 0, location)
+			if after > before {
+				end = end.AddDate(0, 0, 1)
+			}
+		}
+		windows = append(windows, sq.And{sq.GtOrEq{"flight_date": start.UTC()}, sq.Lt{"flight_date": end.UTC()}})
+	}
+	return windows
+}
+
+/*
+*
+GetNextFlightsOnRoute: flights on same departure and destination after given time, nearest first
+*/
+func (flightStore *FlightStore) GetNextFlightsOnRoute(ctx context.Context, departure string, destination string,
+	after time.Time, limit uint64) ([]types.Flight, error) {
+	queryBuilder := sq.Select(flightColumns).From("flights").
+		Where(sq.Eq{"departure": departure, "destination": destination}).
+		Where(sq.Gt{"flight_date": after}).
+		OrderBy("flight_date ASC").Limit(limit).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to use query builder: %w", err)
+	}
+	rows, err := flightStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to executed %w", err)
+	}
+	defer rows.Close()
+	flights := []types.Flight{}
+	for rows.Next() {
+		flight, err := scanFlight(rows)
+		if err != nil {
+			return nil, err
+		}
+		flights = append(flights, flight)
+	}
+	return flights, rows.Err()
+}
+
+/*
+*
+UpdateWaitCapacity: replace total oversell seats and remaining wait seats of flight
+*/
+func (flightStore *FlightStore) UpdateWaitCapacity(ctx context.Context, flightID uuid.UUID, waitCapacity int32, waitSeats int32) (types.Flight, error) {
+	queryBuilder := sq.Update("flights").Set("wait_capacity", waitCapacity).Set("wait_seats", waitSeats).
+		Set("updated_at", time.Now().UTC()).Where(sq.Eq{"id": flightID}).
+		Suffix("RETURNING " + flightColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Flight{}, fmt.Errorf("failed to use query builder: %w", err)
+	}
+	flight, err := scanFlight(flightStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.Flight{}, fmt.Errorf("failed to update wait capacity %w", err)
+	}
+	return flight, nil
+}
+
+/*
+*
+GetFlightsDepartingBetween: flights with flight_date in [from, to)
+*/
+func (flightStore *FlightStore) GetFlightsDepartingBetween(ctx context.Context, from time.Time, to time.Time) ([]types.Flight, error) {
+	queryBuilder := sq.Select(flightColumns).From("flights").
+		Where(sq.GtOrEq{"flight_date": from}).Where(sq.Lt{"flight_date": to}).
+		OrderBy("flight_date ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to use query builder: %w", err)
+	}
+	rows, err := flightStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to executed %w", err)
+	}
+	defer rows.Close()
+	flights := []types.Flight{}
+	for rows.Next() {
+		flight, err := scanFlight(rows)
+		if err != nil {
+			return nil, err
+		}
+		flights = append(flights, flight)
+	}
+	return flights, rows.Err()
+}
+
+/*
+*
+GetFlightsByIds: flights with given ids, missing ids are skipped
+*/
+func (flightStore *FlightStore) GetFlightsByIds(ctx context.Context, flightIDs []uuid.UUID) ([]types.Flight, error) {
+	flights := []types.Flight{}
+	if len(flightIDs) == 0 {
+		return flights, nil
+	}
+	queryBuilder := sq.Select(flightColumns).From("flights").
+		Where(sq.Eq{"id": flightIDs}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to use query builder: %w", err)
+	}
+	rows, err := flightStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to executed %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		flight, err := scanFlight(rows)
+		if err != nil {
+			return nil, err
+		}
+		flights = append(flights, flight)
+	}
+	return flights, rows.Err()
+}
+
+/*
+*
+GetFlightByScheduleDate: flight generated by schedule_id at flight_date, sql.ErrNoRows when not generated
+*/
+func (flightStore *FlightStore) GetFlightByScheduleDate(ctx context.Context, scheduleID uuid.UUID, flightDate time.Time) (types.Flight, error) {
+	queryBuilder := sq.Select(flightColumns).From("flights").
+		Where(sq.Eq{"schedule_id": scheduleID, "flight_date": flightDate}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Flight{}, fmt.Errorf("failed to use query builder: %w", err)
+	}
+	flight, err := scanFlight(flightStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.Flight{}, fmt.Errorf("failed to get flight of schedule %s at %s %w", scheduleID, flightDate, err)
+	}
+	return flight, nil
+}
+
+/*
+*
+GetFareCalendar: lowest price and confirmed seats of bookable flights per local date of timezone, flight_date in [from, to),
+waitlist seats are not counted
+*/
+func (flightStore *FlightStore) GetFareCalendar(ctx context.Context, departure string, destination string, timezone string,
+	from time.Time, to time.Time) ([]types.FareCalendarEntry, error) {
+	queryBuilder := sq.Select().Column(sq.Expr("(flight_date AT TIME ZONE ?)::date AS local_date", timezone)).
+		Columns("MIN(price)", "COUNT(*)", "SUM(available_seats)").From("flights").
+		Where(sq.Eq{"departure": departure, "destination": destination}).
+		Where(sq.GtOrEq{"flight_date": from}).Where(sq.Lt{"flight_date": to}).
+		Where(sq.GtOrEq{"flight_date": time.Now().UTC()}).
+		Where(sq.Or{sq.NotEq{"available_seats": 0}, sq.NotEq{"wait_seats": 0}}).
+		GroupBy("local_date").OrderBy("local_date ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to use query builder: %w", err)
+	}
+	rows, err := flightStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to executed %w", err)
+	}
+	defer rows.Close()
+	entries := []types.FareCalendarEntry{}
+	for rows.Next() {
+		var entry types.FareCalendarEntry
+		if err := rows.Scan(&entry.Date, &entry.LowestPrice, &entry.Flights, &entry.AvailableSeats); err != nil {
+			return nil, fmt.Errorf("scan fare calendar failed %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
 func (flightStore *FlightStore) UpdateFlight(tx *sql.Tx, ctx context.Context,
 	updateFlightParams types.UpdateFlightEntityParam) (types.Flight, error) {
 	updatedAt := time.Now().UTC()
@@ -149,7 +379,7 @@ func (flightStore *FlightStore) UpdateFlight(tx *sql.Tx, ctx context.Context,
 	queryBuilder = queryBuilder.Set("wait_seats", updateFlightParams.WaitSeats)
 	queryBuilder = queryBuilder.Set("next_wait_order", updateFlightParams.NextWaitOrder)
 	queryBuilder = queryBuilder.Set("updated_at", updatedAt)
-	queryBuilder = queryBuilder.Where(sq.Eq{"id": updateFlightParams.ID}).Suffix("RETURNING *;")
+	queryBuilder = queryBuilder.Where(sq.Eq{"id": updateFlightParams.ID}).Suffix("RETURNING " + flightColumns + ";")
 	queryBuilder = queryBuilder.PlaceholderFormat(sq.Dollar)
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -159,22 +389,52 @@ func (flightStore *FlightStore) UpdateFlight(tx *sql.Tx, ctx context.Context,
 	if err != nil {
 		return types.Flight{}, fmt.Errorf("failed to executed %w", err)
 	}
+	defer rows.Close()
 	var flight types.Flight
 	if rows.Next() {
-		err = rows.Scan(&flight.ID,
-			&flight.Departure,
-			&flight.Destination,
-			&flight.Price,
-			&flight.FlightDate,
-			&flight.AvailableSeats,
-			&flight.WaitSeats,
-			&flight.NextWaitOrder,
-			&flight.CreatedAt,
-			&flight.UpdatedAt,
-		)
+		flight, err = scanFlight(rows)
 		if err != nil {
 			return types.Flight{}, err
 		}
 	}
 	return flight, nil
 }
+
+// flightColumns: column order used by scanFlight
+const flightColumns = "id, departure, destination, price, flight_date, arrival_date, available_seats, wait_seats, next_wait_order, capacity, wait_capacity, wait_capacity_override, " +
+	"COALESCE(carrier_code, '') AS carrier_code, COALESCE(flight_number, '') AS flight_number, COALESCE(aircraft_type, '') AS aircraft_type, created_at, updated_at"
+
+type flightScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFlight(row flightScanner) (types.Flight, error) {
+	var flight types.Flight
+	err := row.Scan(&flight.ID,
+		&flight.Departure,
+		&flight.Destination,
+		&flight.Price,
+		&flight.FlightDate,
+		&flight.ArrivalDate,
+		&flight.AvailableSeats,
+		&flight.WaitSeats,
+		&flight.NextWaitOrder,
+		&flight.Capacity,
+		&flight.WaitCapacity,
+		&flight.WaitCapacityOverride,
+		&flight.CarrierCode,
+		&flight.FlightNumber,
+		&flight.AircraftType,
+		&flight.CreatedAt,
+		&flight.UpdatedAt,
+	)
+	if err != nil {
+		return types.Flight{}, err
+	}
+	// timestamptz columns come back in session timezone
+	flight.FlightDate = flight.FlightDate.UTC()
+	flight.ArrivalDate.Time = flight.ArrivalDate.Time.UTC()
+	flight.CreatedAt = flight.CreatedAt.UTC()
+	flight.UpdatedAt = flight.UpdatedAt.UTC()
+	return flight, nil
+}
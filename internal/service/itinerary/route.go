@@ -0,0 +1,273 @@
+package itinerary
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/config"
+	"github.com/yuanyu90221/airline-order-system/internal/service/abuse"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/customer"
+	"github.com/yuanyu90221/airline-order-system/internal/service/order"
+	"github.com/yuanyu90221/airline-order-system/internal/service/waitingroom"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	itineraryStore   types.ItineraryStore
+	itineraryService types.ItineraryService
+	flightStore      types.FlightStore
+	orderStore       types.OrderStore
+	orderCacheStore  types.OrderCacheStore
+	airportDirectory types.AirportDirectory
+	// flash sale flights only take orders of admitted waiting room tokens
+	waitingRoomCacheStore types.WaitingRoomCacheStore
+	// nil when abuse screening is disabled
+	abuseService types.AbuseService
+}
+
+func NewHandler(itineraryStore types.ItineraryStore, itineraryService types.ItineraryService,
+	flightStore types.FlightStore, orderStore types.OrderStore, orderCacheStore types.OrderCacheStore,
+	airportDirectory types.AirportDirectory, waitingRoomCacheStore types.WaitingRoomCacheStore,
+	abuseService types.AbuseService) *Handler {
+	return &Handler{
+		itineraryStore:        itineraryStore,
+		itineraryService:      itineraryService,
+		flightStore:           flightStore,
+		orderStore:            orderStore,
+		orderCacheStore:       orderCacheStore,
+		airportDirectory:      airportDirectory,
+		waitingRoomCacheStore: waitingRoomCacheStore,
+		abuseService:          abuseService,
+	}
+}
+
+func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
+	router.GET("/", h.SearchItineraries)
+	router.POST("/orders", h.BookItinerary)
+	router.GET("/:id/orders", h.GetItineraryOrders)
+}
+
+func (h *Handler) SearchItineraries(ctx *gin.Context) {
+	pagination := types.Pagination{
+		Offset: 0,
+		Limit:  10,
+	}
+	queryParams := types.QueryItineraryRequest{
+		MaxStops:             1,
+		MinConnectionMinutes: config.AppConfig.ConnectionMinMinutes,
+		MaxConnectionMinutes: config.AppConfig.ConnectionMaxMinutes,
+	}
+	query := ctx.Request.URL.Query()
+	queryParams.Departure = strings.ToUpper(query.Get("departure"))
+	queryParams.Destination = strings.ToUpper(query.Get("destination"))
+	// integer query params, missing ones keep defaults
+	integerParams := []struct {
+		name  string
+		value *int64
+	}{
+		{"limit", &pagination.Limit},
+		{"offset", &pagination.Offset},
+		{"flight_date", &queryParams.FlightDate},
+		{"max_stops", &queryParams.MaxStops},
+		{"min_connection_minutes", &queryParams.MinConnectionMinutes},
+		{"max_connection_minutes", &queryParams.MaxConnectionMinutes},
+	}
+	for _, param := range integerParams {
+		if !query.Has(param.name) {
+			continue
+		}
+		value, err := strconv.ParseInt(query.Get(param.name), 10, 64)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("%s parse err: %w", param.name, err))
+			return
+		}
+		*param.value = value
+	}
+	if pagination.Limit < 1 || pagination.Limit > 50 || pagination.Offset < 0 {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("limit must be within 1 to 50 and offset not negative"))
+		return
+	}
+	if err := util.Validdate.Struct(queryParams); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid query:%w", valErrs))
+		}
+		return
+	}
+	candidates, err := h.itineraryStore.SearchItineraries(ctx, queryParams, pagination)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	hasMore := int64(len(candidates)) > pagination.Limit
+	if hasMore {
+		candidates = candidates[:pagination.Limit]
+	}
+	legIDs := []uuid.UUID{}
+	for _, candidate := range candidates {
+		legIDs = append(legIDs, candidate.LegIDs...)
+	}
+	flights, err := h.flightStore.GetFlightsByIds(ctx, legIDs)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	flightsByID := make(map[uuid.UUID]types.FlightResponse, len(flights))
+	// live remain per flight, legs are shared between itineraries
+	remains := make(map[uuid.UUID]int64, len(flights))
+	for _, flight := range flights {
+		remain, err := h.orderCacheStore.GetCurrentRemain(ctx, types.OrderCacheParam{
+			FlightID:         flight.ID.String(),
+			CurrentTotal:     int64(flight.AvailableSeats),
+			CurrentWait:      int64(flight.WaitSeats),
+			CurrentWaitOrder: int64(flight.NextWaitOrder),
+		})
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			return
+		}
+		flightResponse := types.ConvertFlightToRespone(flight)
+		flightResponse.Remain = int(remain.CurrentRemain)
+		flightsByID[flight.ID] = flightResponse
+		remains[flight.ID] = remain.CurrentRemain
+	}
+	result := types.ItinerariesFetchResponse{
+		Itineraries: make([]types.ItineraryResponse, 0, len(candidates)),
+		Pagination:  pagination,
+	}
+	for _, candidate := range candidates {
+		itinerary := types.ItineraryResponse{
+			Stops:      candidate.Stops,
+			TotalPrice: candidate.TotalPrice,
+			Remain:     -1,
+			Legs:       make([]types.FlightResponse, 0, len(candidate.LegIDs)),
+		}
+		for _, legID := range candidate.LegIDs {
+			itinerary.Legs = append(itinerary.Legs, flightsByID[legID])
+			if itinerary.Remain < 0 || remains[legID] < itinerary.Remain {
+				itinerary.Remain = remains[legID]
+			}
+		}
+		if err := h.airportDirectory.LocalizeFlights(ctx, itinerary.Legs); err != nil {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			return
+		}
+		itinerary.ConnectionMinutes = ConnectionMinutes(itinerary.Legs)
+		result.Itineraries = append(result.Itineraries, itinerary)
+	}
+	if hasMore {
+		result.NextOffset = pagination.Offset + pagination.Limit
+		result.HasMore = true
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed on response json")
+}
+
+func (h *Handler) BookItinerary(ctx *gin.Context) {
+	var requestItinerary types.CreateItineraryOrderRequest
+	if err := util.ParseJSON(ctx.Request, &requestItinerary); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(requestItinerary); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	if customerID, ok := customer.CustomerID(ctx); ok {
+		requestItinerary.CustomerID = customerID.String()
+	}
+	if bookingAgency, ok := agency.CurrentAgency(ctx); ok {
+		requestItinerary.AgencyID = bookingAgency.ID.String()
+	}
+	// tokens are checked here and only used once seats of every leg are held
+	admissions, err := waitingroom.RequireAdmission(ctx, h.waitingRoomCacheStore, requestItinerary.FlightIDs,
+		ctx.Request.Header.Values(waitingroom.TokenHeader))
+	if err != nil {
+		order.WriteAdmissionError(ctx, err)
+		return
+	}
+	requestItinerary.Admissions = admissions
+	evaluation, err := abuse.Screen(ctx, h.abuseService, requestItinerary.FlightIDs, requestItinerary.Passengers)
+	if err != nil {
+		order.WriteAbuseError(ctx, evaluation, err)
+		return
+	}
+	result, err := h.itineraryService.BookItinerary(ctx, requestItinerary)
+	if err != nil {
+		if errors.Is(err, agency.ErrAllotmentExceeded) {
+			util.WriteError(ctx.Writer, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, waitingroom.ErrAdmissionRequired) {
+			util.WriteError(ctx.Writer, http.StatusForbidden, err)
+			return
+		}
+		if errors.Is(err, order.ErrFlightNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, order.ErrSegmentsUnavailable) {
+			util.WriteError(ctx.Writer, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, ErrLegsNotConnected) || errors.Is(err, ErrPassengersMismatch) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	orderIDs := make([]string, 0, len(result.Orders))
+	for _, createdOrder := range result.Orders {
+		orderIDs = append(orderIDs, createdOrder.ID)
+	}
+	abuse.RecordOrders(ctx, h.abuseService, evaluation, orderIDs)
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, result), "failed to write result")
+}
+
+func (h *Handler) GetItineraryOrders(ctx *gin.Context) {
+	itineraryID := ctx.Param("id")
+	id, err := uuid.Parse(itineraryID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", itineraryID, err))
+		return
+	}
+	orders, err := h.orderStore.GetOrdersByItineraryID(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get orders %w", err))
+		return
+	}
+	if len(orders) == 0 {
+		util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("itinerary %s not found", itineraryID))
+		return
+	}
+	for _, legOrder := range orders {
+		if err := order.AuthorizeOrder(ctx, legOrder); err != nil {
+			order.WriteAuthorizeError(ctx, err)
+			return
+		}
+	}
+	result := types.QueryItineraryOrderResponse{
+		ItineraryID: id.String(),
+		Orders:      make([]types.QueryOrderResponse, 0, len(orders)),
+	}
+	for _, legOrder := range orders {
+		passengers, err := h.orderStore.GetPassengersByOrderID(ctx, legOrder.ID)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get passengers %w", err))
+			return
+		}
+		result.Orders = append(result.Orders, types.ConvertOrderEntityToResponse(legOrder, passengers))
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed to response json")
+}
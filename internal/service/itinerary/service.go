@@ -0,0 +1,173 @@
+package itinerary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	bloomfilter "github.com/alovn/go-bloomfilter"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/broker"
+	"github.com/yuanyu90221/airline-order-system/internal/config"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/order"
+	"github.com/yuanyu90221/airline-order-system/internal/service/waitingroom"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+var (
+	ErrLegsNotConnected   = apperr.New(apperr.BadRequest, "legs do not connect")
+	ErrPassengersMismatch = apperr.New(apperr.BadRequest, "passengers length not equal to ticket_numbers")
+)
+
+// book connecting itineraries, every leg is confirmed or none is
+type ItineraryService struct {
+	orderCacheStore       types.OrderCacheStore
+	flightCacheStore      types.FlightCacheStore
+	bFilter               bloomfilter.BloomFilter
+	mq                    *broker.Broker
+	agencyCacheStore      types.AgencyCacheStore
+	waitingRoomCacheStore types.WaitingRoomCacheStore
+}
+
+func NewItineraryService(orderCacheStore types.OrderCacheStore, flightCacheStore types.FlightCacheStore,
+	bFilter bloomfilter.BloomFilter, mq *broker.Broker, agencyCacheStore types.AgencyCacheStore,
+	waitingRoomCacheStore types.WaitingRoomCacheStore) *ItineraryService {
+	return &ItineraryService{
+		orderCacheStore:       orderCacheStore,
+		flightCacheStore:      flightCacheStore,
+		bFilter:               bFilter,
+		mq:                    mq,
+		agencyCacheStore:      agencyCacheStore,
+		waitingRoomCacheStore: waitingRoomCacheStore,
+	}
+}
+
+/*
+*
+BookItinerary: reserve confirmed seats on every leg counter in one cache script,
+then publish linked orders of all legs in one event, legs booked with api key count against allotment of agency,
+waiting room admissions of request are used once seats are held and given back when event is not published
+*/
+func (service *ItineraryService) BookItinerary(ctx context.Context, createItineraryOrder types.CreateItineraryOrderRequest) (types.CreateItineraryOrderResponse, error) {
+	if int64(len(createItineraryOrder.Passengers)) != createItineraryOrder.TicketNumbers {
+		return types.CreateItineraryOrderResponse{}, fmt.Errorf("passengers length %d, ticket_numbers %d %w",
+			len(createItineraryOrder.Passengers), createItineraryOrder.TicketNumbers, ErrPassengersMismatch)
+	}
+	legs, err := order.LoadSegmentFlights(ctx, service.bFilter, service.flightCacheStore, createItineraryOrder.FlightIDs)
+	if err != nil {
+		return types.CreateItineraryOrderResponse{}, err
+	}
+	if err := ValidateConnections(legs, config.AppConfig.ConnectionMinMinutes, config.AppConfig.ConnectionMaxMinutes); err != nil {
+		return types.CreateItineraryOrderResponse{}, err
+	}
+	allotment, err := agency.ReserveAllotment(ctx, service.agencyCacheStore, legs, createItineraryOrder.TicketNumbers)
+	if err != nil {
+		return types.CreateItineraryOrderResponse{}, err
+	}
+	reserved, result, err := order.ReserveSegments(ctx, service.orderCacheStore, legs, createItineraryOrder.TicketNumbers)
+	if err != nil {
+		agency.ReleaseAllotment(ctx, service.agencyCacheStore, allotment)
+		return types.CreateItineraryOrderResponse{}, err
+	}
+	itineraryID := uuid.New()
+	itineraryEvent := types.CreateOrderEvent{
+		ItineraryID: itineraryID.String(),
+		Legs:        make([]types.CreateOrderEvent, 0, len(reserved)),
+	}
+	for legIndex, leg := range reserved {
+		id := uuid.New()
+		pnr, err := order.ReservePNR(ctx, service.orderCacheStore, id)
+		if err != nil {
+			order.ReleaseSegments(ctx, service.orderCacheStore, reserved, createItineraryOrder.TicketNumbers)
+			releaseLegPNRs(ctx, service.orderCacheStore, itineraryEvent.Legs)
+			agency.ReleaseAllotment(ctx, service.agencyCacheStore, allotment)
+			return types.CreateItineraryOrderResponse{}, err
+		}
+		itineraryEvent.Legs = append(itineraryEvent.Legs, types.CreateOrderEvent{
+			ID:             id.String(),
+			FlightID:       leg.FlightID,
+			TicketNumbers:  createItineraryOrder.TicketNumbers,
+			AvailableSeats: result.Segments[legIndex].CurrentTotal,
+			WaitOrder:      -1,
+			WaitSeats:      result.Segments[legIndex].CurrentWait,
+			PNR:            pnr,
+			Passengers:     createItineraryOrder.Passengers,
+			ItineraryID:    itineraryID.String(),
+			LegSequence:    int16(legIndex + 1),
+			CustomerID:     createItineraryOrder.CustomerID,
+			AgencyID:       createItineraryOrder.AgencyID,
+			Fare:           legs[legIndex].Price,
+		})
+	}
+	consumed, err := waitingroom.ConsumeAdmission(ctx, service.waitingRoomCacheStore, createItineraryOrder.Admissions)
+	if err != nil {
+		order.ReleaseSegments(ctx, service.orderCacheStore, reserved, createItineraryOrder.TicketNumbers)
+		releaseLegPNRs(ctx, service.orderCacheStore, itineraryEvent.Legs)
+		agency.ReleaseAllotment(ctx, service.agencyCacheStore, allotment)
+		return types.CreateItineraryOrderResponse{}, err
+	}
+	// order worker creates orders of all legs in one transaction
+	if err := order.PublishCreateOrderEvent(ctx, service.mq, itineraryEvent); err != nil {
+		order.ReleaseSegments(ctx, service.orderCacheStore, reserved, createItineraryOrder.TicketNumbers)
+		releaseLegPNRs(ctx, service.orderCacheStore, itineraryEvent.Legs)
+		agency.ReleaseAllotment(ctx, service.agencyCacheStore, allotment)
+		waitingroom.RestoreAdmission(ctx, service.waitingRoomCacheStore, consumed)
+		return types.CreateItineraryOrderResponse{}, err
+	}
+	response := types.CreateItineraryOrderResponse{
+		ItineraryID: itineraryID.String(),
+		Orders:      make([]types.CreateOrderResponse, 0, len(itineraryEvent.Legs)),
+	}
+	for _, legEvent := range itineraryEvent.Legs {
+		response.Orders = append(response.Orders, types.ConvertCreateOrderEventToResponse(legEvent))
+	}
+	return response, nil
+}
+
+// releaseLegPNRs: free pnr of every leg already reserved when itinerary is not published
+func releaseLegPNRs(ctx context.Context, orderCacheStore types.OrderCacheStore, legs []types.CreateOrderEvent) {
+	for _, leg := range legs {
+		order.ReleasePNR(ctx, orderCacheStore, leg.PNR, uuid.MustParse(leg.ID))
+	}
+}
+
+/*
+*
+ValidateConnections: legs depart in the future, each leg departs from previous destination
+within minMinutes to maxMinutes after previous arrival
+*/
+func ValidateConnections(legs []types.Flight, minMinutes int64, maxMinutes int64) error {
+	if len(legs) > 0 && !legs[0].FlightDate.After(time.Now().UTC()) {
+		return fmt.Errorf("flight %s already departed %w", legs[0].ID, ErrLegsNotConnected)
+	}
+	for index := 1; index < len(legs); index++ {
+		previous, next := legs[index-1], legs[index]
+		if previous.Destination != next.Departure {
+			return fmt.Errorf("flight %s arrives %s, flight %s departs %s %w",
+				previous.ID, previous.Destination, next.ID, next.Departure, ErrLegsNotConnected)
+		}
+		if !previous.ArrivalDate.Valid {
+			return fmt.Errorf("flight %s has no arrival_date %w", previous.ID, ErrLegsNotConnected)
+		}
+		connection := int64(next.FlightDate.Sub(previous.ArrivalDate.Time) / time.Minute)
+		if connection < minMinutes || connection > maxMinutes {
+			return fmt.Errorf("connection %d minutes at %s not within %d to %d minutes %w",
+				connection, next.Departure, minMinutes, maxMinutes, ErrLegsNotConnected)
+		}
+	}
+	return nil
+}
+
+// ConnectionMinutes: minutes between arrival and next departure for each connection
+func ConnectionMinutes(legs []types.FlightResponse) []int64 {
+	connections := []int64{}
+	for index := 1; index < len(legs); index++ {
+		if legs[index-1].ArrivalDate == nil {
+			continue
+		}
+		connections = append(connections, int64(legs[index].FlightDate.Sub(*legs[index-1].ArrivalDate)/time.Minute))
+	}
+	return connections
+}
@@ -0,0 +1,102 @@
+package itinerary
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// legHorizon: later legs of itinerary depart within legHorizon after search window
+const legHorizon = 48 * time.Hour
+
+type ItineraryStore struct {
+	db *sql.DB
+}
+
+func NewItineraryStore(db *sql.DB) *ItineraryStore {
+	return &ItineraryStore{db: db}
+}
+
+/*
+*
+itineraryLegs: candidate legs with connection window of next departure,
+flights without arrival_date have null window and could not connect
+*/
+const itineraryLegs = `WITH legs AS (
+	SELECT id, departure, destination, flight_date, arrival_date, price,
+		arrival_date + $6::int * interval '1 minute' AS connect_from,
+		arrival_date + $7::int * interval '1 minute' AS connect_to
+	FROM flights
+	WHERE flight_date >= $3 AND flight_date < $5 AND (available_seats <> 0 OR wait_seats <> 0)
+)
+`
+
+const directItineraries = `SELECT f1.id AS leg1, NULL::uuid AS leg2, NULL::uuid AS leg3, 0 AS stops,
+	f1.price AS total_price, f1.flight_date AS departure_date
+FROM legs f1
+WHERE f1.departure = $1 AND f1.destination = $2 AND f1.flight_date < $4`
+
+const oneStopItineraries = `SELECT f1.id, f2.id, NULL::uuid, 1, f1.price + f2.price, f1.flight_date
+FROM legs f1
+JOIN legs f2 ON f2.departure = f1.destination AND f2.flight_date BETWEEN f1.connect_from AND f1.connect_to
+WHERE f1.departure = $1 AND f2.destination = $2 AND f1.flight_date < $4`
+
+const twoStopItineraries = `SELECT f1.id, f2.id, f3.id, 2, f1.price + f2.price + f3.price, f1.flight_date
+FROM legs f1
+JOIN legs f2 ON f2.departure = f1.destination AND f2.flight_date BETWEEN f1.connect_from AND f1.connect_to
+JOIN legs f3 ON f3.departure = f2.destination AND f3.flight_date BETWEEN f2.connect_from AND f2.connect_to
+WHERE f1.departure = $1 AND f3.destination = $2 AND f1.flight_date < $4
+	AND f1.destination <> $2 AND f2.destination <> $1`
+
+/*
+*
+SearchItineraries: direct and connecting itineraries up to max_stops, cheapest first,
+one more than limit is returned to tell whether next page exists
+*/
+func (itineraryStore *ItineraryStore) SearchItineraries(ctx context.Context, queryParams types.QueryItineraryRequest,
+	pagination types.Pagination) ([]types.ItineraryCandidate, error) {
+	query := itineraryLegs + "SELECT leg1, leg2, leg3, stops, total_price FROM (" + directItineraries
+	if queryParams.MaxStops >= 1 {
+		query += "\nUNION ALL\n" + oneStopItineraries
+	}
+	if queryParams.MaxStops >= 2 {
+		query += "\nUNION ALL\n" + twoStopItineraries
+	}
+	query += `) itineraries
+ORDER BY total_price ASC, stops ASC, departure_date ASC, leg1, leg2, leg3
+LIMIT $8 OFFSET $9;`
+	windowStart := time.Unix(queryParams.FlightDate, 0).UTC()
+	// search from now on when flight_date is in the past
+	if now := time.Now().UTC(); windowStart.Before(now) {
+		windowStart = now
+	}
+	windowEnd := time.Unix(queryParams.FlightDate, 0).UTC().Add(24 * time.Hour)
+	rows, err := itineraryStore.db.QueryContext(ctx, query,
+		queryParams.Departure, queryParams.Destination, windowStart, windowEnd, windowEnd.Add(legHorizon),
+		queryParams.MinConnectionMinutes, queryParams.MaxConnectionMinutes, pagination.Limit+1, pagination.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search itineraries %w", err)
+	}
+	defer rows.Close()
+	candidates := []types.ItineraryCandidate{}
+	for rows.Next() {
+		var leg1 uuid.UUID
+		var leg2, leg3 uuid.NullUUID
+		var candidate types.ItineraryCandidate
+		if err := rows.Scan(&leg1, &leg2, &leg3, &candidate.Stops, &candidate.TotalPrice); err != nil {
+			return nil, fmt.Errorf("scan itinerary failed %w", err)
+		}
+		candidate.LegIDs = []uuid.UUID{leg1}
+		for _, leg := range []uuid.NullUUID{leg2, leg3} {
+			if leg.Valid {
+				candidate.LegIDs = append(candidate.LegIDs, leg.UUID)
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
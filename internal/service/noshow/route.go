@@ -0,0 +1,34 @@
+package noshow
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	noShowStore types.NoShowStore
+}
+
+func NewHandler(noShowStore types.NoShowStore) *Handler {
+	return &Handler{
+		noShowStore: noShowStore,
+	}
+}
+
+func (h *Handler) RegisterAdminRoute(router *gin.RouterGroup) {
+	router.GET("/routes/:dep/:dest/noshow-stats", h.GetRouteStats)
+}
+
+func (h *Handler) GetRouteStats(ctx *gin.Context) {
+	departure := ctx.Param("dep")
+	destination := ctx.Param("dest")
+	stats, err := h.noShowStore.GetRouteStats(ctx, departure, destination)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, AggregateRouteStats(departure, destination, stats)), "failed to response json")
+}
@@ -0,0 +1,76 @@
+package noshow
+
+import (
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+func rateOf(booked int32, noShows int32) types.NoShowRateResponse {
+	response := types.NoShowRateResponse{Booked: booked, NoShows: noShows}
+	if booked > 0 {
+		response.NoShowRate = float64(noShows) / float64(booked)
+	}
+	return response
+}
+
+/*
+*
+AggregateRouteStats: roll up route stats into overall, per day of week and per lead time rates
+*/
+func AggregateRouteStats(departure string, destination string, stats []types.NoShowStat) types.NoShowStatsResponse {
+	var booked, noShows int32
+	dayBooked := map[int16]int32{}
+	dayNoShows := map[int16]int32{}
+	bucketBooked := map[string]int32{}
+	bucketNoShows := map[string]int32{}
+	for _, stat := range stats {
+		booked += stat.Booked
+		noShows += stat.NoShows
+		dayBooked[stat.DayOfWeek] += stat.Booked
+		dayNoShows[stat.DayOfWeek] += stat.NoShows
+		bucketBooked[stat.LeadTimeBucket] += stat.Booked
+		bucketNoShows[stat.LeadTimeBucket] += stat.NoShows
+	}
+	response := types.NoShowStatsResponse{
+		Departure:   departure,
+		Destination: destination,
+		Overall:     rateOf(booked, noShows),
+		ByDayOfWeek: []types.NoShowDayOfWeekResponse{},
+		ByLeadTime:  []types.NoShowLeadTimeResponse{},
+		Stats:       stats,
+	}
+	for day := int16(0); day < 7; day++ {
+		if _, ok := dayBooked[day]; !ok {
+			continue
+		}
+		response.ByDayOfWeek = append(response.ByDayOfWeek, types.NoShowDayOfWeekResponse{
+			DayOfWeek:          day,
+			NoShowRateResponse: rateOf(dayBooked[day], dayNoShows[day]),
+		})
+	}
+	for _, bucket := range LeadTimeBuckets {
+		if _, ok := bucketBooked[bucket.Label]; !ok {
+			continue
+		}
+		response.ByLeadTime = append(response.ByLeadTime, types.NoShowLeadTimeResponse{
+			LeadTimeBucket:     bucket.Label,
+			NoShowRateResponse: rateOf(bucketBooked[bucket.Label], bucketNoShows[bucket.Label]),
+		})
+	}
+	return response
+}
+
+/*
+*
+DayOfWeekRate: no-show rate of route on day of week across all lead times
+*/
+func DayOfWeekRate(stats []types.NoShowStat, dayOfWeek int16) types.NoShowRateResponse {
+	var booked, noShows int32
+	for _, stat := range stats {
+		if stat.DayOfWeek != dayOfWeek {
+			continue
+		}
+		booked += stat.Booked
+		noShows += stat.NoShows
+	}
+	return rateOf(booked, noShows)
+}
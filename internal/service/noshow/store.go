@@ -0,0 +1,186 @@
+package noshow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// LeadTimeBuckets: booking lead time ranges used to group no-show stats
+var LeadTimeBuckets = []types.LeadTimeBucket{
+	{Label: "0-6", MinDays: 0, MaxDays: 6},
+	{Label: "7-13", MinDays: 7, MaxDays: 13},
+	{Label: "14-29", MinDays: 14, MaxDays: 29},
+	{Label: "30+", MinDays: 30, MaxDays: -1},
+}
+
+// leadTimeBucketCase: sql case expression mapping lead_time_days into bucket label
+func leadTimeBucketCase() string {
+	var builder strings.Builder
+	builder.WriteString("CASE")
+	for _, bucket := range LeadTimeBuckets {
+		if bucket.MaxDays < 0 {
+			builder.WriteString(fmt.Sprintf(" WHEN lead_time_days >= %d THEN '%s'", bucket.MinDays, bucket.Label))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf(" WHEN lead_time_days <= %d THEN '%s'", bucket.MaxDays, bucket.Label))
+	}
+	builder.WriteString(" END")
+	return builder.String()
+}
+
+type NoShowStore struct {
+	db *sql.DB
+}
+
+func NewNoShowStore(db *sql.DB) *NoShowStore {
+	return &NoShowStore{db: db}
+}
+
+/*
+*
+GetFlightsPendingOutcome: flights departed before departedBefore with gate closed and outcomes not recorded yet
+*/
+func (noShowStore *NoShowStore) GetFlightsPendingOutcome(ctx context.Context, departedBefore time.Time, limit uint64) ([]uuid.UUID, error) {
+	queryBuilder := sq.Select("f.id").From("flights f").
+		Join("gate_closures g ON g.flight_id = f.id").
+		LeftJoin("flight_outcome_records r ON r.flight_id = f.id").
+		Where(sq.Eq{"r.flight_id": nil}).
+		Where(sq.LtOrEq{"f.flight_date": departedBefore}).
+		OrderBy("f.flight_date ASC").Limit(limit).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := noShowStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flights pending outcome %w", err)
+	}
+	defer rows.Close()
+	flightIDs := []uuid.UUID{}
+	for rows.Next() {
+		var flightID uuid.UUID
+		if err := rows.Scan(&flightID); err != nil {
+			return nil, fmt.Errorf("scan flight id failed %w", err)
+		}
+		flightIDs = append(flightIDs, flightID)
+	}
+	return flightIDs, rows.Err()
+}
+
+/*
+*
+RecordFlightOutcomes: record boarded, no-show or denied outcome of every ticketed passenger on flight_id,
+day of week is local at departure airport like schedule departures, return number of outcomes recorded
+*/
+func (noShowStore *NoShowStore) RecordFlightOutcomes(ctx context.Context, flightID uuid.UUID) (int64, error) {
+	tx, err := noShowStore.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("create db tx failed %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `INSERT INTO boarding_outcomes
+(passenger_id, order_id, flight_id, departure, destination, day_of_week, lead_time_days, outcome)
+SELECT p.id, o.id, f.id, f.departure, f.destination,
+  EXTRACT(DOW FROM f.flight_date AT TIME ZONE COALESCE(a.timezone, 'UTC'))::smallint,
+  GREATEST(0, FLOOR(EXTRACT(EPOCH FROM (f.flight_date - (o.created_at AT TIME ZONE 'UTC'))) / 86400))::integer,
+  CASE WHEN d.passenger_id IS NOT NULL THEN $2 WHEN p.checked_in_at IS NOT NULL THEN $3 ELSE $4 END
+FROM passengers p
+JOIN orders o ON o.id = p.order_id
+JOIN flights f ON f.id = o.flight_id
+LEFT JOIN airports a ON a.iata_code = f.departure
+LEFT JOIN denied_boardings d ON d.flight_id = f.id AND d.passenger_id = p.id
+WHERE f.id = $1 AND o.paid_at IS NOT NULL AND o.canceled_at IS NULL
+ON CONFLICT (passenger_id) DO NOTHING;`, flightID, types.OutcomeDenied, types.OutcomeBoarded, types.OutcomeNoShow)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("insert boarding outcomes failed %w", err)
+	}
+	recorded, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to get affected rows %w", err)
+	}
+	queryBuilder := sq.Insert("flight_outcome_records").Columns("flight_id", "passengers").
+		Values(flightID, recorded).Suffix("ON CONFLICT (flight_id) DO NOTHING;").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("flight outcome record query builder failed %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("insert flight outcome record failed %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit flight outcomes %w", err)
+	}
+	return recorded, nil
+}
+
+/*
+*
+ComputeStats: aggregate boarding outcomes by route, day of week and lead time bucket into noshow_stats,
+denied passengers showed up so they count as booked but not as no-show
+*/
+func (noShowStore *NoShowStore) ComputeStats(ctx context.Context) (int64, error) {
+	query := fmt.Sprintf(`INSERT INTO noshow_stats
+(departure, destination, day_of_week, lead_time_bucket, booked, no_shows, no_show_rate, computed_at)
+SELECT departure, destination, day_of_week, %s AS lead_time_bucket,
+  COUNT(*), COUNT(*) FILTER (WHERE outcome = $1),
+  ROUND(COUNT(*) FILTER (WHERE outcome = $1)::numeric / COUNT(*), 4), now()
+FROM boarding_outcomes
+GROUP BY departure, destination, day_of_week, lead_time_bucket
+ON CONFLICT (departure, destination, day_of_week, lead_time_bucket) DO UPDATE SET
+  booked = EXCLUDED.booked, no_shows = EXCLUDED.no_shows,
+  no_show_rate = EXCLUDED.no_show_rate, computed_at = EXCLUDED.computed_at;`, leadTimeBucketCase())
+	result, err := noShowStore.db.ExecContext(ctx, query, types.OutcomeNoShow)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute noshow stats %w", err)
+	}
+	return result.RowsAffected()
+}
+
+/*
+*
+GetRouteStats: no-show stats of route ordered by day of week and lead time
+*/
+func (noShowStore *NoShowStore) GetRouteStats(ctx context.Context, departure string, destination string) ([]types.NoShowStat, error) {
+	queryBuilder := sq.Select("departure", "destination", "day_of_week", "lead_time_bucket", "booked", "no_shows",
+		"no_show_rate", "computed_at").From("noshow_stats").
+		Where(sq.Eq{"departure": departure, "destination": destination}).
+		OrderBy("day_of_week ASC", "lead_time_bucket ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := noShowStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query noshow stats %w", err)
+	}
+	defer rows.Close()
+	stats := []types.NoShowStat{}
+	for rows.Next() {
+		var stat types.NoShowStat
+		err := rows.Scan(
+			&stat.Departure,
+			&stat.Destination,
+			&stat.DayOfWeek,
+			&stat.LeadTimeBucket,
+			&stat.Booked,
+			&stat.NoShows,
+			&stat.NoShowRate,
+			&stat.ComputedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan noshow stat failed %w", err)
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,59 @@
+package noshow
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// outcomeBatchSize: max flights recorded per tick
+const outcomeBatchSize = 50
+
+// record outcomes of departed flights and refresh no-show stats
+type StatsWorker struct {
+	noShowStore types.NoShowStore
+	interval    time.Duration
+}
+
+func NewStatsWorker(noShowStore types.NoShowStore, interval time.Duration) *StatsWorker {
+	return &StatsWorker{
+		noShowStore: noShowStore,
+		interval:    interval,
+	}
+}
+
+func (worker *StatsWorker) Run(ctx context.Context) error {
+	log.Println("noshow stats worker start")
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("noshow stats worker end")
+			return nil
+		case <-ticker.C:
+			worker.refresh(ctx)
+		}
+	}
+}
+
+func (worker *StatsWorker) refresh(ctx context.Context) {
+	flightIDs, err := worker.noShowStore.GetFlightsPendingOutcome(ctx, time.Now().UTC(), outcomeBatchSize)
+	if err != nil {
+		log.Printf("failed to get flights pending outcome %v", err)
+		return
+	}
+	if len(flightIDs) == 0 {
+		return
+	}
+	for _, flightID := range flightIDs {
+		if _, err := worker.noShowStore.RecordFlightOutcomes(ctx, flightID); err != nil {
+			log.Printf("failed to record outcomes for flight %s %v", flightID, err)
+		}
+	}
+	if _, err := worker.noShowStore.ComputeStats(ctx); err != nil {
+		log.Printf("failed to compute noshow stats %v", err)
+	}
+}
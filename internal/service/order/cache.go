@@ -2,10 +2,16 @@ package order
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/yuanyu90221/airline-order-system/internal/config"
+	"github.com/yuanyu90221/airline-order-system/internal/metrics"
 	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
 )
 
 type CacheStore struct {
@@ -18,13 +24,25 @@ func NewCacheStore(rdb *redis.Client) *CacheStore {
 	}
 }
 
+/*
+*
+CounterKey: prefix of seat counter keys of flight_id, {hash tag} keeps counters of all flights in one cluster slot,
+without hash tag keys stay flight_id:total, flight_id:wait and flight_id:wait_order
+*/
+func CounterKey(flightID string) string {
+	if config.AppConfig.InventoryHashTag == "" {
+		return flightID
+	}
+	return fmt.Sprintf("{%s}:%s", config.AppConfig.InventoryHashTag, flightID)
+}
+
 /*
 *
 CreateOrder: create order with flight_id
 */
 func (cache *CacheStore) CreateOrder(ctx context.Context, createOrderParam types.OrderCacheCreateParam,
 ) (types.OrderCacheResult, error) {
-	result := CreateOrderWithFlightID.Run(ctx, cache.rdb, []string{createOrderParam.FlightID},
+	result := CreateOrderWithFlightID.Run(ctx, cache.rdb, []string{CounterKey(createOrderParam.FlightID)},
 		createOrderParam.TicketNumbers,
 		createOrderParam.CurrentTotal,
 		createOrderParam.CurrentWait,
@@ -33,6 +51,7 @@ func (cache *CacheStore) CreateOrder(ctx context.Context, createOrderParam types
 	if err != nil {
 		return types.OrderCacheResult{}, fmt.Errorf("failed to createOrder with flightId: %s, %w", createOrderParam.FlightID, err)
 	}
+	metrics.ObserveOrderScript("create_order", createOrderParam.FlightID, resultList[3] == 1, resultList[4] == 1)
 	return types.OrderCacheResult{
 		CurrentTotal:     resultList[0],
 		CurrentWait:      resultList[1],
@@ -42,12 +61,46 @@ func (cache *CacheStore) CreateOrder(ctx context.Context, createOrderParam types
 	}, nil
 }
 
+/*
+*
+CreateSegmentedOrder: reserve confirmed seats on every segment flight in one script, nothing is reserved when any segment is short
+*/
+func (cache *CacheStore) CreateSegmentedOrder(ctx context.Context, createParam types.OrderCacheSegmentsCreateParam) (types.OrderCacheSegmentsResult, error) {
+	keys := make([]string, 0, 3*len(createParam.Segments))
+	args := make([]interface{}, 0, 1+3*len(createParam.Segments))
+	args = append(args, createParam.TicketNumbers)
+	for _, segment := range createParam.Segments {
+		counterKey := CounterKey(segment.FlightID)
+		keys = append(keys, counterKey+":total", counterKey+":wait", counterKey+":wait_order")
+		args = append(args, segment.CurrentTotal, segment.CurrentWait, segment.CurrentWaitOrder)
+	}
+	result := CreateOrderWithFlightIDs.Run(ctx, cache.rdb, keys, args...)
+	resultList, err := result.Int64Slice()
+	if err != nil {
+		return types.OrderCacheSegmentsResult{}, fmt.Errorf("failed to createOrder with %d segments, %w", len(createParam.Segments), err)
+	}
+	segmentsResult := types.OrderCacheSegmentsResult{
+		IsValid:  resultList[0] == 1,
+		Segments: make([]types.OrderCacheResult, 0, len(createParam.Segments)),
+	}
+	for index, segment := range createParam.Segments {
+		metrics.ObserveOrderScript("create_segmented_order", segment.FlightID, segmentsResult.IsValid, false)
+		segmentsResult.Segments = append(segmentsResult.Segments, types.OrderCacheResult{
+			CurrentTotal:     resultList[1+3*index],
+			CurrentWait:      resultList[2+3*index],
+			CurrentWaitOrder: resultList[3+3*index],
+			IsValid:          segmentsResult.IsValid,
+		})
+	}
+	return segmentsResult, nil
+}
+
 /*
 *
 GetCurrentRemain: get current flight_id remain
 */
 func (cache *CacheStore) GetCurrentRemain(ctx context.Context, getRemainParam types.OrderCacheParam) (types.OrderCacheRemain, error) {
-	result := GetCurrentRemainWithFlightID.Run(ctx, cache.rdb, []string{getRemainParam.FlightID},
+	result := GetCurrentRemainWithFlightID.Run(ctx, cache.rdb, []string{CounterKey(getRemainParam.FlightID)},
 		getRemainParam.CurrentTotal, getRemainParam.CurrentWait, getRemainParam.CurrentWaitOrder)
 	remain, err := result.Int64()
 	if err != nil {
@@ -58,6 +111,165 @@ func (cache *CacheStore) GetCurrentRemain(ctx context.Context, getRemainParam ty
 	}, nil
 }
 
+/*
+*
+GetCurrentRemains: remain of many flights read in one pipeline, counters not in cache yet fall back to defaults of param,
+read only so it never initializes counters
+*/
+func (cache *CacheStore) GetCurrentRemains(ctx context.Context, getRemainParams []types.OrderCacheParam) ([]types.OrderCacheRemain, error) {
+	pipe := cache.rdb.Pipeline()
+	counters := make([][2]*redis.StringCmd, 0, len(getRemainParams))
+	for _, getRemainParam := range getRemainParams {
+		counterKey := CounterKey(getRemainParam.FlightID)
+		counters = append(counters, [2]*redis.StringCmd{
+			pipe.Get(ctx, counterKey+":total"),
+			pipe.Get(ctx, counterKey+":wait"),
+		})
+	}
+	if len(counters) > 0 {
+		// missing counters answer redis.Nil, checked per command below
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to get remains %w", err)
+		}
+	}
+	remains := make([]types.OrderCacheRemain, 0, len(getRemainParams))
+	for index, getRemainParam := range getRemainParams {
+		total, err := counterOrDefault(counters[index][0], getRemainParam.CurrentTotal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get total with flightId: %s, %w", getRemainParam.FlightID, err)
+		}
+		wait, err := counterOrDefault(counters[index][1], getRemainParam.CurrentWait)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get wait with flightId: %s, %w", getRemainParam.FlightID, err)
+		}
+		remains = append(remains, types.OrderCacheRemain{CurrentRemain: total + wait})
+	}
+	return remains, nil
+}
+
+// counterOrDefault: value of counter GET, defaultValue when counter is not in cache
+func counterOrDefault(cmd *redis.StringCmd, defaultValue int64) (int64, error) {
+	value, err := cmd.Int64()
+	if errors.Is(err, redis.Nil) {
+		return defaultValue, nil
+	}
+	return value, err
+}
+
+/*
+*
+ReleaseOrder: give back seats reserved by CreateOrder when booking is abandoned
+*/
+func (cache *CacheStore) ReleaseOrder(ctx context.Context, releaseParam types.OrderCacheReleaseParam) (types.OrderCacheResult, error) {
+	isWait := 0
+	if releaseParam.IsWait {
+		isWait = 1
+	}
+	result := ReleaseOrderWithFlightID.Run(ctx, cache.rdb, []string{CounterKey(releaseParam.FlightID)},
+		releaseParam.TicketNumbers,
+		isWait,
+		releaseParam.CurrentTotal,
+		releaseParam.CurrentWait,
+		releaseParam.CurrentWaitOrder)
+	resultList, err := result.Int64Slice()
+	if err != nil {
+		return types.OrderCacheResult{}, fmt.Errorf("failed to release order with flightId: %s, %w", releaseParam.FlightID, err)
+	}
+	return types.OrderCacheResult{
+		CurrentTotal:     resultList[0],
+		CurrentWait:      resultList[1],
+		CurrentWaitOrder: resultList[2],
+		IsValid:          true,
+		IsWait:           releaseParam.IsWait,
+	}, nil
+}
+
+/*
+*
+ReservePNR: reserve pnr for order_id, return false when pnr already taken
+*/
+func (cache *CacheStore) ReservePNR(ctx context.Context, pnr string, orderID string) (bool, error) {
+	ok, err := cache.rdb.SetNX(ctx, fmt.Sprintf("pnr:%s", pnr), orderID, 0).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve pnr: %s, %w", pnr, err)
+	}
+	return ok, nil
+}
+
+/*
+*
+ReleasePNR: free pnr reserved for order_id when order is not published, pnr reserved by another order is kept
+*/
+func (cache *CacheStore) ReleasePNR(ctx context.Context, pnr string, orderID string) error {
+	err := ReleasePNRWithOrderID.Run(ctx, cache.rdb, []string{fmt.Sprintf("pnr:%s", pnr)}, orderID).Err()
+	if err != nil {
+		return fmt.Errorf("failed to release pnr: %s, %w", pnr, err)
+	}
+	return nil
+}
+
+/*
+*
+AdjustWait: move oversell of flight to wait capacity and shift remaining wait seats by the difference, never below zero,
+difference is computed and applied in one script so concurrent recomputes never apply it twice
+*/
+func (cache *CacheStore) AdjustWait(ctx context.Context, adjustParam types.WaitCacheAdjustParam) (types.WaitCacheAdjustResult, error) {
+	result := AdjustWaitWithFlightID.Run(ctx, cache.rdb, []string{CounterKey(adjustParam.FlightID)},
+		adjustParam.WaitCapacity,
+		adjustParam.CurrentTotal,
+		adjustParam.CurrentWait,
+		adjustParam.CurrentWaitOrder,
+		adjustParam.CurrentWaitCapacity)
+	resultList, err := result.Int64Slice()
+	if err != nil {
+		return types.WaitCacheAdjustResult{}, fmt.Errorf("failed to adjust wait with flightId: %s, %w", adjustParam.FlightID, err)
+	}
+	return types.WaitCacheAdjustResult{
+		OrderCacheResult: types.OrderCacheResult{
+			CurrentTotal:     resultList[0],
+			CurrentWait:      resultList[1],
+			CurrentWaitOrder: resultList[2],
+			IsValid:          true,
+		},
+		WaitCapacity: resultList[3],
+		AppliedDelta: resultList[4],
+	}, nil
+}
+
+// pnrMaxAttempts: max retry when generated pnr collides with existing one
+const pnrMaxAttempts = 5
+
+/*
+*
+ReservePNR: generate unique pnr and reserve it in cache store
+*/
+func ReservePNR(ctx context.Context, orderCacheStore types.OrderCacheStore, orderID uuid.UUID) (string, error) {
+	for attempt := 0; attempt < pnrMaxAttempts; attempt++ {
+		pnr, err := util.GeneratePNR()
+		if err != nil {
+			return "", err
+		}
+		ok, err := orderCacheStore.ReservePNR(ctx, pnr, orderID.String())
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return pnr, nil
+		}
+	}
+	return "", fmt.Errorf("failed to reserve pnr after %d attempts", pnrMaxAttempts)
+}
+
+/*
+*
+ReleasePNR: free pnr of order that could not be published, failures are logged only
+*/
+func ReleasePNR(ctx context.Context, orderCacheStore types.OrderCacheStore, pnr string, orderID uuid.UUID) {
+	if err := orderCacheStore.ReleasePNR(ctx, pnr, orderID.String()); err != nil {
+		log.Printf("failed to release pnr %s of order %s %v", pnr, orderID, err)
+	}
+}
+
 /*
 *
 CreateOrderWithFlightID: luascript for execute counter on specific flight_id
@@ -114,6 +326,52 @@ redis.call("SET", wait_order_key, wait_order)
 return {total, wait, wait_order, is_valid, is_wait}
 `)
 
+/*
+*
+CreateOrderWithFlightIDs: luascript for all-or-nothing confirmed seats on several flights,
+keys are declared so they must share one hash tag on redis cluster
+input keys: total, wait, wait_order key of each flight, arguments: request, then default_total, default_wait, default_wait_order of each flight
+return {is_valid, then current_total, current_wait, current_wait_order of each flight}
+*
+*/
+var CreateOrderWithFlightIDs = redis.NewScript(`
+local request = tonumber(ARGV[1])
+local segments = #KEYS / 3
+local counters = {}
+local is_valid = 1
+if request <= 0 then
+	is_valid = 0
+end
+for i = 1, segments do
+	local counter = {}
+	for j = 1, 3 do
+		local value = redis.call("GET", KEYS[3 * (i - 1) + j])
+		if not value then
+			value = ARGV[1 + 3 * (i - 1) + j]
+		end
+		counter[j] = tonumber(value)
+	end
+	if counter[1] < request then
+		is_valid = 0
+	end
+	counters[i] = counter
+end
+local result = {is_valid}
+for i = 1, segments do
+	local counter = counters[i]
+	if is_valid == 1 then
+		counter[1] = counter[1] - request
+		for j = 1, 3 do
+			redis.call("SET", KEYS[3 * (i - 1) + j], counter[j])
+		end
+	end
+	for j = 1, 3 do
+		table.insert(result, counter[j])
+	end
+end
+return result
+`)
+
 /*
 *
 luascript for execute counter on specific flight_id
@@ -147,3 +405,114 @@ redis.call("SET", wait_order_key, wait_order)
 remain = tonumber(total) + tonumber(wait)
 return remain
 `)
+
+/*
+*
+AdjustWaitWithFlightID: luascript for moving oversell of specific flight_id to target wait capacity
+remaining wait seats shift by target minus current wait capacity, never below zero
+input key: flight_id, arguments: wait_capacity, default_total, default_wait, default_wait_order, default_wait_capacity
+return {current_total, current_wait, current_wait_order, current_wait_capacity, applied_delta}
+*
+*/
+var AdjustWaitWithFlightID = redis.NewScript(`
+local total_key = KEYS[1]..":total"
+local wait_key = KEYS[1]..":wait"
+local wait_order_key = KEYS[1]..":wait_order"
+local wait_capacity_key = KEYS[1]..":wait_capacity"
+local target = tonumber(ARGV[1])
+local default_total = tonumber(ARGV[2])
+local default_wait = tonumber(ARGV[3])
+local default_wait_order = tonumber(ARGV[4])
+local default_wait_capacity = tonumber(ARGV[5])
+local total = redis.call("GET", total_key)
+if not total then
+	total = default_total
+end
+total = tonumber(total)
+local wait = redis.call("GET", wait_key)
+if not wait then
+	wait = default_wait
+end
+wait = tonumber(wait)
+local wait_capacity = redis.call("GET", wait_capacity_key)
+if not wait_capacity then
+	wait_capacity = default_wait_capacity
+end
+wait_capacity = tonumber(wait_capacity)
+local adjusted = wait + target - wait_capacity
+if adjusted < 0 then
+	adjusted = 0
+end
+local applied_delta = adjusted - wait
+wait = adjusted
+wait_capacity = wait_capacity + applied_delta
+local wait_order = redis.call("GET", wait_order_key)
+if not wait_order then
+	wait_order = default_wait_order
+end
+wait_order = tonumber(wait_order)
+redis.call("SET", total_key, total)
+redis.call("SET", wait_key, wait)
+redis.call("SET", wait_order_key, wait_order)
+redis.call("SET", wait_capacity_key, wait_capacity)
+return {total, wait, wait_order, wait_capacity, applied_delta}
+`)
+
+/*
+*
+ReleaseOrderWithFlightID: luascript for giving back reserved seats on specific flight_id
+waitlist position is not reused, wait_order keeps increasing
+input key: flight_id, arguments: request, is_wait, default_total, default_wait, default_wait_order
+return {current_total, current_wait, current_wait_order}
+*
+*/
+var ReleaseOrderWithFlightID = redis.NewScript(`
+local total_key = KEYS[1]..":total"
+local wait_key = KEYS[1]..":wait"
+local wait_order_key = KEYS[1]..":wait_order"
+local request = tonumber(ARGV[1])
+local is_wait = tonumber(ARGV[2])
+local default_total = tonumber(ARGV[3])
+local default_wait = tonumber(ARGV[4])
+local default_wait_order = tonumber(ARGV[5])
+local total = redis.call("GET", total_key)
+if not total then
+	total = default_total
+end
+total = tonumber(total)
+local wait = redis.call("GET", wait_key)
+if not wait then
+	wait = default_wait
+end
+wait = tonumber(wait)
+local wait_order = redis.call("GET", wait_order_key)
+if not wait_order then
+	wait_order = default_wait_order
+end
+wait_order = tonumber(wait_order)
+if request > 0 then
+	if is_wait == 1 then
+		wait = wait + request
+	else
+		total = total + request
+	end
+end
+redis.call("SET", total_key, total)
+redis.call("SET", wait_key, wait)
+redis.call("SET", wait_order_key, wait_order)
+return {total, wait, wait_order}
+`)
+
+/*
+*
+ReleasePNRWithOrderID: luascript for deleting pnr key only when it is still reserved for order_id
+input key: pnr key, arguments: order_id
+return 1 when released, 0 when pnr belongs to another order or is gone
+*
+*/
+var ReleasePNRWithOrderID = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
@@ -0,0 +1,200 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/auth"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/customer"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+var (
+	ErrOrderNotOwned            = apperr.New(apperr.Forbidden, "order belongs to another customer or agency")
+	ErrBookingReferenceRequired = apperr.New(apperr.Unauthorized, "pnr and passenger last name are required")
+	ErrBookingReferenceMismatch = apperr.New(apperr.Forbidden, "pnr or passenger last name does not match order")
+)
+
+// headers proving holder of anonymous order, checked before order is paid or changed and before its tickets are read
+const (
+	PNRHeader               = "X-Booking-Reference"
+	PassengerLastNameHeader = "X-Passenger-Last-Name"
+)
+
+/*
+*
+AuthorizeOrder: orders of customer or agency are only for that customer or agency and admin,
+anonymous orders stay readable to holder of id or pnr, changing them needs AuthorizeBookingReference
+*/
+func AuthorizeOrder(ctx *gin.Context, order types.Order) error {
+	if !order.CustomerID.Valid && !order.AgencyID.Valid {
+		return nil
+	}
+	principal, ok := auth.GetPrincipal(ctx)
+	if !ok {
+		return auth.ErrAuthenticationRequired
+	}
+	if principal.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+	if customerID, ok := customer.CustomerID(ctx); ok && order.CustomerID.Valid && customerID == order.CustomerID.UUID {
+		return nil
+	}
+	if bookingAgency, ok := agency.CurrentAgency(ctx); ok && order.AgencyID.Valid && bookingAgency.ID == order.AgencyID.UUID {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", auth.ErrForbidden, ErrOrderNotOwned)
+}
+
+/*
+*
+AuthorizeBookingReference: anonymous order is only paid or changed by caller sending its pnr
+and last name of one of its passengers, owned orders are left to AuthorizeOrder and admin is always allowed
+*/
+func AuthorizeBookingReference(ctx *gin.Context, orderStore types.OrderStore, order types.Order) error {
+	if order.ID == uuid.Nil || order.CustomerID.Valid || order.AgencyID.Valid {
+		return nil
+	}
+	if principal, ok := auth.GetPrincipal(ctx); ok && principal.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+	pnr := strings.ToUpper(strings.TrimSpace(ctx.GetHeader(PNRHeader)))
+	lastName := strings.TrimSpace(ctx.GetHeader(PassengerLastNameHeader))
+	if pnr == "" || lastName == "" {
+		return ErrBookingReferenceRequired
+	}
+	if !order.PNR.Valid || order.PNR.String != pnr {
+		return ErrBookingReferenceMismatch
+	}
+	passengers, err := orderStore.GetPassengersByOrderID(ctx, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get passengers of order %s %w", order.ID, err)
+	}
+	for _, passenger := range passengers {
+		if strings.EqualFold(passengerLastName(passenger.Name), lastName) {
+			return nil
+		}
+	}
+	return ErrBookingReferenceMismatch
+}
+
+// passengerLastName: last word of passenger name
+func passengerLastName(name string) string {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
+
+// WriteAuthorizeError: 401 for anonymous request or missing booking reference, 403 for other principals
+func WriteAuthorizeError(ctx *gin.Context, err error) {
+	if apperr.CodeOf(err) == "" {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	if errors.Is(err, ErrBookingReferenceRequired) {
+		util.WriteError(ctx.Writer, http.StatusUnauthorized, err)
+		return
+	}
+	if errors.Is(err, auth.ErrAuthenticationRequired) {
+		ctx.Header("WWW-Authenticate", "Bearer")
+		util.WriteError(ctx.Writer, http.StatusUnauthorized, err)
+		return
+	}
+	util.WriteError(ctx.Writer, http.StatusForbidden, err)
+}
+
+/*
+*
+RequireOrderOwner: authorize routes of order :id or :pnr, unknown orders are left to handler,
+anonymous orders are only changed with booking reference
+*/
+func RequireOrderOwner(orderStore types.OrderStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var order types.Order
+		var err error
+		if orderID := ctx.Param("id"); orderID != "" {
+			id, parseErr := uuid.Parse(orderID)
+			if parseErr != nil {
+				ctx.Next()
+				return
+			}
+			order, err = orderStore.GetOrderById(ctx, id)
+		} else if pnr := ctx.Param("pnr"); pnr != "" {
+			order, err = orderStore.GetOrderByPNR(ctx, strings.ToUpper(pnr))
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.Next()
+				return
+			}
+		} else {
+			ctx.Next()
+			return
+		}
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get order %w", err))
+			ctx.Abort()
+			return
+		}
+		if err := AuthorizeOrder(ctx, order); err != nil {
+			WriteAuthorizeError(ctx, err)
+			ctx.Abort()
+			return
+		}
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if err := AuthorizeBookingReference(ctx, orderStore, order); err != nil {
+				WriteAuthorizeError(ctx, err)
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+/*
+*
+RequireTicketHolder: authorize routes of ticket :number like its order, ticket numbers are sequential
+so tickets of anonymous orders are only read with booking reference, unknown tickets are left to handler
+*/
+func RequireTicketHolder(orderStore types.OrderStore, ticketStore types.TicketStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ticket, err := ticketStore.GetTicketByNumber(ctx, ctx.Param("number"))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.Next()
+				return
+			}
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get ticket %w", err))
+			ctx.Abort()
+			return
+		}
+		order, err := orderStore.GetOrderById(ctx, ticket.OrderID)
+		if err != nil {
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get order %w", err))
+			ctx.Abort()
+			return
+		}
+		if err := AuthorizeOrder(ctx, order); err != nil {
+			WriteAuthorizeError(ctx, err)
+			ctx.Abort()
+			return
+		}
+		if err := AuthorizeBookingReference(ctx, orderStore, order); err != nil {
+			WriteAuthorizeError(ctx, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package order
+
+import (
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+// orderCursor: position of last order on page, orders are listed newest first
+type orderCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+}
+
+func encodeOrderCursor(order types.Order) (string, error) {
+	return util.EncodeCursor(orderCursor{CreatedAt: order.CreatedAt, ID: order.ID})
+}
+
+/*
+*
+decodeOrderCursor: position of cursor issued by order listing, util.ErrInvalidCursor when malformed
+*/
+func decodeOrderCursor(token string) (orderCursor, error) {
+	var cursor orderCursor
+	if err := util.DecodeCursor(token, &cursor); err != nil {
+		return orderCursor{}, err
+	}
+	if cursor.ID == uuid.Nil || cursor.CreatedAt.IsZero() {
+		return orderCursor{}, fmt.Errorf("%w: missing position", util.ErrInvalidCursor)
+	}
+	return cursor, nil
+}
+
+// orderStatusCondition: where condition matching types.Order Status
+func orderStatusCondition(status string) sq.Sqlizer {
+	switch status {
+	case types.OrderStatusCanceled:
+		return sq.NotEq{"canceled_at": nil}
+	case types.OrderStatusPaid:
+		return sq.And{sq.Eq{"canceled_at": nil}, sq.NotEq{"paid_at": nil}}
+	case types.OrderStatusWaitlisted:
+		return sq.And{sq.Eq{"canceled_at": nil}, sq.Eq{"paid_at": nil}, sq.GtOrEq{"wait_order": 0}}
+	default:
+		return sq.And{sq.Eq{"canceled_at": nil}, sq.Eq{"paid_at": nil}, sq.Lt{"wait_order": 0}}
+	}
+}
@@ -1,10 +1,13 @@
 package order
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
 	bloomfilter "github.com/alovn/go-bloomfilter"
 	"github.com/gin-gonic/gin"
@@ -12,6 +15,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/yuanyu90221/airline-order-system/internal/broker"
 	"github.com/yuanyu90221/airline-order-system/internal/config"
+	"github.com/yuanyu90221/airline-order-system/internal/service/abuse"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/customer"
+	"github.com/yuanyu90221/airline-order-system/internal/service/waitingroom"
 	"github.com/yuanyu90221/airline-order-system/internal/types"
 	"github.com/yuanyu90221/airline-order-system/internal/util"
 )
@@ -22,22 +29,36 @@ type Handler struct {
 	bFilter          bloomfilter.BloomFilter
 	mq               *broker.Broker
 	orderStore       types.OrderStore
+	orderService     types.OrderServcie
+	agencyCacheStore types.AgencyCacheStore
+	// flash sale flights only take orders of admitted waiting room tokens
+	waitingRoomCacheStore types.WaitingRoomCacheStore
+	// nil when abuse screening is disabled
+	abuseService types.AbuseService
 }
 
 func NewHandler(orderCacheStore types.OrderCacheStore, flightCacheStore types.FlightCacheStore,
-	bFilter bloomfilter.BloomFilter, mq *broker.Broker, orderStore types.OrderStore) *Handler {
+	bFilter bloomfilter.BloomFilter, mq *broker.Broker, orderStore types.OrderStore,
+	orderService types.OrderServcie, agencyCacheStore types.AgencyCacheStore,
+	waitingRoomCacheStore types.WaitingRoomCacheStore, abuseService types.AbuseService) *Handler {
 	return &Handler{
-		orderCacheStore:  orderCacheStore,
-		flightCacheStore: flightCacheStore,
-		bFilter:          bFilter,
-		mq:               mq,
-		orderStore:       orderStore,
+		orderCacheStore:       orderCacheStore,
+		flightCacheStore:      flightCacheStore,
+		bFilter:               bFilter,
+		mq:                    mq,
+		orderStore:            orderStore,
+		orderService:          orderService,
+		agencyCacheStore:      agencyCacheStore,
+		waitingRoomCacheStore: waitingRoomCacheStore,
+		abuseService:          abuseService,
 	}
 }
 
 func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
 	router.POST("/", h.CreateOrder)
 	router.GET("/:id", h.GetOrderById)
+	router.GET("/by-pnr/:pnr", h.GetOrderByPNR)
+	router.POST("/:id/pay", h.PayOrder)
 }
 
 func (h *Handler) CreateOrder(ctx *gin.Context) {
@@ -51,10 +72,44 @@ func (h *Handler) CreateOrder(ctx *gin.Context) {
 	if err := util.Validdate.Struct(requestOrder); err != nil {
 		var valErrs validator.ValidationErrors
 		if errors.As(err, &valErrs) {
-			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%v", valErrs))
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
 		}
 		return
 	}
+	if int64(len(requestOrder.Passengers)) != requestOrder.TicketNumbers {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload: passengers length %d not equal to ticket_numbers %d",
+			len(requestOrder.Passengers), requestOrder.TicketNumbers))
+		return
+	}
+	if customerID, ok := customer.CustomerID(ctx); ok {
+		requestOrder.CustomerID = customerID.String()
+	}
+	if bookingAgency, ok := agency.CurrentAgency(ctx); ok {
+		requestOrder.AgencyID = bookingAgency.ID.String()
+	}
+	flightIDs := []string{requestOrder.FlightID}
+	if len(requestOrder.Segments) > 0 {
+		flightIDs = flightIDs[:0]
+		for _, segment := range requestOrder.Segments {
+			flightIDs = append(flightIDs, segment.FlightID)
+		}
+	}
+	// tokens are checked here and only used once seats are held
+	admissions, err := waitingroom.RequireAdmission(ctx, h.waitingRoomCacheStore, flightIDs,
+		ctx.Request.Header.Values(waitingroom.TokenHeader))
+	if err != nil {
+		WriteAdmissionError(ctx, err)
+		return
+	}
+	evaluation, err := abuse.Screen(ctx, h.abuseService, flightIDs, requestOrder.Passengers)
+	if err != nil {
+		WriteAbuseError(ctx, evaluation, err)
+		return
+	}
+	if len(requestOrder.Segments) > 0 {
+		h.createSegmentedOrder(ctx, requestOrder, evaluation, admissions)
+		return
+	}
 	// log.Println("requestOrder", requestOrder)
 	// use bloomfilter to check flightID exists
 	binaryFlightID, status, err := util.ParseFlightIDIntoBinary(requestOrder.FlightID)
@@ -76,6 +131,12 @@ func (h *Handler) CreateOrder(ctx *gin.Context) {
 		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("FlightID %s not in flight cache %w", requestOrder.FlightID, err))
 		return
 	}
+	// seats booked with api key count against allotment of agency
+	allotment, err := agency.ReserveAllotment(ctx, h.agencyCacheStore, []types.Flight{flightInfo}, requestOrder.TicketNumbers)
+	if err != nil {
+		writeAllotmentError(ctx, err)
+		return
+	}
 	cacheRequest := types.OrderCacheParam{
 		FlightID:         requestOrder.FlightID,
 		CurrentTotal:     int64(flightInfo.AvailableSeats),
@@ -88,15 +149,33 @@ func (h *Handler) CreateOrder(ctx *gin.Context) {
 		TicketNumbers:   requestOrder.TicketNumbers,
 	})
 	if err != nil {
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
 		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("could not create order in cachestore: %w", err))
 		return
 	}
 	if !result.IsValid {
-		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf(`seats insufficient, could not create order with request ticket numbers: %d , with available seats %d, wait seats %d `, requestOrder.TicketNumbers, result.CurrentTotal, result.CurrentWait))
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		util.WriteError(ctx.Writer, http.StatusConflict, fmt.Errorf(`%w, could not create order with request ticket numbers: %d , with available seats %d, wait seats %d `, ErrSeatsInsufficient, requestOrder.TicketNumbers, result.CurrentTotal, result.CurrentWait))
 		return
 	}
 	// generate order id
 	id := uuid.New()
+	// reserve pnr only for order holding seats, so rejected orders do not consume pnr
+	pnr, err := ReservePNR(ctx, h.orderCacheStore, id)
+	if err != nil {
+		h.releaseOrderSeats(ctx, cacheRequest, requestOrder.TicketNumbers, result.IsWait)
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	consumed, err := waitingroom.ConsumeAdmission(ctx, h.waitingRoomCacheStore, admissions)
+	if err != nil {
+		h.releaseOrderSeats(ctx, cacheRequest, requestOrder.TicketNumbers, result.IsWait)
+		ReleasePNR(ctx, h.orderCacheStore, pnr, id)
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		WriteAdmissionError(ctx, err)
+		return
+	}
 	// update result to rabbitmq
 	requestEvent := types.CreateOrderEvent{
 		ID:             id.String(),
@@ -106,6 +185,11 @@ func (h *Handler) CreateOrder(ctx *gin.Context) {
 		WaitOrder:      result.CurrentWaitOrder,
 		WaitSeats:      result.CurrentWait,
 		IsWait:         result.IsWait,
+		PNR:            pnr,
+		Passengers:     requestOrder.Passengers,
+		CustomerID:     requestOrder.CustomerID,
+		AgencyID:       requestOrder.AgencyID,
+		Fare:           flightInfo.Price,
 	}
 	if !requestEvent.IsWait {
 		requestEvent.WaitOrder = -1
@@ -113,18 +197,164 @@ func (h *Handler) CreateOrder(ctx *gin.Context) {
 
 	data, err := json.Marshal(requestEvent)
 	if err != nil {
+		h.releaseOrderSeats(ctx, cacheRequest, requestOrder.TicketNumbers, result.IsWait)
+		ReleasePNR(ctx, h.orderCacheStore, pnr, id)
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		waitingroom.RestoreAdmission(ctx, h.waitingRoomCacheStore, consumed)
 		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("marshal data error %w", err))
 		return
 	}
 	err = h.mq.SendMessageToQueue(ctx, config.AppConfig.OrderQueueName, data)
 	if err != nil {
+		h.releaseOrderSeats(ctx, cacheRequest, requestOrder.TicketNumbers, result.IsWait)
+		ReleasePNR(ctx, h.orderCacheStore, pnr, id)
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		waitingroom.RestoreAdmission(ctx, h.waitingRoomCacheStore, consumed)
 		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("send rabbitmq error %w", err))
 		return
 	}
+	abuse.RecordOrders(ctx, h.abuseService, evaluation, []string{requestEvent.ID})
 	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated,
 		types.ConvertCreateOrderEventToResponse(requestEvent)), "failed to write result")
 }
 
+// releaseOrderSeats: give back seats or wait seats taken by CreateOrder when order is not published
+func (h *Handler) releaseOrderSeats(ctx *gin.Context, cacheRequest types.OrderCacheParam, ticketNumbers int64, isWait bool) {
+	_, err := h.orderCacheStore.ReleaseOrder(ctx, types.OrderCacheReleaseParam{
+		OrderCacheParam: cacheRequest,
+		TicketNumbers:   ticketNumbers,
+		IsWait:          isWait,
+	})
+	if err != nil {
+		log.Printf("failed to release %d seats on flight %s %v", ticketNumbers, cacheRequest.FlightID, err)
+	}
+}
+
+/*
+*
+createSegmentedOrder: round-trip or multi-city order, confirmed seats on all segments or none
+*/
+func (h *Handler) createSegmentedOrder(ctx *gin.Context, requestOrder types.CreateOrderRequest, evaluation types.AbuseEvaluation,
+	admissions []types.WaitingRoomAdmission) {
+	flightIDs := make([]string, 0, len(requestOrder.Segments))
+	for _, segment := range requestOrder.Segments {
+		flightIDs = append(flightIDs, segment.FlightID)
+	}
+	flights, err := LoadSegmentFlights(ctx, h.bFilter, h.flightCacheStore, flightIDs)
+	if err != nil {
+		if errors.Is(err, ErrFlightNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	if err := ValidateSegments(flights); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	allotment, err := agency.ReserveAllotment(ctx, h.agencyCacheStore, flights, requestOrder.TicketNumbers)
+	if err != nil {
+		writeAllotmentError(ctx, err)
+		return
+	}
+	segments, result, err := ReserveSegments(ctx, h.orderCacheStore, flights, requestOrder.TicketNumbers)
+	if err != nil {
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		if errors.Is(err, ErrSegmentsUnavailable) {
+			util.WriteError(ctx.Writer, http.StatusConflict, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	id := uuid.New()
+	pnr, err := ReservePNR(ctx, h.orderCacheStore, id)
+	if err != nil {
+		ReleaseSegments(ctx, h.orderCacheStore, segments, requestOrder.TicketNumbers)
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	consumed, err := waitingroom.ConsumeAdmission(ctx, h.waitingRoomCacheStore, admissions)
+	if err != nil {
+		ReleaseSegments(ctx, h.orderCacheStore, segments, requestOrder.TicketNumbers)
+		ReleasePNR(ctx, h.orderCacheStore, pnr, id)
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		WriteAdmissionError(ctx, err)
+		return
+	}
+	requestEvent := types.CreateOrderEvent{
+		ID:             id.String(),
+		FlightID:       segments[0].FlightID,
+		TicketNumbers:  requestOrder.TicketNumbers,
+		AvailableSeats: result.Segments[0].CurrentTotal,
+		WaitOrder:      -1,
+		WaitSeats:      result.Segments[0].CurrentWait,
+		PNR:            pnr,
+		Passengers:     requestOrder.Passengers,
+		Segments:       make([]types.OrderSegmentEvent, 0, len(segments)),
+		CustomerID:     requestOrder.CustomerID,
+		AgencyID:       requestOrder.AgencyID,
+	}
+	for _, flight := range flights {
+		requestEvent.Fare += flight.Price
+	}
+	for index, segment := range segments {
+		requestEvent.Segments = append(requestEvent.Segments, types.OrderSegmentEvent{
+			FlightID:       segment.FlightID,
+			AvailableSeats: result.Segments[index].CurrentTotal,
+			WaitSeats:      result.Segments[index].CurrentWait,
+			WaitOrder:      result.Segments[index].CurrentWaitOrder,
+		})
+	}
+	if err := PublishCreateOrderEvent(ctx, h.mq, requestEvent); err != nil {
+		ReleaseSegments(ctx, h.orderCacheStore, segments, requestOrder.TicketNumbers)
+		ReleasePNR(ctx, h.orderCacheStore, pnr, id)
+		agency.ReleaseAllotment(ctx, h.agencyCacheStore, allotment)
+		waitingroom.RestoreAdmission(ctx, h.waitingRoomCacheStore, consumed)
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	abuse.RecordOrders(ctx, h.abuseService, evaluation, []string{requestEvent.ID})
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated,
+		types.ConvertCreateOrderEventToResponse(requestEvent)), "failed to write result")
+}
+
+// WriteAdmissionError: 403 when flight in waiting room is ordered without admitted token
+func WriteAdmissionError(ctx *gin.Context, err error) {
+	if errors.Is(err, waitingroom.ErrAdmissionRequired) {
+		util.WriteError(ctx.Writer, http.StatusForbidden, err)
+		return
+	}
+	util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+}
+
+/*
+*
+WriteAbuseError: 403 for blocked order request, 428 with challenge in abuse.ChallengeHeader for challenged one
+*/
+func WriteAbuseError(ctx *gin.Context, evaluation types.AbuseEvaluation, err error) {
+	switch {
+	case errors.Is(err, abuse.ErrOrderBlocked):
+		util.WriteError(ctx.Writer, http.StatusForbidden, err)
+	case errors.Is(err, abuse.ErrChallengeRequired):
+		ctx.Header(abuse.ChallengeHeader, evaluation.Challenge)
+		util.WriteError(ctx.Writer, http.StatusPreconditionRequired, err)
+	default:
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+	}
+}
+
+// writeAllotmentError: 409 when agency used up its seat allotment of flight
+func writeAllotmentError(ctx *gin.Context, err error) {
+	if errors.Is(err, agency.ErrAllotmentExceeded) {
+		util.WriteError(ctx.Writer, http.StatusConflict, err)
+		return
+	}
+	util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+}
+
 func (h *Handler) GetOrderById(ctx *gin.Context) {
 	orderID := ctx.Param("id")
 	if orderID == "" {
@@ -141,5 +371,73 @@ func (h *Handler) GetOrderById(ctx *gin.Context) {
 		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get order %w", err))
 		return
 	}
-	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertOrderEntityToResponse(result)), "failed to response json")
+	if result.ID == uuid.Nil {
+		util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("order %s %w", id, ErrOrderNotFound))
+		return
+	}
+	passengers, err := h.orderStore.GetPassengersByOrderID(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get passengers %w", err))
+		return
+	}
+	segments, err := h.orderStore.GetOrderSegments(ctx, result.ID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get segments %w", err))
+		return
+	}
+	response := types.ConvertOrderEntityToResponse(result, passengers)
+	response.Segments = types.ConvertOrderSegmentsToResponse(segments)
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, response), "failed to response json")
+}
+
+func (h *Handler) GetOrderByPNR(ctx *gin.Context) {
+	pnr := strings.ToUpper(ctx.Param("pnr"))
+	if len(pnr) != util.PNRLength {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("pnr %s must be %d characters", pnr, util.PNRLength))
+		return
+	}
+	result, err := h.orderStore.GetOrderByPNR(ctx, pnr)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("order with pnr %s not found", pnr))
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get order %w", err))
+		return
+	}
+	passengers, err := h.orderStore.GetPassengersByOrderID(ctx, result.ID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get passengers %w", err))
+		return
+	}
+	segments, err := h.orderStore.GetOrderSegments(ctx, result.ID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get segments %w", err))
+		return
+	}
+	response := types.ConvertOrderEntityToResponse(result, passengers)
+	response.Segments = types.ConvertOrderSegmentsToResponse(segments)
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, response), "failed to response json")
+}
+
+func (h *Handler) PayOrder(ctx *gin.Context) {
+	orderID := ctx.Param("id")
+	id, err := uuid.Parse(orderID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", orderID, err))
+		return
+	}
+	result, passengers, err := h.orderService.PayOrderHandler(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOrderNotFound):
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+		case errors.Is(err, ErrOrderNotPayable):
+			util.WriteError(ctx.Writer, http.StatusConflict, err)
+		default:
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to pay order %w", err))
+		}
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertOrderEntityToResponse(result, passengers)), "failed to response json")
 }
@@ -0,0 +1,129 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	bloomfilter "github.com/alovn/go-bloomfilter"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/broker"
+	"github.com/yuanyu90221/airline-order-system/internal/config"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+var (
+	ErrFlightNotFound      = apperr.New(apperr.NotFound, "flight not found")
+	ErrSegmentsOutOfOrder  = apperr.New(apperr.BadRequest, "segments not in travel order")
+	ErrSegmentsUnavailable = apperr.New(apperr.InsufficientSeats, "seats insufficient on segment")
+	ErrSeatsInsufficient   = apperr.New(apperr.InsufficientSeats, "seats insufficient")
+)
+
+/*
+*
+LoadSegmentFlights: flight info of every segment from flight cache, flights not in bloomfilter are not found
+*/
+func LoadSegmentFlights(ctx context.Context, bFilter bloomfilter.BloomFilter, flightCacheStore types.FlightCacheStore,
+	flightIDs []string) ([]types.Flight, error) {
+	flights := make([]types.Flight, 0, len(flightIDs))
+	for _, flightID := range flightIDs {
+		binaryFlightID, _, err := util.ParseFlightIDIntoBinary(flightID)
+		if err != nil {
+			return nil, err
+		}
+		isExist, err := bFilter.MightContain(binaryFlightID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check FlightID in bloomfilter %w", err)
+		}
+		if !isExist {
+			return nil, fmt.Errorf("flight %s %w", flightID, ErrFlightNotFound)
+		}
+		flightInfo, err := flightCacheStore.GetFlightCacheInfo(ctx, flightID)
+		if err != nil {
+			return nil, fmt.Errorf("flight %s not in flight cache %v %w", flightID, err, ErrFlightNotFound)
+		}
+		flights = append(flights, flightInfo)
+	}
+	return flights, nil
+}
+
+/*
+*
+ValidateSegments: first segment departs in the future, every segment departs after previous arrival,
+previous departure when arrival is unknown
+*/
+func ValidateSegments(flights []types.Flight) error {
+	if len(flights) > 0 && !flights[0].FlightDate.After(time.Now().UTC()) {
+		return fmt.Errorf("flight %s already departed %w", flights[0].ID, ErrSegmentsOutOfOrder)
+	}
+	for index := 1; index < len(flights); index++ {
+		previous, next := flights[index-1], flights[index]
+		previousEnd := previous.FlightDate
+		if previous.ArrivalDate.Valid {
+			previousEnd = previous.ArrivalDate.Time
+		}
+		if !next.FlightDate.After(previousEnd) {
+			return fmt.Errorf("flight %s departs before flight %s arrives %w", next.ID, previous.ID, ErrSegmentsOutOfOrder)
+		}
+	}
+	return nil
+}
+
+/*
+*
+ReserveSegments: reserve confirmed seats on every flight in one cache script
+*/
+func ReserveSegments(ctx context.Context, orderCacheStore types.OrderCacheStore, flights []types.Flight,
+	ticketNumbers int64) ([]types.OrderCacheParam, types.OrderCacheSegmentsResult, error) {
+	segments := make([]types.OrderCacheParam, 0, len(flights))
+	for _, flightInfo := range flights {
+		segments = append(segments, types.OrderCacheParam{
+			FlightID:         flightInfo.ID.String(),
+			CurrentTotal:     int64(flightInfo.AvailableSeats),
+			CurrentWait:      int64(flightInfo.WaitSeats),
+			CurrentWaitOrder: int64(flightInfo.NextWaitOrder),
+		})
+	}
+	result, err := orderCacheStore.CreateSegmentedOrder(ctx, types.OrderCacheSegmentsCreateParam{
+		Segments:      segments,
+		TicketNumbers: ticketNumbers,
+	})
+	if err != nil {
+		return nil, types.OrderCacheSegmentsResult{}, fmt.Errorf("could not create order in cachestore: %w", err)
+	}
+	if !result.IsValid {
+		return nil, result, fmt.Errorf("could not reserve %d seats on every segment %w", ticketNumbers, ErrSegmentsUnavailable)
+	}
+	return segments, result, nil
+}
+
+/*
+*
+ReleaseSegments: give back confirmed seats of segments when order could not be published, failures are logged only
+*/
+func ReleaseSegments(ctx context.Context, orderCacheStore types.OrderCacheStore, segments []types.OrderCacheParam, ticketNumbers int64) {
+	for _, segment := range segments {
+		_, err := orderCacheStore.ReleaseOrder(ctx, types.OrderCacheReleaseParam{
+			OrderCacheParam: segment,
+			TicketNumbers:   ticketNumbers,
+		})
+		if err != nil {
+			log.Printf("failed to release %d seats on flight %s %v", ticketNumbers, segment.FlightID, err)
+		}
+	}
+}
+
+// PublishCreateOrderEvent: send event to order queue for order worker
+func PublishCreateOrderEvent(ctx context.Context, mq *broker.Broker, requestEvent types.CreateOrderEvent) error {
+	data, err := json.Marshal(requestEvent)
+	if err != nil {
+		return fmt.Errorf("marshal data error %w", err)
+	}
+	if err := mq.SendMessageToQueue(ctx, config.AppConfig.OrderQueueName, data); err != nil {
+		return fmt.Errorf("send rabbitmq error %w", err)
+	}
+	return nil
+}
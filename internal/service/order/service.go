@@ -3,30 +3,42 @@ package order
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
 	"github.com/yuanyu90221/airline-order-system/internal/types"
 )
 
+var (
+	ErrOrderNotFound   = apperr.New(apperr.NotFound, "order not found")
+	ErrOrderNotPayable = apperr.New(apperr.Conflict, "order is not payable")
+)
+
 // handle create order
 type OrderService struct {
-	db          *sql.DB
-	orderStore  types.OrderStore
-	flightStore types.FlightStore
+	db            *sql.DB
+	orderStore    types.OrderStore
+	flightStore   types.FlightStore
+	ticketService types.TicketService
 }
 
-func NewOrderService(db *sql.DB, orderStore types.OrderStore, flightStore types.FlightStore) *OrderService {
+func NewOrderService(db *sql.DB, orderStore types.OrderStore, flightStore types.FlightStore,
+	ticketService types.TicketService) *OrderService {
 	return &OrderService{
-		db:          db,
-		orderStore:  orderStore,
-		flightStore: flightStore,
+		db:            db,
+		orderStore:    orderStore,
+		flightStore:   flightStore,
+		ticketService: ticketService,
 	}
 }
 
 func (orderService *OrderService) CreateOrderHandler(ctx context.Context,
 	createOrderParams types.CreateOrderEntityParam,
 	updateFlightParams types.UpdateFlightEntityParam,
+	createPassengerParams []types.CreatePassengerEntityParam,
 ) (types.Flight, types.Order, error) {
 	// create db transaction
 	tx, err := orderService.db.BeginTx(ctx, nil)
@@ -43,6 +55,15 @@ func (orderService *OrderService) CreateOrderHandler(ctx context.Context,
 		}
 		return types.Flight{}, types.Order{}, err
 	}
+	_, err = orderService.orderStore.CreatePassengers(tx, ctx, createPassengerParams)
+	if err != nil {
+		log.Printf("failed to create passengers %v", err)
+		err = tx.Rollback()
+		if err != nil {
+			return types.Flight{}, types.Order{}, fmt.Errorf("tx roolback failed %w", err)
+		}
+		return types.Flight{}, types.Order{}, err
+	}
 	flight, err := orderService.flightStore.UpdateFlight(tx, ctx, updateFlightParams)
 	if err != nil {
 		log.Printf("failed to create order %v", err)
@@ -57,3 +78,160 @@ func (orderService *OrderService) CreateOrderHandler(ctx context.Context,
 	}
 	return flight, order, nil
 }
+
+/*
+*
+CreateSegmentedOrderHandler: create order with segments and passengers, update counters of every segment flight in the same transaction
+*/
+func (orderService *OrderService) CreateSegmentedOrderHandler(ctx context.Context,
+	createOrderParams types.CreateOrderEntityParam,
+	updateFlightParams []types.UpdateFlightEntityParam,
+	createPassengerParams []types.CreatePassengerEntityParam,
+) ([]types.Flight, types.Order, error) {
+	tx, err := orderService.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, types.Order{}, fmt.Errorf("create db tx failed %w", err)
+	}
+	order, err := orderService.orderStore.CreateOrder(tx, ctx, createOrderParams)
+	if err != nil {
+		log.Printf("failed to create order %v", err)
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return nil, types.Order{}, fmt.Errorf("tx roolback failed %w", rollbackErr)
+		}
+		return nil, types.Order{}, err
+	}
+	flightIDs := make([]uuid.UUID, 0, len(updateFlightParams))
+	for _, updateFlightParam := range updateFlightParams {
+		flightIDs = append(flightIDs, updateFlightParam.ID)
+	}
+	_, err = orderService.orderStore.CreateOrderSegments(tx, ctx, order.ID, flightIDs)
+	if err != nil {
+		log.Printf("failed to create order segments %v", err)
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return nil, types.Order{}, fmt.Errorf("tx roolback failed %w", rollbackErr)
+		}
+		return nil, types.Order{}, err
+	}
+	_, err = orderService.orderStore.CreatePassengers(tx, ctx, createPassengerParams)
+	if err != nil {
+		log.Printf("failed to create passengers %v", err)
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return nil, types.Order{}, fmt.Errorf("tx roolback failed %w", rollbackErr)
+		}
+		return nil, types.Order{}, err
+	}
+	flights := make([]types.Flight, 0, len(updateFlightParams))
+	for _, updateFlightParam := range updateFlightParams {
+		flight, err := orderService.flightStore.UpdateFlight(tx, ctx, updateFlightParam)
+		if err != nil {
+			log.Printf("failed to update segment flight %v", err)
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return nil, types.Order{}, fmt.Errorf("tx roolback failed %w", rollbackErr)
+			}
+			return nil, types.Order{}, err
+		}
+		flights = append(flights, flight)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, types.Order{}, err
+	}
+	return flights, order, nil
+}
+
+/*
+*
+CreateItineraryOrdersHandler: create linked order and passengers of every itinerary leg and update counters of leg flights
+in the same transaction, so itinerary is stored with all legs or none
+*/
+func (orderService *OrderService) CreateItineraryOrdersHandler(ctx context.Context,
+	legs []types.ItineraryLegEntityParam,
+) ([]types.Flight, []types.Order, error) {
+	tx, err := orderService.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create db tx failed %w", err)
+	}
+	flights := make([]types.Flight, 0, len(legs))
+	orders := make([]types.Order, 0, len(legs))
+	for _, leg := range legs {
+		order, err := orderService.orderStore.CreateOrder(tx, ctx, leg.CreateOrder)
+		if err != nil {
+			log.Printf("failed to create itinerary leg order %v", err)
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return nil, nil, fmt.Errorf("tx roolback failed %w", rollbackErr)
+			}
+			return nil, nil, err
+		}
+		if _, err := orderService.orderStore.CreatePassengers(tx, ctx, leg.Passengers); err != nil {
+			log.Printf("failed to create passengers %v", err)
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return nil, nil, fmt.Errorf("tx roolback failed %w", rollbackErr)
+			}
+			return nil, nil, err
+		}
+		flight, err := orderService.flightStore.UpdateFlight(tx, ctx, leg.UpdateFlight)
+		if err != nil {
+			log.Printf("failed to update leg flight %v", err)
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return nil, nil, fmt.Errorf("tx roolback failed %w", rollbackErr)
+			}
+			return nil, nil, err
+		}
+		flights = append(flights, flight)
+		orders = append(orders, order)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return flights, orders, nil
+}
+
+/*
+*
+PayOrderHandler: mark order paid and issue e-tickets in the same transaction
+*/
+func (orderService *OrderService) PayOrderHandler(ctx context.Context, orderID uuid.UUID) (types.Order, []types.Passenger, error) {
+	current, err := orderService.orderStore.GetOrderById(ctx, orderID)
+	if err != nil {
+		return types.Order{}, nil, err
+	}
+	if current.ID == uuid.Nil {
+		return types.Order{}, nil, fmt.Errorf("order %s %w", orderID, ErrOrderNotFound)
+	}
+	if current.PaidAt.Valid || current.CanceledAt.Valid {
+		return types.Order{}, nil, fmt.Errorf("order %s already paid or canceled %w", orderID, ErrOrderNotPayable)
+	}
+	if current.WaitOrder >= 0 {
+		return types.Order{}, nil, fmt.Errorf("order %s is waitlisted %w", orderID, ErrOrderNotPayable)
+	}
+	passengers, err := orderService.orderStore.GetPassengersByOrderID(ctx, orderID)
+	if err != nil {
+		return types.Order{}, nil, err
+	}
+	tx, err := orderService.db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.Order{}, nil, fmt.Errorf("create db tx failed %w", err)
+	}
+	order, err := orderService.orderStore.PayOrder(tx, ctx, orderID)
+	if err != nil {
+		log.Printf("failed to pay order %v", err)
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return types.Order{}, nil, fmt.Errorf("tx roolback failed %w", rollbackErr)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Order{}, nil, fmt.Errorf("order %s %w", orderID, ErrOrderNotPayable)
+		}
+		return types.Order{}, nil, err
+	}
+	passengers, err = orderService.ticketService.IssueTickets(tx, ctx, order, passengers)
+	if err != nil {
+		log.Printf("failed to issue tickets %v", err)
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return types.Order{}, nil, fmt.Errorf("tx roolback failed %w", rollbackErr)
+		}
+		return types.Order{}, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return types.Order{}, nil, err
+	}
+	return order, passengers, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
@@ -20,66 +21,431 @@ func NewOrderStore(db *sql.DB) *OrderStore {
 	return &OrderStore{db: db}
 }
 func (orderStore *OrderStore) CreateOrder(tx *sql.Tx, ctx context.Context, createOrderParam types.CreateOrderEntityParam) (types.Order, error) {
-	queryBuilder := sq.Insert("orders").Columns("id", "flight_id", "wait_order", "ticket_numbers").Values(createOrderParam.ID,
-		createOrderParam.FlightID, createOrderParam.WaitOrder, createOrderParam.TicketNumbers).Suffix("RETURNING *;").PlaceholderFormat(sq.Dollar)
+	queryBuilder := sq.Insert("orders").Columns("id", "flight_id", "wait_order", "ticket_numbers", "pnr", "itinerary_id", "leg_sequence", "customer_id", "agency_id", "fare").Values(createOrderParam.ID,
+		createOrderParam.FlightID, createOrderParam.WaitOrder, createOrderParam.TicketNumbers,
+		sql.NullString{String: createOrderParam.PNR, Valid: createOrderParam.PNR != ""},
+		createOrderParam.ItineraryID, createOrderParam.LegSequence, createOrderParam.CustomerID, createOrderParam.AgencyID, createOrderParam.Fare).
+		Suffix("RETURNING " + orderColumns + ";").PlaceholderFormat(sq.Dollar)
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
 		log.Println(err)
 		return types.Order{}, fmt.Errorf("create order query builder failed %w", err)
 	}
-	rows, err := tx.QueryContext(ctx, query, args...)
+	resultOrder, err := scanOrder(tx.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		log.Println(err)
 		return types.Order{}, fmt.Errorf("insert order failed %w", err)
 	}
+	return resultOrder, nil
+}
+
+func (orderStore *OrderStore) GetOrderById(ctx context.Context, orderID uuid.UUID) (types.Order, error) {
+	queryBuilder := sq.Select(orderColumns).From("orders").Where(sq.Eq{"id": orderID}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Order{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := orderStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return types.Order{}, fmt.Errorf("failed to query order %w", err)
+	}
+	defer rows.Close()
 	var resultOrder types.Order
 	for rows.Next() {
-		err := rows.Scan(
-			&resultOrder.ID,
-			&resultOrder.FlightID,
-			&resultOrder.PaidAt,
-			&resultOrder.CanceledAt,
-			&resultOrder.CreatedAt,
-			&resultOrder.WaitOrder,
-			&resultOrder.TicketNumbers,
-		)
+		resultOrder, err = scanOrder(rows)
 		if err != nil {
-			log.Println(err)
 			return types.Order{}, fmt.Errorf("scan order failed %w", err)
 		}
 	}
 	return resultOrder, nil
 }
 
-func (orderStore *OrderStore) GetOrderById(ctx context.Context, orderID uuid.UUID) (types.Order, error) {
-	queryBuilder := sq.Select("id",
-		"flight_id", "paid_at", "canceled_at",
-		"created_at", "wait_order", "ticket_numbers").From("orders").Where(sq.Eq{"id": orderID}).PlaceholderFormat(sq.Dollar)
+func (orderStore *OrderStore) GetOrderByPNR(ctx context.Context, pnr string) (types.Order, error) {
+	queryBuilder := sq.Select(orderColumns).From("orders").Where(sq.Eq{"pnr": pnr}).PlaceholderFormat(sq.Dollar)
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
 		return types.Order{}, fmt.Errorf("failed to create query string %w", err)
 	}
-	rows, err := orderStore.db.QueryContext(ctx, query, args...)
+	resultOrder, err := scanOrder(orderStore.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return types.Order{}, fmt.Errorf("no order with id %s %w", orderID.String(), err)
+			return types.Order{}, fmt.Errorf("no order with pnr %s %w", pnr, err)
 		}
 		return types.Order{}, fmt.Errorf("failed to query order %w", err)
 	}
-	var resultOrder types.Order
+	return resultOrder, nil
+}
+
+/*
+*
+GetOrdersByItineraryID: legs of connecting itinerary ordered by leg_sequence
+*/
+func (orderStore *OrderStore) GetOrdersByItineraryID(ctx context.Context, itineraryID uuid.UUID) ([]types.Order, error) {
+	queryBuilder := sq.Select(orderColumns).From("orders").Where(sq.Eq{"itinerary_id": itineraryID}).
+		OrderBy("leg_sequence ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := orderStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders %w", err)
+	}
+	defer rows.Close()
+	orders := []types.Order{}
 	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan order failed %w", err)
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+/*
+*
+GetOrdersByCustomerID: orders of customer newest first, keyset page after pagination cursor or offset page
+*/
+func (orderStore *OrderStore) GetOrdersByCustomerID(ctx context.Context, customerID uuid.UUID,
+	queryParams types.QueryCustomerOrdersRequest, pagination types.Pagination) ([]types.Order, types.Pagination, error) {
+	whereCondition := sq.And{sq.Eq{"customer_id": customerID}}
+	if queryParams.Status != "" {
+		whereCondition = append(whereCondition, orderStatusCondition(queryParams.Status))
+	}
+	if queryParams.CreatedFrom > 0 {
+		whereCondition = append(whereCondition, sq.GtOrEq{"created_at": time.Unix(queryParams.CreatedFrom, 0)})
+	}
+	if queryParams.CreatedTo > 0 {
+		whereCondition = append(whereCondition, sq.Lt{"created_at": time.Unix(queryParams.CreatedTo, 0)})
+	}
+	if pagination.Cursor != "" {
+		cursor, err := decodeOrderCursor(pagination.Cursor)
+		if err != nil {
+			return nil, types.Pagination{}, err
+		}
+		whereCondition = append(whereCondition, sq.Expr("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID))
+	}
+	// one more row tells whether next page exists
+	queryBuilder := sq.Select(orderColumns).From("orders").Where(whereCondition).
+		OrderBy("created_at DESC", "id DESC").Limit(uint64(pagination.Limit + 1)).PlaceholderFormat(sq.Dollar)
+	if pagination.Cursor == "" && pagination.Offset > 0 {
+		queryBuilder = queryBuilder.Offset(uint64(pagination.Offset))
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, types.Pagination{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := orderStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, types.Pagination{}, fmt.Errorf("failed to query orders %w", err)
+	}
+	defer rows.Close()
+	orders := []types.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, types.Pagination{}, fmt.Errorf("scan order failed %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, types.Pagination{}, err
+	}
+	if int64(len(orders)) > pagination.Limit {
+		orders = orders[:pagination.Limit]
+		pagination.HasMore = true
+		pagination.NextCursor, err = encodeOrderCursor(orders[len(orders)-1])
+		if err != nil {
+			return nil, types.Pagination{}, err
+		}
+		if pagination.Cursor == "" {
+			pagination.NextOffset = pagination.Offset + pagination.Limit
+		}
+	}
+	return orders, pagination, nil
+}
+
+/*
+*
+CreateOrderSegments: flights of multi-segment order in travel order, segment_sequence starts from 1
+*/
+func (orderStore *OrderStore) CreateOrderSegments(tx *sql.Tx, ctx context.Context, orderID uuid.UUID, flightIDs []uuid.UUID) ([]types.OrderSegment, error) {
+	queryBuilder := sq.Insert("order_segments").Columns("order_id", "segment_sequence", "flight_id")
+	for index, flightID := range flightIDs {
+		queryBuilder = queryBuilder.Values(orderID, index+1, flightID)
+	}
+	queryBuilder = queryBuilder.Suffix("RETURNING " + orderSegmentColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("create order segments query builder failed %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("insert order segments failed %w", err)
+	}
+	defer rows.Close()
+	return scanOrderSegments(rows)
+}
+
+func (orderStore *OrderStore) GetOrderSegments(ctx context.Context, orderID uuid.UUID) ([]types.OrderSegment, error) {
+	queryBuilder := sq.Select(orderSegmentColumns).From("order_segments").Where(sq.Eq{"order_id": orderID}).
+		OrderBy("segment_sequence ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := orderStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order segments %w", err)
+	}
+	defer rows.Close()
+	return scanOrderSegments(rows)
+}
+
+func (orderStore *OrderStore) CreatePassengers(tx *sql.Tx, ctx context.Context,
+	createPassengerParams []types.CreatePassengerEntityParam) ([]types.Passenger, error) {
+	if len(createPassengerParams) == 0 {
+		return []types.Passenger{}, nil
+	}
+	// ordinal keeps passengers in order of request
+	queryBuilder := sq.Insert("passengers").Columns("id", "order_id", "name", "date_of_birth", "document_number", "ordinal")
+	for ordinal, passenger := range createPassengerParams {
+		queryBuilder = queryBuilder.Values(passenger.ID, passenger.OrderID, passenger.Name,
+			passenger.DateOfBirth, passenger.DocumentNumber, ordinal)
+	}
+	queryBuilder = queryBuilder.Suffix("RETURNING " + passengerColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("create passengers query builder failed %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("insert passengers failed %w", err)
+	}
+	defer rows.Close()
+	return scanPassengers(rows)
+}
+
+func (orderStore *OrderStore) GetPassengersByOrderID(ctx context.Context, orderID uuid.UUID) ([]types.Passenger, error) {
+	queryBuilder := sq.Select(passengerColumns).
+		From("passengers").Where(sq.Eq{"order_id": orderID}).OrderBy("ordinal ASC", "id ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := orderStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query passengers %w", err)
+	}
+	defer rows.Close()
+	return scanPassengers(rows)
+}
+
+func (orderStore *OrderStore) PayOrder(tx *sql.Tx, ctx context.Context, orderID uuid.UUID) (types.Order, error) {
+	queryBuilder := sq.Update("orders").Set("paid_at", time.Now().UTC()).
+		Where(sq.Eq{"id": orderID, "paid_at": nil, "canceled_at": nil}).
+		Suffix("RETURNING " + orderColumns + ";").
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Order{}, fmt.Errorf("pay order query builder failed %w", err)
+	}
+	resultOrder, err := scanOrder(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.Order{}, fmt.Errorf("update order paid_at failed %w", err)
+	}
+	return resultOrder, nil
+}
+
+func (orderStore *OrderStore) UpdatePassengerSeats(ctx context.Context, seatParams []types.PassengerSeatParam) ([]types.Passenger, error) {
+	tx, err := orderStore.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create db tx failed %w", err)
+	}
+	passengers := make([]types.Passenger, 0, len(seatParams))
+	for _, seatParam := range seatParams {
+		queryBuilder := sq.Update("passengers").Set("seat_number", seatParam.SeatNumber).
+			Where(sq.Eq{"id": seatParam.PassengerID}).Suffix("RETURNING " + passengerColumns + ";").PlaceholderFormat(sq.Dollar)
+		query, args, err := queryBuilder.ToSql()
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("update passenger seat query builder failed %w", err)
+		}
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("update passenger seat failed %w", err)
+		}
+		updated, err := scanPassengers(rows)
+		rows.Close()
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		passengers = append(passengers, updated...)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return passengers, nil
+}
+
+/*
+*
+CheckinPassengers: mark passengers of flight checked in with next check-in sequence numbers, skip passengers already checked in,
+sequence numbers are taken from database under advisory lock of flight so concurrent check-ins never share one
+*/
+func (orderStore *OrderStore) CheckinPassengers(ctx context.Context, flightID uuid.UUID, passengerIDs []uuid.UUID) ([]types.Passenger, error) {
+	tx, err := orderStore.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create db tx failed %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "checkin:"+flightID.String()); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("lock checkin of flight %s failed %w", flightID, err)
+	}
+	queryBuilder := sq.Select("COALESCE(MAX(p.checkin_sequence), 0)").
+		From("passengers p").Join("orders o ON o.id = p.order_id").
+		Where(sq.Eq{"o.flight_id": flightID}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("checkin sequence query builder failed %w", err)
+	}
+	var lastSequence int32
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&lastSequence); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("get checkin sequence of flight %s failed %w", flightID, err)
+	}
+	checkedInAt := time.Now().UTC()
+	passengers := make([]types.Passenger, 0, len(passengerIDs))
+	for _, passengerID := range passengerIDs {
+		queryBuilder := sq.Update("passengers").Set("checked_in_at", checkedInAt).Set("checkin_sequence", lastSequence+1).
+			Where(sq.Eq{"id": passengerID, "checked_in_at": nil}).Suffix("RETURNING " + passengerColumns + ";").PlaceholderFormat(sq.Dollar)
+		query, args, err := queryBuilder.ToSql()
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("checkin passenger query builder failed %w", err)
+		}
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("checkin passenger failed %w", err)
+		}
+		updated, err := scanPassengers(rows)
+		rows.Close()
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		lastSequence += int32(len(updated))
+		passengers = append(passengers, updated...)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return passengers, nil
+}
+
+/*
+*
+VolunteerPassengers: mark passengers of order as volunteers for denied boarding, empty passengerIDs for all
+*/
+func (orderStore *OrderStore) VolunteerPassengers(ctx context.Context, orderID uuid.UUID, passengerIDs []uuid.UUID) ([]types.Passenger, error) {
+	condition := sq.Eq{"order_id": orderID, "volunteered_at": nil}
+	if len(passengerIDs) > 0 {
+		condition["id"] = passengerIDs
+	}
+	queryBuilder := sq.Update("passengers").Set("volunteered_at", time.Now().UTC()).
+		Where(condition).Suffix("RETURNING " + passengerColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("volunteer passengers query builder failed %w", err)
+	}
+	rows, err := orderStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("volunteer passengers failed %w", err)
+	}
+	defer rows.Close()
+	return scanPassengers(rows)
+}
+
+// orderColumns: column order used by scanOrder
+const orderColumns = "id, flight_id, paid_at, canceled_at, created_at, wait_order, ticket_numbers, pnr, itinerary_id, leg_sequence, customer_id, agency_id"
+
+type orderScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrder(scanner orderScanner) (types.Order, error) {
+	var order types.Order
+	err := scanner.Scan(
+		&order.ID,
+		&order.FlightID,
+		&order.PaidAt,
+		&order.CanceledAt,
+		&order.CreatedAt,
+		&order.WaitOrder,
+		&order.TicketNumbers,
+		&order.PNR,
+		&order.ItineraryID,
+		&order.LegSequence,
+		&order.CustomerID,
+		&order.AgencyID,
+	)
+	if err != nil {
+		return types.Order{}, err
+	}
+	return order, nil
+}
+
+// orderSegmentColumns: column order used by scanOrderSegments
+const orderSegmentColumns = "order_id, segment_sequence, flight_id, created_at"
+
+func scanOrderSegments(rows *sql.Rows) ([]types.OrderSegment, error) {
+	segments := []types.OrderSegment{}
+	for rows.Next() {
+		var segment types.OrderSegment
 		err := rows.Scan(
-			&resultOrder.ID,
-			&resultOrder.FlightID,
-			&resultOrder.PaidAt,
-			&resultOrder.CanceledAt,
-			&resultOrder.CreatedAt,
-			&resultOrder.WaitOrder,
-			&resultOrder.TicketNumbers,
+			&segment.OrderID,
+			&segment.SegmentSequence,
+			&segment.FlightID,
+			&segment.CreatedAt,
 		)
 		if err != nil {
-			return types.Order{}, fmt.Errorf("scan order failed %w", err)
+			return nil, fmt.Errorf("scan order segment failed %w", err)
 		}
+		segments = append(segments, segment)
 	}
-	return resultOrder, nil
+	return segments, rows.Err()
+}
+
+// passengerColumns: column order used by scanPassengers
+const passengerColumns = "id, order_id, name, date_of_birth, document_number, ticket_number, ticket_issued_at, seat_number, checked_in_at, checkin_sequence, volunteered_at, created_at"
+
+func scanPassengers(rows *sql.Rows) ([]types.Passenger, error) {
+	passengers := []types.Passenger{}
+	for rows.Next() {
+		var passenger types.Passenger
+		err := rows.Scan(
+			&passenger.ID,
+			&passenger.OrderID,
+			&passenger.Name,
+			&passenger.DateOfBirth,
+			&passenger.DocumentNumber,
+			&passenger.TicketNumber,
+			&passenger.TicketIssuedAt,
+			&passenger.SeatNumber,
+			&passenger.CheckedInAt,
+			&passenger.CheckinSequence,
+			&passenger.VolunteeredAt,
+			&passenger.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan passenger failed %w", err)
+		}
+		passengers = append(passengers, passenger)
+	}
+	return passengers, rows.Err()
 }
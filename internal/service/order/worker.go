@@ -2,16 +2,30 @@ package order
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/yuanyu90221/airline-order-system/internal/broker"
 	"github.com/yuanyu90221/airline-order-system/internal/config"
+	"github.com/yuanyu90221/airline-order-system/internal/metrics"
 	"github.com/yuanyu90221/airline-order-system/internal/types"
 )
 
+const (
+	// requeueDelay: pause before requeueing message of failed transaction, so an unavailable database is not hammered
+	requeueDelay = time.Second
+	// ordersPrimaryKey: constraint violated when event of committed order is delivered again
+	ordersPrimaryKey = "orders_pkey"
+)
+
 type OrderWorker struct {
 	orderService     types.OrderServcie
 	flightCacheStore types.FlightCacheStore
@@ -38,51 +52,241 @@ func (orderWorker *OrderWorker) Run(ctx context.Context) error {
 		return err
 	}
 	for msg := range msgch {
-		data := msg.Body
-		var createOrderEvent types.CreateOrderEvent
-		err := json.Unmarshal(data, &createOrderEvent)
+		if publishedAt, ok := broker.PublishedAt(msg); ok {
+			metrics.OrderWorkerQueueLag.Observe(time.Since(publishedAt).Seconds())
+		}
+		start := time.Now()
+		outcome := orderWorker.processMessage(ctx, msg)
+		metrics.OrderWorkerProcessingDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	}
+	log.Println("worker end")
+	<-ctx.Done()
+	return nil
+}
+
+/*
+*
+processMessage: persist order of create order event, return outcome of message, acked, rejected or requeued
+*/
+func (orderWorker *OrderWorker) processMessage(ctx context.Context, msg amqp.Delivery) string {
+	var createOrderEvent types.CreateOrderEvent
+	err := json.Unmarshal(msg.Body, &createOrderEvent)
+	if err != nil {
+		rejectMessage(msg, "unmarchal event failed", err)
+		return metrics.OutcomeRejected
+	}
+	// log.Println(createOrderEvent)
+	if len(createOrderEvent.Legs) > 0 {
+		return orderWorker.createItineraryOrders(ctx, msg, createOrderEvent)
+	}
+	createOrderParam, createPassengerParams, err := parseCreateOrderEvent(createOrderEvent)
+	if err != nil {
+		rejectMessage(msg, "parse create order event failed:", err)
+		return metrics.OutcomeRejected
+	}
+
+	if len(createOrderEvent.Segments) > 0 {
+		return orderWorker.createSegmentedOrder(ctx, msg, createOrderEvent, createOrderParam, createPassengerParams)
+	}
+	updateFlightParams := types.UpdateFlightEntityParam{
+		ID:             createOrderParam.FlightID,
+		AvailableSeats: int32(createOrderEvent.AvailableSeats),
+		WaitSeats:      int32(createOrderEvent.WaitSeats),
+		NextWaitOrder:  int32(createOrderEvent.WaitOrder),
+	}
+	flight, order, err := orderWorker.orderService.CreateOrderHandler(ctx, createOrderParam, updateFlightParams, createPassengerParams)
+	if err != nil {
+		return failMessage(ctx, msg, fmt.Sprintf("failed to create order %s", createOrderParam.ID), err)
+	}
+	// order is committed, stale flight cache is refreshed by next order of flight
+	if _, err := orderWorker.flightCacheStore.UpdateFlight(ctx, flight); err != nil {
+		log.Printf("faield to update flight cache %v", err)
+	}
+	// rebooked orders are already paid on original flight, issue tickets directly
+	if createOrderEvent.Prepaid && !createOrderEvent.IsWait {
+		if _, _, err := orderWorker.orderService.PayOrderHandler(ctx, order.ID); err != nil {
+			log.Printf("failed to pay prepaid order %v, %v", err, order.ID)
+		}
+	}
+	// log.Printf("finish update flight: %v\n order: %v\n", flight, order)
+	return ackMessage(msg)
+}
+
+// parseCreateOrderEvent: order and passengers of create order event, error when event is malformed
+func parseCreateOrderEvent(createOrderEvent types.CreateOrderEvent) (types.CreateOrderEntityParam, []types.CreatePassengerEntityParam, error) {
+	flightID, err := uuid.Parse(createOrderEvent.FlightID)
+	if err != nil {
+		return types.CreateOrderEntityParam{}, nil, fmt.Errorf("parse flightID failed %w", err)
+	}
+	ID, err := uuid.Parse(createOrderEvent.ID)
+	if err != nil {
+		return types.CreateOrderEntityParam{}, nil, fmt.Errorf("parse order id failed %w", err)
+	}
+	createOrderParam := types.CreateOrderEntityParam{
+		ID:            ID,
+		FlightID:      flightID,
+		WaitOrder:     int32(createOrderEvent.WaitOrder),
+		TicketNumbers: int32(createOrderEvent.TicketNumbers),
+		PNR:           createOrderEvent.PNR,
+		Fare:          sql.NullFloat64{Float64: createOrderEvent.Fare, Valid: createOrderEvent.Fare > 0},
+	}
+	if createOrderEvent.ItineraryID != "" {
+		itineraryID, err := uuid.Parse(createOrderEvent.ItineraryID)
 		if err != nil {
-			log.Println("unmarchal event failed", err)
-			continue
+			return types.CreateOrderEntityParam{}, nil, fmt.Errorf("parse itineraryID failed %w", err)
 		}
-		// log.Println(createOrderEvent)
-		flightID, err := uuid.Parse(createOrderEvent.FlightID)
+		createOrderParam.ItineraryID = uuid.NullUUID{UUID: itineraryID, Valid: true}
+		createOrderParam.LegSequence = sql.NullInt16{Int16: createOrderEvent.LegSequence, Valid: true}
+	}
+	if createOrderEvent.CustomerID != "" {
+		customerID, err := uuid.Parse(createOrderEvent.CustomerID)
 		if err != nil {
-			log.Println("parse flightID failed:", err)
-			continue
+			return types.CreateOrderEntityParam{}, nil, fmt.Errorf("parse customerID failed %w", err)
 		}
-		ID, err := uuid.Parse(createOrderEvent.ID)
+		createOrderParam.CustomerID = uuid.NullUUID{UUID: customerID, Valid: true}
+	}
+	if createOrderEvent.AgencyID != "" {
+		agencyID, err := uuid.Parse(createOrderEvent.AgencyID)
 		if err != nil {
-			log.Println("parse flightID failed:", err)
-			continue
+			return types.CreateOrderEntityParam{}, nil, fmt.Errorf("parse agencyID failed %w", err)
 		}
-		createOrderParam := types.CreateOrderEntityParam{
-			ID:            ID,
-			FlightID:      flightID,
-			WaitOrder:     int32(createOrderEvent.WaitOrder),
-			TicketNumbers: int32(createOrderEvent.TicketNumbers),
+		createOrderParam.AgencyID = uuid.NullUUID{UUID: agencyID, Valid: true}
+	}
+	createPassengerParams := make([]types.CreatePassengerEntityParam, 0, len(createOrderEvent.Passengers))
+	for _, passenger := range createOrderEvent.Passengers {
+		dateOfBirth, err := time.Parse(time.DateOnly, passenger.DateOfBirth)
+		if err != nil {
+			return types.CreateOrderEntityParam{}, nil, fmt.Errorf("parse passenger date_of_birth failed %w", err)
 		}
+		createPassengerParams = append(createPassengerParams, types.CreatePassengerEntityParam{
+			ID:             uuid.New(),
+			OrderID:        ID,
+			Name:           passenger.Name,
+			DateOfBirth:    dateOfBirth,
+			DocumentNumber: passenger.DocumentNumber,
+		})
+	}
+	return createOrderParam, createPassengerParams, nil
+}
 
-		updateFlightParams := types.UpdateFlightEntityParam{
-			ID:             flightID,
-			AvailableSeats: int32(createOrderEvent.AvailableSeats),
-			WaitSeats:      int32(createOrderEvent.WaitSeats),
-			NextWaitOrder:  int32(createOrderEvent.WaitOrder),
-		}
-		flight, order, err := orderWorker.orderService.CreateOrderHandler(ctx, createOrderParam, updateFlightParams)
+/*
+*
+createItineraryOrders: persist orders of every itinerary leg in one transaction and refresh flight cache of every leg,
+return outcome of message
+*/
+func (orderWorker *OrderWorker) createItineraryOrders(ctx context.Context, msg amqp.Delivery, createOrderEvent types.CreateOrderEvent) string {
+	legs := make([]types.ItineraryLegEntityParam, 0, len(createOrderEvent.Legs))
+	for _, legEvent := range createOrderEvent.Legs {
+		createOrderParam, createPassengerParams, err := parseCreateOrderEvent(legEvent)
 		if err != nil {
-			log.Printf("failed to create order %v, %v", err, order)
-			continue
+			rejectMessage(msg, "parse itinerary leg event failed:", err)
+			return metrics.OutcomeRejected
 		}
-		_, err = orderWorker.flightCacheStore.UpdateFlight(ctx, flight)
+		legs = append(legs, types.ItineraryLegEntityParam{
+			CreateOrder: createOrderParam,
+			UpdateFlight: types.UpdateFlightEntityParam{
+				ID:             createOrderParam.FlightID,
+				AvailableSeats: int32(legEvent.AvailableSeats),
+				WaitSeats:      int32(legEvent.WaitSeats),
+				NextWaitOrder:  int32(legEvent.WaitOrder),
+			},
+			Passengers: createPassengerParams,
+		})
+	}
+	flights, _, err := orderWorker.orderService.CreateItineraryOrdersHandler(ctx, legs)
+	if err != nil {
+		return failMessage(ctx, msg, fmt.Sprintf("failed to create itinerary %s", createOrderEvent.ItineraryID), err)
+	}
+	orderWorker.updateFlightCache(ctx, flights)
+	return ackMessage(msg)
+}
+
+/*
+*
+createSegmentedOrder: persist multi-segment order and refresh flight cache of every segment, return outcome of message
+*/
+func (orderWorker *OrderWorker) createSegmentedOrder(ctx context.Context, msg amqp.Delivery, createOrderEvent types.CreateOrderEvent,
+	createOrderParam types.CreateOrderEntityParam, createPassengerParams []types.CreatePassengerEntityParam) string {
+	updateFlightParams := make([]types.UpdateFlightEntityParam, 0, len(createOrderEvent.Segments))
+	for _, segment := range createOrderEvent.Segments {
+		flightID, err := uuid.Parse(segment.FlightID)
 		if err != nil {
+			rejectMessage(msg, "parse segment flightID failed:", err)
+			return metrics.OutcomeRejected
+		}
+		updateFlightParams = append(updateFlightParams, types.UpdateFlightEntityParam{
+			ID:             flightID,
+			AvailableSeats: int32(segment.AvailableSeats),
+			WaitSeats:      int32(segment.WaitSeats),
+			NextWaitOrder:  int32(segment.WaitOrder),
+		})
+	}
+	flights, order, err := orderWorker.orderService.CreateSegmentedOrderHandler(ctx, createOrderParam, updateFlightParams, createPassengerParams)
+	if err != nil {
+		return failMessage(ctx, msg, fmt.Sprintf("failed to create segmented order %s", createOrderParam.ID), err)
+	}
+	orderWorker.updateFlightCache(ctx, flights)
+	if createOrderEvent.Prepaid {
+		if _, _, err := orderWorker.orderService.PayOrderHandler(ctx, order.ID); err != nil {
+			log.Printf("failed to pay prepaid order %v, %v", err, order.ID)
+		}
+	}
+	return ackMessage(msg)
+}
+
+// updateFlightCache: refresh cache of flights after order is committed, failure is only logged as message is acked anyway
+func (orderWorker *OrderWorker) updateFlightCache(ctx context.Context, flights []types.Flight) {
+	for _, flight := range flights {
+		if _, err := orderWorker.flightCacheStore.UpdateFlight(ctx, flight); err != nil {
 			log.Printf("faield to update flight cache %v", err)
-			continue
 		}
-		msg.Ack(false)
-		// log.Printf("finish update flight: %v\n order: %v\n", flight, order)
 	}
-	log.Println("worker end")
-	<-ctx.Done()
-	return nil
+}
+
+// ackMessage: ack message of committed order
+func ackMessage(msg amqp.Delivery) string {
+	if err := msg.Ack(false); err != nil {
+		log.Printf("failed to ack message %v", err)
+	}
+	return metrics.OutcomeAcked
+}
+
+/*
+*
+failMessage: settle message of failed order transaction, data refused by database is rejected,
+order already created by earlier delivery is acked, other failures like lost connections are requeued after requeueDelay
+*/
+func failMessage(ctx context.Context, msg amqp.Delivery, reason string, err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == ordersPrimaryKey {
+			log.Printf("%s, already created by earlier delivery %v", reason, err)
+			return ackMessage(msg)
+		}
+		// data exception and integrity constraint violation fail on every redelivery
+		if pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23" {
+			rejectMessage(msg, reason, err)
+			return metrics.OutcomeRejected
+		}
+	}
+	log.Println(reason, err)
+	select {
+	case <-ctx.Done():
+	case <-time.After(requeueDelay):
+	}
+	if err := msg.Nack(false, true); err != nil {
+		log.Printf("failed to nack message %v", err)
+	}
+	return metrics.OutcomeRequeued
+}
+
+/*
+*
+rejectMessage: nack malformed message without requeue, redelivery could never succeed
+*/
+func rejectMessage(msg amqp.Delivery, reason string, err error) {
+	log.Println(reason, err)
+	if err := msg.Nack(false, false); err != nil {
+		log.Printf("failed to nack message %v", err)
+	}
 }
@@ -0,0 +1,89 @@
+package overbooking
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	overbookingService types.OverbookingService
+	policyStore        types.OverbookingPolicyStore
+}
+
+func NewHandler(overbookingService types.OverbookingService, policyStore types.OverbookingPolicyStore) *Handler {
+	return &Handler{
+		overbookingService: overbookingService,
+		policyStore:        policyStore,
+	}
+}
+
+func (h *Handler) RegisterAdminRoute(router *gin.RouterGroup) {
+	router.POST("/overbooking-policies", h.CreatePolicy)
+	router.GET("/overbooking-policies", h.GetPolicies)
+	router.POST("/flights/:id/overbooking/recompute", h.RecomputeFlight)
+}
+
+func (h *Handler) CreatePolicy(ctx *gin.Context) {
+	var createPolicy types.CreateOverbookingPolicyRequest
+	if err := util.ParseJSON(ctx.Request, &createPolicy); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createPolicy); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	policy, err := h.policyStore.CreatePolicy(ctx, createPolicy)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, policy), "failed to response json")
+}
+
+func (h *Handler) GetPolicies(ctx *gin.Context) {
+	query := ctx.Request.URL.Query()
+	policies, err := h.policyStore.GetPolicies(ctx, query.Get("departure"), query.Get("destination"))
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, policies), "failed to response json")
+}
+
+func (h *Handler) RecomputeFlight(ctx *gin.Context) {
+	flightID := ctx.Param("id")
+	id, err := uuid.Parse(flightID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", flightID, err))
+		return
+	}
+	flight, err := h.overbookingService.RecomputeFlight(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrFlightNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, ErrPolicyNotFound) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return
+		}
+		if errors.Is(err, ErrFlightDeparted) || errors.Is(err, ErrWaitSeatsFixed) {
+			util.WriteError(ctx.Writer, http.StatusConflict, err)
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to recompute overbooking %w", err))
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertFlightToRespone(flight)), "failed to response json")
+}
@@ -0,0 +1,182 @@
+package overbooking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/service/airport"
+	"github.com/yuanyu90221/airline-order-system/internal/service/noshow"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// noShowMinSample: booked passengers needed before measured no-show rate replaces policy no_show_rate
+const noShowMinSample = 30
+
+var (
+	ErrFlightNotFound = apperr.New(apperr.NotFound, "flight not found")
+	ErrFlightDeparted = apperr.New(apperr.Conflict, "flight already departed")
+	ErrPolicyNotFound = apperr.New(apperr.NotFound, "no overbooking policy effective on route")
+	ErrWaitSeatsFixed = apperr.New(apperr.Conflict, "wait seats of flight were given explicitly")
+)
+
+// compute and adjust oversell (wait_seats) of flights from overbooking policies
+type OverbookingService struct {
+	policyStore      types.OverbookingPolicyStore
+	flightStore      types.FlightStore
+	orderCacheStore  types.OrderCacheStore
+	flightCacheStore types.FlightCacheStore
+	noShowStore      types.NoShowStore
+	airportDirectory types.AirportDirectory
+}
+
+func NewOverbookingService(policyStore types.OverbookingPolicyStore, flightStore types.FlightStore,
+	orderCacheStore types.OrderCacheStore, flightCacheStore types.FlightCacheStore, noShowStore types.NoShowStore,
+	airportDirectory types.AirportDirectory) *OverbookingService {
+	return &OverbookingService{
+		policyStore:      policyStore,
+		flightStore:      flightStore,
+		orderCacheStore:  orderCacheStore,
+		flightCacheStore: flightCacheStore,
+		noShowStore:      noShowStore,
+		airportDirectory: airportDirectory,
+	}
+}
+
+/*
+*
+WaitSeatsForPolicy: oversell seats of capacity under policy
+the larger of oversell_percentage of capacity and expected no-shows (no_show_rate * capacity),
+limited by max_wait_seats when it is set
+*/
+func WaitSeatsForPolicy(policy types.OverbookingPolicy, capacity int64) int64 {
+	byPercentage := math.Floor(float64(capacity) * policy.OversellPercentage / 100)
+	byNoShow := math.Floor(float64(capacity) * policy.NoShowRate)
+	waitSeats := int64(math.Max(byPercentage, byNoShow))
+	if policy.MaxWaitSeats > 0 && waitSeats > int64(policy.MaxWaitSeats) {
+		waitSeats = int64(policy.MaxWaitSeats)
+	}
+	return waitSeats
+}
+
+/*
+*
+ComputeWaitSeats: oversell seats from policy effective on route at flightDate, ErrPolicyNotFound when no policy matched
+policy with use_noshow_stats takes measured route no-show rate on same local day of week at departure airport
+once sample is large enough
+*/
+func (service *OverbookingService) ComputeWaitSeats(ctx context.Context, departure string, destination string,
+	flightDate time.Time, capacity int64) (int64, error) {
+	policy, err := service.policyStore.GetEffectivePolicy(ctx, departure, destination, flightDate)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s-%s at %s %w", departure, destination, flightDate.Format(time.RFC3339), ErrPolicyNotFound)
+		}
+		return 0, fmt.Errorf("failed to get overbooking policy %w", err)
+	}
+	if policy.UseNoShowStats {
+		stats, err := service.noShowStore.GetRouteStats(ctx, departure, destination)
+		if err != nil {
+			return 0, err
+		}
+		// outcomes are recorded by local day of week, flights from unknown airports keep utc day
+		location, err := service.airportDirectory.Location(ctx, departure)
+		if err != nil {
+			if !errors.Is(err, airport.ErrAirportNotFound) {
+				return 0, err
+			}
+			location = time.UTC
+		}
+		measured := noshow.DayOfWeekRate(stats, int16(flightDate.In(location).Weekday()))
+		if measured.Booked >= noShowMinSample {
+			policy.NoShowRate = measured.NoShowRate
+		}
+	}
+	return WaitSeatsForPolicy(policy, capacity), nil
+}
+
+/*
+*
+RecomputeFlight: apply current policy to flight, flights with explicit wait seats or on routes without policy keep their oversell,
+cache moves remaining wait seats by the difference atomically and keeps wait seats already sold, so remaining never goes below zero
+*/
+func (service *OverbookingService) RecomputeFlight(ctx context.Context, flightID uuid.UUID) (types.Flight, error) {
+	flight, err := service.flightStore.GetFlightById(ctx, flightID)
+	if err != nil {
+		return types.Flight{}, err
+	}
+	if flight.ID == uuid.Nil {
+		return types.Flight{}, fmt.Errorf("flight %s %w", flightID, ErrFlightNotFound)
+	}
+	if !flight.FlightDate.After(time.Now().UTC()) {
+		return types.Flight{}, fmt.Errorf("flight %s %w", flightID, ErrFlightDeparted)
+	}
+	if flight.WaitCapacityOverride {
+		return types.Flight{}, fmt.Errorf("flight %s %w", flightID, ErrWaitSeatsFixed)
+	}
+	waitCapacity, err := service.ComputeWaitSeats(ctx, flight.Departure, flight.Destination, flight.FlightDate, int64(flight.Capacity))
+	if err != nil {
+		return types.Flight{}, err
+	}
+	flightInfo, err := service.flightCacheStore.GetFlightCacheInfo(ctx, flightID.String())
+	if err != nil {
+		flightInfo = convertFlightResponseToEntity(flight)
+	}
+	result, err := service.orderCacheStore.AdjustWait(ctx, types.WaitCacheAdjustParam{
+		OrderCacheParam: types.OrderCacheParam{
+			FlightID:         flightID.String(),
+			CurrentTotal:     int64(flightInfo.AvailableSeats),
+			CurrentWait:      int64(flightInfo.WaitSeats),
+			CurrentWaitOrder: int64(flightInfo.NextWaitOrder),
+		},
+		WaitCapacity:        waitCapacity,
+		CurrentWaitCapacity: int64(flight.WaitCapacity),
+	})
+	if err != nil {
+		return types.Flight{}, err
+	}
+	if result.AppliedDelta == 0 && result.WaitCapacity == int64(flight.WaitCapacity) {
+		return convertFlightResponseToEntity(flight), nil
+	}
+	// counters in cache are source of truth, later order events carry them back to db
+	updated, err := service.flightStore.UpdateWaitCapacity(ctx, flightID, int32(result.WaitCapacity), int32(result.CurrentWait))
+	if err != nil {
+		return types.Flight{}, err
+	}
+	updated.AvailableSeats = int32(result.CurrentTotal)
+	updated.NextWaitOrder = int32(result.CurrentWaitOrder)
+	if _, err := service.flightCacheStore.UpdateFlight(ctx, updated); err != nil {
+		return types.Flight{}, err
+	}
+	return updated, nil
+}
+
+func convertFlightResponseToEntity(flight types.FlightResponse) types.Flight {
+	result := types.Flight{
+		ID:             flight.ID,
+		Departure:      flight.Departure,
+		Destination:    flight.Destination,
+		FlightDate:     flight.FlightDate,
+		Price:          flight.Price,
+		AvailableSeats: flight.AvailableSeats,
+		WaitSeats:      flight.WaitSeats,
+		NextWaitOrder:  flight.NextWaitOrder,
+		Capacity:       flight.Capacity,
+		WaitCapacity:   flight.WaitCapacity,
+		CarrierCode:    flight.CarrierCode,
+		FlightNumber:   flight.FlightNumber,
+		AircraftType:   flight.AircraftType,
+		CreatedAt:      flight.CreatedAt,
+		UpdatedAt:      flight.UpdatedAt,
+	}
+	result.WaitCapacityOverride = flight.WaitCapacityOverride
+	if flight.ArrivalDate != nil {
+		result.ArrivalDate = sql.NullTime{Time: *flight.ArrivalDate, Valid: true}
+	}
+	return result
+}
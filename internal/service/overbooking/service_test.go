@@ -0,0 +1,36 @@
+package overbooking
+
+import (
+	"testing"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+func TestWaitSeatsForPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   types.OverbookingPolicy
+		capacity int64
+		want     int64
+	}{
+		{name: "no oversell", policy: types.OverbookingPolicy{}, capacity: 180, want: 0},
+		{name: "by percentage", policy: types.OverbookingPolicy{OversellPercentage: 5}, capacity: 100, want: 5},
+		{name: "by no-show rate", policy: types.OverbookingPolicy{NoShowRate: 0.08}, capacity: 100, want: 8},
+		{name: "larger of percentage and no-show", policy: types.OverbookingPolicy{OversellPercentage: 10, NoShowRate: 0.08},
+			capacity: 100, want: 10},
+		{name: "percentage rounded down", policy: types.OverbookingPolicy{OversellPercentage: 3.5}, capacity: 150, want: 5},
+		{name: "no-show rounded down", policy: types.OverbookingPolicy{NoShowRate: 0.0333}, capacity: 150, want: 4},
+		{name: "capped by max wait seats", policy: types.OverbookingPolicy{OversellPercentage: 10, MaxWaitSeats: 6},
+			capacity: 100, want: 6},
+		{name: "max wait seats above computed", policy: types.OverbookingPolicy{OversellPercentage: 10, MaxWaitSeats: 50},
+			capacity: 100, want: 10},
+		{name: "zero capacity", policy: types.OverbookingPolicy{OversellPercentage: 10, NoShowRate: 0.1}, capacity: 0, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := WaitSeatsForPolicy(test.policy, test.capacity); got != test.want {
+				t.Errorf("WaitSeatsForPolicy(%+v, %d) = %d, want %d", test.policy, test.capacity, got, test.want)
+			}
+		})
+	}
+}
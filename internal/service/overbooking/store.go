@@ -0,0 +1,129 @@
+package overbooking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+const policyColumns = "id, departure, destination, oversell_percentage, no_show_rate, max_wait_seats, use_noshow_stats, effective_from, effective_to, created_at, updated_at"
+
+type PolicyStore struct {
+	db *sql.DB
+}
+
+func NewPolicyStore(db *sql.DB) *PolicyStore {
+	return &PolicyStore{db: db}
+}
+
+type policyScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPolicy(scanner policyScanner) (types.OverbookingPolicy, error) {
+	var policy types.OverbookingPolicy
+	err := scanner.Scan(
+		&policy.ID,
+		&policy.Departure,
+		&policy.Destination,
+		&policy.OversellPercentage,
+		&policy.NoShowRate,
+		&policy.MaxWaitSeats,
+		&policy.UseNoShowStats,
+		&policy.EffectiveFrom,
+		&policy.EffectiveTo,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	)
+	if err != nil {
+		return types.OverbookingPolicy{}, fmt.Errorf("scan overbooking policy failed %w", err)
+	}
+	return policy, nil
+}
+
+/*
+*
+CreatePolicy: empty departure or destination matches any route
+*/
+func (policyStore *PolicyStore) CreatePolicy(ctx context.Context, createParams types.CreateOverbookingPolicyRequest) (types.OverbookingPolicy, error) {
+	effectiveTo := sql.NullTime{}
+	if createParams.EffectiveTo != 0 {
+		effectiveTo = sql.NullTime{Time: time.Unix(createParams.EffectiveTo, 0).UTC(), Valid: true}
+	}
+	queryBuilder := sq.Insert("overbooking_policies").
+		Columns("id", "departure", "destination", "oversell_percentage", "no_show_rate", "max_wait_seats", "use_noshow_stats", "effective_from", "effective_to").
+		Values(uuid.New(), createParams.Departure, createParams.Destination, createParams.OversellPercentage,
+			createParams.NoShowRate, createParams.MaxWaitSeats, createParams.UseNoShowStats, time.Unix(createParams.EffectiveFrom, 0).UTC(), effectiveTo).
+		Suffix("RETURNING " + policyColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.OverbookingPolicy{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	policy, err := scanPolicy(policyStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.OverbookingPolicy{}, fmt.Errorf("failed to insert overbooking policy %w", err)
+	}
+	return policy, nil
+}
+
+/*
+*
+GetPolicies: policies filtered by route, empty filter lists all
+*/
+func (policyStore *PolicyStore) GetPolicies(ctx context.Context, departure string, destination string) ([]types.OverbookingPolicy, error) {
+	queryBuilder := sq.Select(policyColumns).From("overbooking_policies").PlaceholderFormat(sq.Dollar)
+	if departure != "" {
+		queryBuilder = queryBuilder.Where(sq.Eq{"departure": departure})
+	}
+	if destination != "" {
+		queryBuilder = queryBuilder.Where(sq.Eq{"destination": destination})
+	}
+	query, args, err := queryBuilder.OrderBy("departure ASC", "destination ASC", "effective_from DESC").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := policyStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query overbooking policies %w", err)
+	}
+	defer rows.Close()
+	policies := []types.OverbookingPolicy{}
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+/*
+*
+GetEffectivePolicy: most specific policy effective at flightDate,
+route policy wins over departure or destination only policy, which wins over default policy
+return sql.ErrNoRows when no policy matched
+*/
+func (policyStore *PolicyStore) GetEffectivePolicy(ctx context.Context, departure string, destination string, flightDate time.Time) (types.OverbookingPolicy, error) {
+	queryBuilder := sq.Select(policyColumns).From("overbooking_policies").
+		Where(sq.Or{sq.Eq{"departure": departure}, sq.Eq{"departure": ""}}).
+		Where(sq.Or{sq.Eq{"destination": destination}, sq.Eq{"destination": ""}}).
+		Where(sq.LtOrEq{"effective_from": flightDate}).
+		Where(sq.Or{sq.Eq{"effective_to": nil}, sq.Gt{"effective_to": flightDate}}).
+		OrderBy("(departure <> '') DESC", "(destination <> '') DESC", "effective_from DESC").
+		Limit(1).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.OverbookingPolicy{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	policy, err := scanPolicy(policyStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.OverbookingPolicy{}, err
+	}
+	return policy, nil
+}
@@ -0,0 +1,65 @@
+package overbooking
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// recompute oversell of flights approaching departure
+type RecomputeWorker struct {
+	overbookingService types.OverbookingService
+	flightStore        types.FlightStore
+	horizon            time.Duration
+	interval           time.Duration
+}
+
+func NewRecomputeWorker(overbookingService types.OverbookingService, flightStore types.FlightStore,
+	horizon time.Duration, interval time.Duration) *RecomputeWorker {
+	return &RecomputeWorker{
+		overbookingService: overbookingService,
+		flightStore:        flightStore,
+		horizon:            horizon,
+		interval:           interval,
+	}
+}
+
+func (worker *RecomputeWorker) Run(ctx context.Context) error {
+	log.Println("overbooking recompute worker start")
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("overbooking recompute worker end")
+			return nil
+		case <-ticker.C:
+			worker.recomputeUpcoming(ctx)
+		}
+	}
+}
+
+func (worker *RecomputeWorker) recomputeUpcoming(ctx context.Context) {
+	now := time.Now().UTC()
+	flights, err := worker.flightStore.GetFlightsDepartingBetween(ctx, now, now.Add(worker.horizon))
+	if err != nil {
+		log.Printf("failed to get upcoming flights %v", err)
+		return
+	}
+	for _, flight := range flights {
+		updated, err := worker.overbookingService.RecomputeFlight(ctx, flight.ID)
+		if errors.Is(err, ErrPolicyNotFound) || errors.Is(err, ErrWaitSeatsFixed) {
+			continue
+		}
+		if err != nil {
+			log.Printf("failed to recompute overbooking for flight %s %v", flight.ID, err)
+			continue
+		}
+		if updated.WaitCapacity != flight.WaitCapacity {
+			log.Printf("flight %s wait capacity %d -> %d", flight.ID, flight.WaitCapacity, updated.WaitCapacity)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+type CacheStore struct {
+	rdb *redis.Client
+}
+
+func NewCacheStore(rdb *redis.Client) *CacheStore {
+	return &CacheStore{
+		rdb: rdb,
+	}
+}
+
+/*
+*
+Take: take one token of every bucket or none, buckets are shared by every instance using the same redis
+*/
+func (cache *CacheStore) Take(ctx context.Context, buckets []types.RateLimitBucket) (types.RateLimitResult, error) {
+	keys := make([]string, 0, len(buckets))
+	args := make([]interface{}, 0, 2*len(buckets))
+	for _, bucket := range buckets {
+		keys = append(keys, bucket.Key)
+		args = append(args, bucket.Limit, bucket.Window.Milliseconds())
+	}
+	resultList, err := TakeTokenWithKeys.Run(ctx, cache.rdb, keys, args...).Int64Slice()
+	if err != nil {
+		return types.RateLimitResult{}, fmt.Errorf("failed to take rate limit token %w", err)
+	}
+	result := types.RateLimitResult{
+		Allowed:    resultList[0] == 1,
+		RetryAfter: time.Duration(resultList[1]) * time.Millisecond,
+		Remaining:  -1,
+	}
+	for index, bucket := range buckets {
+		remaining := resultList[2+2*index]
+		if result.Remaining < 0 || remaining < result.Remaining {
+			result.Limit = bucket.Limit
+			result.Remaining = remaining
+			result.Reset = time.Duration(resultList[3+2*index]) * time.Millisecond
+		}
+	}
+	return result, nil
+}
+
+/*
+*
+TakeTokenWithKeys: luascript for token buckets refilled continuously with redis server time,
+one token is taken from every bucket only when every bucket has one
+input keys: bucket keys, arguments: limit, window_ms of each bucket
+return {is_allowed, retry_after_ms, then remaining, reset_ms of each bucket}
+*
+*/
+var TakeTokenWithKeys = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local tokens = {}
+local is_allowed = 1
+local retry_after = 0
+for index, key in ipairs(KEYS) do
+	local limit = tonumber(ARGV[2 * index - 1])
+	local window = tonumber(ARGV[2 * index])
+	local rate = limit / window
+	local bucket = redis.call("HMGET", key, "tokens", "ts")
+	local current = tonumber(bucket[1])
+	local ts = tonumber(bucket[2])
+	if not current or not ts then
+		current = limit
+		ts = now
+	end
+	current = math.min(limit, current + math.max(0, now - ts) * rate)
+	if current < 1 then
+		is_allowed = 0
+		retry_after = math.max(retry_after, math.ceil((1 - current) / rate))
+	end
+	tokens[index] = current
+end
+local result = {is_allowed, retry_after}
+for index, key in ipairs(KEYS) do
+	local limit = tonumber(ARGV[2 * index - 1])
+	local window = tonumber(ARGV[2 * index])
+	local current = tokens[index]
+	if is_allowed == 1 then
+		current = current - 1
+	end
+	redis.call("HSET", key, "tokens", tostring(current), "ts", now)
+	redis.call("PEXPIRE", key, window)
+	table.insert(result, math.floor(current))
+	table.insert(result, math.ceil((limit - current) * window / limit))
+end
+return result
+`)
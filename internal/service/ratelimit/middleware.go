@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/service/agency"
+	"github.com/yuanyu90221/airline-order-system/internal/service/customer"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+var ErrRateLimited = apperr.New(apperr.TooManyRequests, "too many requests")
+
+/*
+*
+Limit: limit requests of route by rule of "METHOD /path", DefaultRoute rule for routes without own rule,
+customer scope counts customers of bearer token, anonymous requests are limited by ip,
+requests are let through when redis fails so limiter outage does not stop bookings
+*/
+func Limit(cacheStore types.RateLimitCacheStore, rules map[string]Rule) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		rule, ok := rules[route]
+		if !ok {
+			route = DefaultRoute
+			rule, ok = rules[DefaultRoute]
+		}
+		if !ok || ctx.FullPath() == "" {
+			ctx.Next()
+			return
+		}
+		buckets := requestBuckets(ctx, route, rule)
+		if len(buckets) == 0 {
+			ctx.Next()
+			return
+		}
+		result, err := cacheStore.Take(ctx, buckets)
+		if err != nil {
+			log.Printf("rate limit skipped %v", err)
+			ctx.Next()
+			return
+		}
+		ctx.Header("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		ctx.Header("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		ctx.Header("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+			util.WriteError(ctx.Writer, http.StatusTooManyRequests, fmt.Errorf("%w on %s", ErrRateLimited, route))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// requestBuckets: bucket of every scope of rule the request could be identified by
+func requestBuckets(ctx *gin.Context, route string, rule Rule) []types.RateLimitBucket {
+	identities := map[string]string{
+		ScopeIP: ctx.ClientIP(),
+	}
+	if customerID, ok := customer.CustomerID(ctx); ok {
+		identities[ScopeCustomer] = customerID.String()
+	}
+	if apiKey, ok := agency.CurrentAPIKey(ctx); ok {
+		identities[ScopeAPIKey] = apiKey.ID.String()
+	}
+	buckets := make([]types.RateLimitBucket, 0, len(rule))
+	for _, scope := range []string{ScopeIP, ScopeCustomer, ScopeAPIKey} {
+		rate, ok := rule[scope]
+		identity, identified := identities[scope]
+		if !ok || !identified || identity == "" {
+			continue
+		}
+		buckets = append(buckets, types.RateLimitBucket{
+			Key:    fmt.Sprintf("rate_limit:%s:%s:%s", route, scope, identity),
+			Limit:  rate.Limit,
+			Window: rate.Window,
+		})
+	}
+	return buckets
+}
+
+func ceilSeconds(duration time.Duration) int64 {
+	return int64(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeIP       = "ip"
+	ScopeCustomer = "customer"
+	ScopeAPIKey   = "api_key"
+)
+
+// DefaultRoute: rule of routes without own rule
+const DefaultRoute = "*"
+
+var ErrInvalidRule = errors.New("invalid rate limit rule")
+
+// Rate: Limit requests every Window
+type Rate struct {
+	Limit  int64
+	Window time.Duration
+}
+
+// Rule: rates of route by scope, scopes without rate are not limited
+type Rule map[string]Rate
+
+/*
+*
+ParseRules: rules by "METHOD /path" of spec like "POST /orders/=ip:30/m;customer:10/m,*=ip:600/m",
+path is the gin route path
+*/
+func ParseRules(spec string) (map[string]Rule, error) {
+	rules := make(map[string]Rule)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, rates, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("%w: %q missing =", ErrInvalidRule, entry)
+		}
+		route = normalizeRoute(route)
+		rule := make(Rule)
+		for _, scopeRate := range strings.Split(rates, ";") {
+			scope, rate, err := parseScopeRate(strings.TrimSpace(scopeRate))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q %v", ErrInvalidRule, entry, err)
+			}
+			rule[scope] = rate
+		}
+		rules[route] = rule
+	}
+	return rules, nil
+}
+
+// normalizeRoute: "post  /orders/" as "POST /orders/"
+func normalizeRoute(route string) string {
+	fields := strings.Fields(route)
+	if len(fields) == 2 {
+		return strings.ToUpper(fields[0]) + " " + fields[1]
+	}
+	return strings.TrimSpace(route)
+}
+
+// parseScopeRate: "ip:30/m" as ip scope with 30 requests a minute
+func parseScopeRate(scopeRate string) (string, Rate, error) {
+	scope, rate, found := strings.Cut(scopeRate, ":")
+	if !found {
+		return "", Rate{}, fmt.Errorf("missing scope in %q", scopeRate)
+	}
+	switch scope {
+	case ScopeIP, ScopeCustomer, ScopeAPIKey:
+	default:
+		return "", Rate{}, fmt.Errorf("unknown scope %q", scope)
+	}
+	limit, unit, found := strings.Cut(rate, "/")
+	if !found {
+		return "", Rate{}, fmt.Errorf("missing unit in %q", scopeRate)
+	}
+	value, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil || value < 1 {
+		return "", Rate{}, fmt.Errorf("limit of %q must be a positive integer", scopeRate)
+	}
+	windows := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	window, ok := windows[unit]
+	if !ok {
+		return "", Rate{}, fmt.Errorf("unit of %q must be s, m or h", scopeRate)
+	}
+	return scope, Rate{Limit: value, Window: window}, nil
+}
@@ -0,0 +1,168 @@
+package schedule
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/service/airport"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+// maxGenerateDays: upper bound of days accepted by manual generate
+const maxGenerateDays = 366
+
+type Handler struct {
+	scheduleService   types.ScheduleService
+	scheduleStore     types.ScheduleStore
+	airportDirectory  types.AirportDirectory
+	aircraftTypeStore types.AircraftTypeStore
+	carrierCode       string
+	defaultDays       int
+}
+
+func NewHandler(scheduleService types.ScheduleService, scheduleStore types.ScheduleStore,
+	airportDirectory types.AirportDirectory, aircraftTypeStore types.AircraftTypeStore, carrierCode string, defaultDays int) *Handler {
+	return &Handler{
+		scheduleService:   scheduleService,
+		scheduleStore:     scheduleStore,
+		airportDirectory:  airportDirectory,
+		aircraftTypeStore: aircraftTypeStore,
+		carrierCode:       carrierCode,
+		defaultDays:       defaultDays,
+	}
+}
+
+func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
+	router.POST("/", h.CreateSchedule)
+	router.GET("/", h.GetSchedules)
+	router.GET("/:id", h.GetScheduleById)
+	router.POST("/:id/generate", h.GenerateFlights)
+}
+
+func (h *Handler) CreateSchedule(ctx *gin.Context) {
+	var createSchedule types.CreateScheduleRequest
+	if err := util.ParseJSON(ctx.Request, &createSchedule); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createSchedule); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	schedule, err := ConvertRequestToSchedule(createSchedule)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if schedule.CarrierCode == "" {
+		schedule.CarrierCode = h.carrierCode
+	}
+	if schedule.AircraftType.Valid {
+		if _, err := h.aircraftTypeStore.GetAircraftTypeByCode(ctx, schedule.AircraftType.String); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+				return
+			}
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	for _, iataCode := range []string{schedule.Departure, schedule.Destination} {
+		if _, err := h.airportDirectory.Location(ctx, iataCode); err != nil {
+			if errors.Is(err, airport.ErrAirportNotFound) {
+				util.WriteError(ctx.Writer, http.StatusNotFound, err)
+				return
+			}
+			util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	result, err := h.scheduleStore.CreateSchedule(ctx, schedule)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, types.ConvertScheduleEntityToResponse(result)), "failed to response json")
+}
+
+func (h *Handler) GetSchedules(ctx *gin.Context) {
+	schedules, err := h.scheduleStore.GetSchedules(ctx)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	result := make([]types.ScheduleResponse, 0, len(schedules))
+	for _, schedule := range schedules {
+		result = append(result, types.ConvertScheduleEntityToResponse(schedule))
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed to response json")
+}
+
+func (h *Handler) GetScheduleById(ctx *gin.Context) {
+	schedule, ok := h.getSchedule(ctx)
+	if !ok {
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertScheduleEntityToResponse(schedule)), "failed to response json")
+}
+
+func (h *Handler) GenerateFlights(ctx *gin.Context) {
+	days := h.defaultDays
+	query := ctx.Request.URL.Query()
+	if query.Has("days") {
+		parsed, err := strconv.Atoi(query.Get("days"))
+		if err != nil || parsed < 0 || parsed > maxGenerateDays {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("days must be between 0 and %d", maxGenerateDays))
+			return
+		}
+		days = parsed
+	}
+	schedule, ok := h.getSchedule(ctx)
+	if !ok {
+		return
+	}
+	created, err := h.scheduleService.GenerateFlights(ctx, schedule, days)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	result := types.GenerateScheduleResponse{ScheduleID: schedule.ID, Created: make([]types.FlightResponse, 0, len(created))}
+	for _, flight := range created {
+		result.Created = append(result.Created, types.ConvertFlightToRespone(flight))
+	}
+	if err := h.airportDirectory.LocalizeFlights(ctx, result.Created); err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, result), "failed to response json")
+}
+
+// getSchedule: load schedule of path id, write error response when failed
+func (h *Handler) getSchedule(ctx *gin.Context) (types.Schedule, bool) {
+	scheduleID := ctx.Param("id")
+	id, err := uuid.Parse(scheduleID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", scheduleID, err))
+		return types.Schedule{}, false
+	}
+	schedule, err := h.scheduleStore.GetScheduleById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, err)
+			return types.Schedule{}, false
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return types.Schedule{}, false
+	}
+	return schedule, true
+}
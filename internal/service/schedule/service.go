@@ -0,0 +1,163 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/service/flight"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// materialize schedules into flights
+type ScheduleService struct {
+	scheduleStore    types.ScheduleStore
+	flightService    types.FlightService
+	airportDirectory types.AirportDirectory
+}
+
+func NewScheduleService(scheduleStore types.ScheduleStore, flightService types.FlightService,
+	airportDirectory types.AirportDirectory) *ScheduleService {
+	return &ScheduleService{
+		scheduleStore:    scheduleStore,
+		flightService:    flightService,
+		airportDirectory: airportDirectory,
+	}
+}
+
+/*
+*
+ConvertRequestToSchedule: parse days, dates and upper case airports of request
+*/
+func ConvertRequestToSchedule(createSchedule types.CreateScheduleRequest) (types.Schedule, error) {
+	schedule := types.Schedule{
+		CarrierCode:   strings.ToUpper(createSchedule.CarrierCode),
+		FlightNumber:  flight.NormalizeFlightNumber(createSchedule.FlightNumber),
+		Departure:     strings.ToUpper(createSchedule.Departure),
+		Destination:   strings.ToUpper(createSchedule.Destination),
+		DepartureTime: createSchedule.DepartureTime,
+		Capacity:      int32(createSchedule.Capacity),
+		Price:         createSchedule.Price,
+	}
+	if createSchedule.AircraftType != "" {
+		schedule.AircraftType.String, schedule.AircraftType.Valid = strings.ToUpper(createSchedule.AircraftType), true
+	}
+	for _, day := range createSchedule.DaysOfWeek {
+		schedule.DaysOfWeek |= 1 << uint(day)
+	}
+	effectiveFrom, err := time.Parse(time.DateOnly, createSchedule.EffectiveFrom)
+	if err != nil {
+		return types.Schedule{}, fmt.Errorf("failed to parse effective_from %w", err)
+	}
+	schedule.EffectiveFrom = effectiveFrom
+	if createSchedule.EffectiveTo != "" {
+		effectiveTo, err := time.Parse(time.DateOnly, createSchedule.EffectiveTo)
+		if err != nil {
+			return types.Schedule{}, fmt.Errorf("failed to parse effective_to %w", err)
+		}
+		if effectiveTo.Before(effectiveFrom) {
+			return types.Schedule{}, fmt.Errorf("effective_to %s before effective_from %s", createSchedule.EffectiveTo, createSchedule.EffectiveFrom)
+		}
+		schedule.EffectiveTo.Time, schedule.EffectiveTo.Valid = effectiveTo, true
+	}
+	if createSchedule.WaitSeats != nil {
+		schedule.WaitSeats.Int32, schedule.WaitSeats.Valid = int32(*createSchedule.WaitSeats), true
+	}
+	if createSchedule.BlockMinutes > 0 {
+		schedule.BlockMinutes.Int32, schedule.BlockMinutes.Valid = int32(createSchedule.BlockMinutes), true
+	}
+	if createSchedule.SeatMapID != "" {
+		if err := schedule.SeatMapID.Scan(createSchedule.SeatMapID); err != nil {
+			return types.Schedule{}, fmt.Errorf("failed to parse seat_map_id %w", err)
+		}
+	}
+	return schedule, nil
+}
+
+/*
+*
+Departures: departure instants of schedule on local dates [from, from+days] at departure airport location
+*/
+func Departures(schedule types.Schedule, location *time.Location, from time.Time, days int) ([]time.Time, error) {
+	departureTime, err := time.Parse("15:04", schedule.DepartureTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid departure_time %s %w", schedule.DepartureTime, err)
+	}
+	localFrom := from.In(location)
+	departures := []time.Time{}
+	for offset := 0; offset <= days; offset++ {
+		date := dateOf(localFrom.AddDate(0, 0, offset))
+		if date.Before(schedule.EffectiveFrom) {
+			continue
+		}
+		if schedule.EffectiveTo.Valid && date.After(schedule.EffectiveTo.Time) {
+			break
+		}
+		if !schedule.OperatesOn(date.Weekday()) {
+			continue
+		}
+		departure := time.Date(date.Year(), date.Month(), date.Day(), departureTime.Hour(), departureTime.Minute(), 0, 0, location)
+		if !departure.After(from) {
+			continue
+		}
+		departures = append(departures, departure.UTC())
+	}
+	return departures, nil
+}
+
+/*
+*
+GenerateFlights: create flights of schedule departing within next days,
+flights already generated for same departure are skipped so it is safe to rerun
+*/
+func (service *ScheduleService) GenerateFlights(ctx context.Context, schedule types.Schedule, days int) ([]types.Flight, error) {
+	location, err := service.airportDirectory.Location(ctx, schedule.Departure)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	departures, err := Departures(schedule, location, now, days)
+	if err != nil {
+		return nil, err
+	}
+	created := []types.Flight{}
+	for _, departure := range departures {
+		createFlight := types.CreateFlightRequest{
+			Price:          schedule.Price,
+			FlightDate:     departure.Unix(),
+			Destination:    schedule.Destination,
+			Departure:      schedule.Departure,
+			AvailableSeats: int64(schedule.Capacity),
+			Capacity:       int64(schedule.Capacity),
+			CarrierCode:    schedule.CarrierCode,
+			FlightNumber:   schedule.FlightNumber,
+			AircraftType:   schedule.AircraftType.String,
+		}
+		createFlight.ScheduleID.UUID, createFlight.ScheduleID.Valid = schedule.ID, true
+		if schedule.WaitSeats.Valid {
+			waitSeats := int64(schedule.WaitSeats.Int32)
+			createFlight.WaitSeats = &waitSeats
+		}
+		if schedule.BlockMinutes.Valid {
+			createFlight.ArrivalDate = departure.Add(time.Duration(schedule.BlockMinutes.Int32) * time.Minute).Unix()
+		}
+		if schedule.SeatMapID.Valid {
+			createFlight.SeatMapID = schedule.SeatMapID.UUID.String()
+		}
+		flightInfo, err := service.flightService.CreateFlight(ctx, createFlight)
+		if err != nil {
+			if errors.Is(err, flight.ErrFlightExists) {
+				continue
+			}
+			return created, fmt.Errorf("failed to generate flight of schedule %s at %s %w", schedule.ID, departure, err)
+		}
+		created = append(created, flightInfo)
+	}
+	generatedUntil := dateOf(now.In(location).AddDate(0, 0, days))
+	if err := service.scheduleStore.UpdateGeneratedUntil(ctx, schedule.ID, generatedUntil); err != nil {
+		return created, err
+	}
+	return created, nil
+}
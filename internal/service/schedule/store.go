@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+const scheduleColumns = "id, carrier_code, flight_number, aircraft_type, departure, destination, days_of_week, departure_time, effective_from, effective_to, capacity, price, wait_seats, seat_map_id, block_minutes, is_active, generated_until, created_at, updated_at"
+
+type ScheduleStore struct {
+	db *sql.DB
+}
+
+func NewScheduleStore(db *sql.DB) *ScheduleStore {
+	return &ScheduleStore{db: db}
+}
+
+type scheduleScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSchedule(scanner scheduleScanner) (types.Schedule, error) {
+	var schedule types.Schedule
+	err := scanner.Scan(
+		&schedule.ID,
+		&schedule.CarrierCode,
+		&schedule.FlightNumber,
+		&schedule.AircraftType,
+		&schedule.Departure,
+		&schedule.Destination,
+		&schedule.DaysOfWeek,
+		&schedule.DepartureTime,
+		&schedule.EffectiveFrom,
+		&schedule.EffectiveTo,
+		&schedule.Capacity,
+		&schedule.Price,
+		&schedule.WaitSeats,
+		&schedule.SeatMapID,
+		&schedule.BlockMinutes,
+		&schedule.IsActive,
+		&schedule.GeneratedUntil,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return types.Schedule{}, err
+	}
+	// date columns compare against utc midnight of local dates
+	schedule.EffectiveFrom = dateOf(schedule.EffectiveFrom)
+	if schedule.EffectiveTo.Valid {
+		schedule.EffectiveTo.Time = dateOf(schedule.EffectiveTo.Time)
+	}
+	if schedule.GeneratedUntil.Valid {
+		schedule.GeneratedUntil.Time = dateOf(schedule.GeneratedUntil.Time)
+	}
+	return schedule, nil
+}
+
+func dateOf(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (scheduleStore *ScheduleStore) querySchedules(ctx context.Context, queryBuilder sq.SelectBuilder) ([]types.Schedule, error) {
+	query, args, err := queryBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := scheduleStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules %w", err)
+	}
+	defer rows.Close()
+	schedules := []types.Schedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan schedule failed %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func (scheduleStore *ScheduleStore) CreateSchedule(ctx context.Context, schedule types.Schedule) (types.Schedule, error) {
+	queryBuilder := sq.Insert("schedules").
+		Columns("id", "carrier_code", "flight_number", "aircraft_type", "departure", "destination", "days_of_week", "departure_time",
+			"effective_from", "effective_to", "capacity", "price", "wait_seats", "seat_map_id", "block_minutes").
+		Values(uuid.New(), schedule.CarrierCode, schedule.FlightNumber, schedule.AircraftType, schedule.Departure, schedule.Destination, schedule.DaysOfWeek,
+			schedule.DepartureTime, schedule.EffectiveFrom, schedule.EffectiveTo, schedule.Capacity, schedule.Price,
+			schedule.WaitSeats, schedule.SeatMapID, schedule.BlockMinutes).
+		Suffix("RETURNING " + scheduleColumns + ";").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Schedule{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	result, err := scanSchedule(scheduleStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return types.Schedule{}, fmt.Errorf("failed to insert schedule %w", err)
+	}
+	return result, nil
+}
+
+/*
+*
+GetScheduleById: return sql.ErrNoRows when schedule not found
+*/
+func (scheduleStore *ScheduleStore) GetScheduleById(ctx context.Context, scheduleID uuid.UUID) (types.Schedule, error) {
+	query, args, err := sq.Select(scheduleColumns).From("schedules").
+		Where(sq.Eq{"id": scheduleID}).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return types.Schedule{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	schedule, err := scanSchedule(scheduleStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Schedule{}, fmt.Errorf("no schedule with id %s %w", scheduleID, err)
+		}
+		return types.Schedule{}, fmt.Errorf("failed to query schedule %w", err)
+	}
+	return schedule, nil
+}
+
+func (scheduleStore *ScheduleStore) GetSchedules(ctx context.Context) ([]types.Schedule, error) {
+	return scheduleStore.querySchedules(ctx, sq.Select(scheduleColumns).From("schedules").
+		OrderBy("flight_number ASC", "effective_from ASC"))
+}
+
+/*
+*
+GetActiveSchedules: active schedules not ended before from
+*/
+func (scheduleStore *ScheduleStore) GetActiveSchedules(ctx context.Context, from time.Time) ([]types.Schedule, error) {
+	return scheduleStore.querySchedules(ctx, sq.Select(scheduleColumns).From("schedules").
+		Where(sq.Eq{"is_active": true}).
+		Where(sq.Or{sq.Eq{"effective_to": nil}, sq.GtOrEq{"effective_to": from}}).
+		OrderBy("flight_number ASC"))
+}
+
+func (scheduleStore *ScheduleStore) UpdateGeneratedUntil(ctx context.Context, scheduleID uuid.UUID, generatedUntil time.Time) error {
+	query, args, err := sq.Update("schedules").Set("generated_until", generatedUntil).Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{"id": scheduleID}).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to create query string %w", err)
+	}
+	if _, err := scheduleStore.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update generated_until %w", err)
+	}
+	return nil
+}
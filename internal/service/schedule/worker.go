@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// generate flights of active schedules ahead of time
+type GenerateWorker struct {
+	scheduleService types.ScheduleService
+	scheduleStore   types.ScheduleStore
+	days            int
+	interval        time.Duration
+}
+
+func NewGenerateWorker(scheduleService types.ScheduleService, scheduleStore types.ScheduleStore,
+	days int, interval time.Duration) *GenerateWorker {
+	return &GenerateWorker{
+		scheduleService: scheduleService,
+		scheduleStore:   scheduleStore,
+		days:            days,
+		interval:        interval,
+	}
+}
+
+func (worker *GenerateWorker) Run(ctx context.Context) error {
+	log.Println("schedule generate worker start")
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("schedule generate worker end")
+			return nil
+		case <-ticker.C:
+			worker.generate(ctx)
+		}
+	}
+}
+
+func (worker *GenerateWorker) generate(ctx context.Context) {
+	schedules, err := worker.scheduleStore.GetActiveSchedules(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("failed to get active schedules %v", err)
+		return
+	}
+	for _, schedule := range schedules {
+		created, err := worker.scheduleService.GenerateFlights(ctx, schedule, worker.days)
+		if err != nil {
+			log.Printf("failed to generate flights of schedule %s %v", schedule.ID, err)
+		}
+		if len(created) > 0 {
+			log.Printf("schedule %s %s generated %d flights", schedule.ID, schedule.FlightNumber, len(created))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package seatmap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+type CacheStore struct {
+	rdb *redis.Client
+}
+
+func NewCacheStore(rdb *redis.Client) *CacheStore {
+	return &CacheStore{
+		rdb: rdb,
+	}
+}
+
+/*
+*
+AssignSeats: assign all seats for passengers atomically, reject when any seat owned by others
+*/
+func (cache *CacheStore) AssignSeats(ctx context.Context, assignParam types.SeatCacheAssignParam) (types.SeatCacheAssignResult, error) {
+	args := make([]interface{}, 0, len(assignParam.Assignments)*2)
+	for _, assignment := range assignParam.Assignments {
+		args = append(args, assignment.PassengerID.String(), assignment.SeatNumber)
+	}
+	result := AssignSeatsWithFlightID.Run(ctx, cache.rdb, []string{assignParam.FlightID}, args...)
+	resultList, err := result.Slice()
+	if err != nil {
+		return types.SeatCacheAssignResult{}, fmt.Errorf("failed to assign seats with flightId: %s, %w", assignParam.FlightID, err)
+	}
+	isValid, _ := resultList[0].(int64)
+	conflictSeat, _ := resultList[1].(string)
+	return types.SeatCacheAssignResult{
+		IsValid:      isValid == 1,
+		ConflictSeat: conflictSeat,
+	}, nil
+}
+
+/*
+*
+RestoreSeats: put passengers back to seats they held before AssignSeats, empty seat_number frees seat of passenger,
+used when assignment could not be saved in database
+*/
+func (cache *CacheStore) RestoreSeats(ctx context.Context, restoreParam types.SeatCacheAssignParam) error {
+	args := make([]interface{}, 0, len(restoreParam.Assignments)*2)
+	for _, assignment := range restoreParam.Assignments {
+		args = append(args, assignment.PassengerID.String(), assignment.SeatNumber)
+	}
+	err := RestoreSeatsWithFlightID.Run(ctx, cache.rdb, []string{restoreParam.FlightID}, args...).Err()
+	if err != nil {
+		return fmt.Errorf("failed to restore seats with flightId: %s, %w", restoreParam.FlightID, err)
+	}
+	return nil
+}
+
+/*
+*
+GetOccupiedSeats: get seat_number -> passenger_id for flight_id
+*/
+func (cache *CacheStore) GetOccupiedSeats(ctx context.Context, flightID string) (map[string]string, error) {
+	result, err := cache.rdb.HGetAll(ctx, fmt.Sprintf("%s:seats", flightID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get occupied seats with flightId: %s, %w", flightID, err)
+	}
+	return result, nil
+}
+
+/*
+*
+AssignSeatsWithFlightID: luascript for assign seats on specific flight_id
+input key: flight_id, arguments: pairs of passenger_id, seat_number
+return {is_valid, conflict_seat}
+*
+*/
+var AssignSeatsWithFlightID = redis.NewScript(`
+local seats_key = KEYS[1]..":seats"
+local passenger_seats_key = KEYS[1]..":passenger_seats"
+for i = 1, #ARGV, 2 do
+	local owner = redis.call("HGET", seats_key, ARGV[i+1])
+	if owner and owner ~= ARGV[i] then
+		return {0, ARGV[i+1]}
+	end
+end
+for i = 1, #ARGV, 2 do
+	local previous = redis.call("HGET", passenger_seats_key, ARGV[i])
+	if previous and previous ~= ARGV[i+1] then
+		redis.call("HDEL", seats_key, previous)
+	end
+	redis.call("HSET", seats_key, ARGV[i+1], ARGV[i])
+	redis.call("HSET", passenger_seats_key, ARGV[i], ARGV[i+1])
+end
+return {1, ""}
+`)
+
+/*
+*
+RestoreSeatsWithFlightID: luascript for restoring seats of passengers on specific flight_id,
+seats taken by other passengers meanwhile are left to them
+input key: flight_id, arguments: pairs of passenger_id, previous seat_number or empty string
+return 1
+*
+*/
+var RestoreSeatsWithFlightID = redis.NewScript(`
+local seats_key = KEYS[1]..":seats"
+local passenger_seats_key = KEYS[1]..":passenger_seats"
+for i = 1, #ARGV, 2 do
+	local current = redis.call("HGET", passenger_seats_key, ARGV[i])
+	if current and current ~= ARGV[i+1] and redis.call("HGET", seats_key, current) == ARGV[i] then
+		redis.call("HDEL", seats_key, current)
+	end
+	if ARGV[i+1] == "" then
+		redis.call("HDEL", passenger_seats_key, ARGV[i])
+	else
+		local owner = redis.call("HGET", seats_key, ARGV[i+1])
+		if not owner or owner == ARGV[i] then
+			redis.call("HSET", seats_key, ARGV[i+1], ARGV[i])
+			redis.call("HSET", passenger_seats_key, ARGV[i], ARGV[i+1])
+		else
+			redis.call("HDEL", passenger_seats_key, ARGV[i])
+		end
+	end
+end
+return 1
+`)
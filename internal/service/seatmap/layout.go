@@ -0,0 +1,60 @@
+package seatmap
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+func ConvertRequestToLayout(createParams types.CreateSeatMapTemplateRequest) types.SeatMapLayout {
+	layout := types.SeatMapLayout{
+		Cabins:       make([]types.CabinLayout, 0, len(createParams.Cabins)),
+		ExitRows:     createParams.ExitRows,
+		BlockedSeats: createParams.BlockedSeats,
+	}
+	for _, cabin := range createParams.Cabins {
+		layout.Cabins = append(layout.Cabins, types.CabinLayout{
+			Cabin:   cabin.Cabin,
+			FromRow: cabin.FromRow,
+			ToRow:   cabin.ToRow,
+			Letters: cabin.Letters,
+		})
+	}
+	return layout
+}
+
+// ValidateLayout: cabins must not overlap, exit rows and blocked seats must exist in layout
+func ValidateLayout(layout types.SeatMapLayout) error {
+	rows := map[int32]string{}
+	for _, cabin := range layout.Cabins {
+		letters := map[rune]bool{}
+		for _, letter := range cabin.Letters {
+			if letters[letter] {
+				return fmt.Errorf("cabin %s has duplicate seat letter %c", cabin.Cabin, letter)
+			}
+			letters[letter] = true
+		}
+		for row := cabin.FromRow; row <= cabin.ToRow; row++ {
+			if other, ok := rows[row]; ok {
+				return fmt.Errorf("row %d is in both cabin %s and %s", row, other, cabin.Cabin)
+			}
+			rows[row] = cabin.Cabin
+		}
+	}
+	for _, row := range layout.ExitRows {
+		if _, ok := rows[row]; !ok {
+			return fmt.Errorf("exit row %d not in any cabin", row)
+		}
+	}
+	seats := map[string]bool{}
+	for _, seat := range layout.Seats(uuid.Nil) {
+		seats[seat.SeatNumber] = true
+	}
+	for _, seatNumber := range layout.BlockedSeats {
+		if !seats[seatNumber] {
+			return fmt.Errorf("blocked seat %s not in layout", seatNumber)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package seatmap
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	seatMapStore   types.SeatMapStore
+	seatCacheStore types.SeatCacheStore
+	orderStore     types.OrderStore
+}
+
+func NewHandler(seatMapStore types.SeatMapStore, seatCacheStore types.SeatCacheStore, orderStore types.OrderStore) *Handler {
+	return &Handler{
+		seatMapStore:   seatMapStore,
+		seatCacheStore: seatCacheStore,
+		orderStore:     orderStore,
+	}
+}
+
+func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
+	router.POST("/", h.CreateTemplate)
+	router.GET("/:id", h.GetTemplateById)
+}
+
+func (h *Handler) RegisterFlightRoute(router *gin.RouterGroup) {
+	router.GET("/:id/seatmap", h.GetFlightSeatMap)
+}
+
+func (h *Handler) RegisterOrderRoute(router *gin.RouterGroup) {
+	router.POST("/:id/seats", h.AssignOrderSeats)
+}
+
+func (h *Handler) CreateTemplate(ctx *gin.Context) {
+	var createTemplate types.CreateSeatMapTemplateRequest
+	if err := util.ParseJSON(ctx.Request, &createTemplate); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(createTemplate); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	if err := ValidateLayout(ConvertRequestToLayout(createTemplate)); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", err))
+		return
+	}
+	template, err := h.seatMapStore.CreateTemplate(ctx, createTemplate)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusCreated, template), "failed to response json")
+}
+
+func (h *Handler) GetTemplateById(ctx *gin.Context) {
+	templateID := ctx.Param("id")
+	id, err := uuid.Parse(templateID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", templateID, err))
+		return
+	}
+	template, err := h.seatMapStore.GetTemplateById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("seat map template %s not found", templateID))
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, template), "failed to response json")
+}
+
+func (h *Handler) GetFlightSeatMap(ctx *gin.Context) {
+	flightID := ctx.Param("id")
+	id, err := uuid.Parse(flightID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", flightID, err))
+		return
+	}
+	seats, err := h.seatMapStore.GetFlightSeats(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	if len(seats) == 0 {
+		util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("flight %s has no seat map", flightID))
+		return
+	}
+	occupied, err := h.seatCacheStore.GetOccupiedSeats(ctx, id.String())
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK,
+		types.ConvertFlightSeatsToSeatMapResponse(id.String(), seats, occupied)), "failed to response json")
+}
+
+func (h *Handler) AssignOrderSeats(ctx *gin.Context) {
+	orderID := ctx.Param("id")
+	id, err := uuid.Parse(orderID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", orderID, err))
+		return
+	}
+	var assignSeats types.AssignSeatsRequest
+	if err := util.ParseJSON(ctx.Request, &assignSeats); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(assignSeats); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	order, err := h.orderStore.GetOrderById(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get order %w", err))
+		return
+	}
+	if order.ID == uuid.Nil {
+		util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("order %s not found", orderID))
+		return
+	}
+	if order.CanceledAt.Valid {
+		util.WriteError(ctx.Writer, http.StatusConflict, fmt.Errorf("order %s is canceled", orderID))
+		return
+	}
+	if order.IsWaitlisted() {
+		util.WriteError(ctx.Writer, http.StatusConflict, fmt.Errorf("order %s is waitlisted, seats available after promoted", orderID))
+		return
+	}
+	passengers, err := h.orderStore.GetPassengersByOrderID(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get passengers %w", err))
+		return
+	}
+	seats, err := h.seatMapStore.GetFlightSeats(ctx, order.FlightID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	assignments, err := buildAssignments(assignSeats, passengers, seats)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", err))
+		return
+	}
+	result, err := h.seatCacheStore.AssignSeats(ctx, types.SeatCacheAssignParam{
+		FlightID:    order.FlightID.String(),
+		Assignments: assignments,
+	})
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("could not assign seats in cachestore: %w", err))
+		return
+	}
+	if !result.IsValid {
+		util.WriteError(ctx.Writer, http.StatusConflict, fmt.Errorf("seat %s already taken", result.ConflictSeat))
+		return
+	}
+	if _, err := h.orderStore.UpdatePassengerSeats(ctx, assignments); err != nil {
+		restoreSeats(ctx, h.seatCacheStore, order.FlightID.String(), assignments, passengers)
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to update passenger seats %w", err))
+		return
+	}
+	passengers, err = h.orderStore.GetPassengersByOrderID(ctx, id)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get passengers %w", err))
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertOrderEntityToResponse(order, passengers)), "failed to response json")
+}
+
+// restoreSeats: give seats locked by AssignSeats back when they could not be saved, passengers keep seats stored in database
+func restoreSeats(ctx context.Context, seatCacheStore types.SeatCacheStore, flightID string,
+	assignments []types.PassengerSeatParam, passengers []types.Passenger) {
+	previousSeats := make(map[uuid.UUID]string, len(passengers))
+	for _, passenger := range passengers {
+		previousSeats[passenger.ID] = passenger.SeatNumber.String
+	}
+	restores := make([]types.PassengerSeatParam, 0, len(assignments))
+	for _, assignment := range assignments {
+		restores = append(restores, types.PassengerSeatParam{
+			PassengerID: assignment.PassengerID,
+			SeatNumber:  previousSeats[assignment.PassengerID],
+		})
+	}
+	if err := seatCacheStore.RestoreSeats(ctx, types.SeatCacheAssignParam{
+		FlightID:    flightID,
+		Assignments: restores,
+	}); err != nil {
+		log.Printf("failed to restore seats of flight %s %v", flightID, err)
+	}
+}
+
+// buildAssignments: passenger must belong to order, seat must exist and not blocked
+func buildAssignments(assignSeats types.AssignSeatsRequest, passengers []types.Passenger,
+	seats []types.FlightSeat) ([]types.PassengerSeatParam, error) {
+	if len(seats) == 0 {
+		return nil, fmt.Errorf("flight has no seat map")
+	}
+	orderPassengers := make(map[uuid.UUID]bool, len(passengers))
+	for _, passenger := range passengers {
+		orderPassengers[passenger.ID] = true
+	}
+	flightSeats := make(map[string]types.FlightSeat, len(seats))
+	for _, seat := range seats {
+		flightSeats[seat.SeatNumber] = seat
+	}
+	requestedPassengers := map[uuid.UUID]bool{}
+	requestedSeats := map[string]bool{}
+	assignments := make([]types.PassengerSeatParam, 0, len(assignSeats.Seats))
+	for _, assignment := range assignSeats.Seats {
+		passengerID := uuid.MustParse(assignment.PassengerID)
+		seatNumber := strings.ToUpper(assignment.SeatNumber)
+		if !orderPassengers[passengerID] {
+			return nil, fmt.Errorf("passenger %s not in order", passengerID)
+		}
+		seat, ok := flightSeats[seatNumber]
+		if !ok {
+			return nil, fmt.Errorf("seat %s not in seat map", seatNumber)
+		}
+		if seat.IsBlocked {
+			return nil, fmt.Errorf("seat %s is blocked", seatNumber)
+		}
+		if requestedPassengers[passengerID] || requestedSeats[seatNumber] {
+			return nil, fmt.Errorf("duplicate assignment for passenger %s or seat %s", passengerID, seatNumber)
+		}
+		requestedPassengers[passengerID] = true
+		requestedSeats[seatNumber] = true
+		assignments = append(assignments, types.PassengerSeatParam{
+			PassengerID: passengerID,
+			SeatNumber:  seatNumber,
+		})
+	}
+	return assignments, nil
+}
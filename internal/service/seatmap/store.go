@@ -0,0 +1,119 @@
+package seatmap
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+type SeatMapStore struct {
+	db *sql.DB
+}
+
+func NewSeatMapStore(db *sql.DB) *SeatMapStore {
+	return &SeatMapStore{db: db}
+}
+
+func (seatMapStore *SeatMapStore) CreateTemplate(ctx context.Context, createParams types.CreateSeatMapTemplateRequest) (types.SeatMapTemplate, error) {
+	layout := ConvertRequestToLayout(createParams)
+	layoutData, err := json.Marshal(layout)
+	if err != nil {
+		return types.SeatMapTemplate{}, fmt.Errorf("marshal seat map layout err %w", err)
+	}
+	queryBuilder := sq.Insert("seat_map_templates").Columns("id", "name", "layout").
+		Values(uuid.New(), createParams.Name, layoutData).
+		Suffix("RETURNING id, name, layout, created_at;").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.SeatMapTemplate{}, fmt.Errorf("create seat map template query builder failed %w", err)
+	}
+	return scanTemplate(seatMapStore.db.QueryRowContext(ctx, query, args...))
+}
+
+func (seatMapStore *SeatMapStore) GetTemplateById(ctx context.Context, templateID uuid.UUID) (types.SeatMapTemplate, error) {
+	queryBuilder := sq.Select("id", "name", "layout", "created_at").From("seat_map_templates").
+		Where(sq.Eq{"id": templateID}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.SeatMapTemplate{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	template, err := scanTemplate(seatMapStore.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.SeatMapTemplate{}, fmt.Errorf("no seat map template with id %s %w", templateID, err)
+		}
+		return types.SeatMapTemplate{}, err
+	}
+	return template, nil
+}
+
+func (seatMapStore *SeatMapStore) CreateFlightSeats(ctx context.Context, seats []types.FlightSeat) error {
+	if len(seats) == 0 {
+		return nil
+	}
+	queryBuilder := sq.Insert("flight_seats").Columns("flight_id", "seat_number", "row_number", "letter", "cabin", "is_exit_row", "is_blocked")
+	for _, seat := range seats {
+		queryBuilder = queryBuilder.Values(seat.FlightID, seat.SeatNumber, seat.RowNumber, seat.Letter, seat.Cabin, seat.IsExitRow, seat.IsBlocked)
+	}
+	queryBuilder = queryBuilder.Suffix("ON CONFLICT (flight_id, seat_number) DO NOTHING;").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return fmt.Errorf("create flight seats query builder failed %w", err)
+	}
+	_, err = seatMapStore.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("insert flight seats failed %w", err)
+	}
+	return nil
+}
+
+func (seatMapStore *SeatMapStore) GetFlightSeats(ctx context.Context, flightID uuid.UUID) ([]types.FlightSeat, error) {
+	queryBuilder := sq.Select("flight_id", "seat_number", "row_number", "letter", "cabin", "is_exit_row", "is_blocked").
+		From("flight_seats").Where(sq.Eq{"flight_id": flightID}).OrderBy("row_number ASC", "letter ASC").PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query string %w", err)
+	}
+	rows, err := seatMapStore.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flight seats %w", err)
+	}
+	defer rows.Close()
+	seats := []types.FlightSeat{}
+	for rows.Next() {
+		var seat types.FlightSeat
+		err := rows.Scan(
+			&seat.FlightID,
+			&seat.SeatNumber,
+			&seat.RowNumber,
+			&seat.Letter,
+			&seat.Cabin,
+			&seat.IsExitRow,
+			&seat.IsBlocked,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan flight seat failed %w", err)
+		}
+		seats = append(seats, seat)
+	}
+	return seats, rows.Err()
+}
+
+func scanTemplate(row *sql.Row) (types.SeatMapTemplate, error) {
+	var template types.SeatMapTemplate
+	var layoutData []byte
+	err := row.Scan(&template.ID, &template.Name, &layoutData, &template.CreatedAt)
+	if err != nil {
+		return types.SeatMapTemplate{}, fmt.Errorf("scan seat map template failed %w", err)
+	}
+	if err := json.Unmarshal(layoutData, &template.Layout); err != nil {
+		return types.SeatMapTemplate{}, fmt.Errorf("unmarshal seat map layout err %w", err)
+	}
+	return template, nil
+}
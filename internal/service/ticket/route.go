@@ -0,0 +1,44 @@
+package ticket
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	ticketStore types.TicketStore
+}
+
+func NewHandler(ticketStore types.TicketStore) *Handler {
+	return &Handler{
+		ticketStore: ticketStore,
+	}
+}
+
+func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
+	router.GET("/:number", h.GetTicketByNumber)
+}
+
+func (h *Handler) GetTicketByNumber(ctx *gin.Context) {
+	ticketNumber := ctx.Param("number")
+	if err := ValidateTicketNumber(ticketNumber); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.ticketStore.GetTicketByNumber(ctx, ticketNumber)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("ticket %s not found", ticketNumber))
+			return
+		}
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, fmt.Errorf("failed to get ticket %w", err))
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertTicketEntityToResponse(result)), "failed to response json")
+}
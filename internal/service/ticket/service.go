@@ -0,0 +1,115 @@
+package ticket
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+const (
+	// TicketNumberLength: airline prefix(3) + serial(9) + check digit(1)
+	TicketNumberLength  = 13
+	airlinePrefixLength = 3
+	maxSerial           = 999999999
+)
+
+// handle e-ticket issuance
+type TicketService struct {
+	ticketStore   types.TicketStore
+	airlinePrefix string
+}
+
+func NewTicketService(ticketStore types.TicketStore, airlinePrefix string) *TicketService {
+	return &TicketService{
+		ticketStore:   ticketStore,
+		airlinePrefix: airlinePrefix,
+	}
+}
+
+/*
+*
+IssueTickets: issue e-ticket numbers for passengers without one, must run inside payment tx
+*/
+func (ticketService *TicketService) IssueTickets(tx *sql.Tx, ctx context.Context, order types.Order,
+	passengers []types.Passenger) ([]types.Passenger, error) {
+	pending := make([]int, 0, len(passengers))
+	for idx, passenger := range passengers {
+		if !passenger.TicketNumber.Valid {
+			pending = append(pending, idx)
+		}
+	}
+	result := make([]types.Passenger, len(passengers))
+	copy(result, passengers)
+	if len(pending) == 0 {
+		return result, nil
+	}
+	lastSerial, err := ticketService.ticketStore.NextSerials(tx, ctx, ticketService.airlinePrefix, int64(len(pending)))
+	if err != nil {
+		return nil, err
+	}
+	serial := lastSerial - int64(len(pending)) + 1
+	for _, idx := range pending {
+		ticketNumber, err := FormatTicketNumber(ticketService.airlinePrefix, serial)
+		if err != nil {
+			return nil, err
+		}
+		passenger, err := ticketService.ticketStore.IssueTicket(tx, ctx, passengers[idx].ID, ticketNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue ticket for order %s %w", order.ID, err)
+		}
+		result[idx] = passenger
+		serial++
+	}
+	return result, nil
+}
+
+// FormatTicketNumber: airline prefix + zero padded serial + mod 7 check digit
+func FormatTicketNumber(airlinePrefix string, serial int64) (string, error) {
+	if len(airlinePrefix) != airlinePrefixLength || !isDigits(airlinePrefix) {
+		return "", fmt.Errorf("airline prefix %s must be %d digits", airlinePrefix, airlinePrefixLength)
+	}
+	if serial <= 0 || serial > maxSerial {
+		return "", fmt.Errorf("ticket serial %d out of range", serial)
+	}
+	body := fmt.Sprintf("%s%09d", airlinePrefix, serial)
+	checkDigit, err := checkDigitOf(body)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d", body, checkDigit), nil
+}
+
+// ValidateTicketNumber: verify length, digits and check digit
+func ValidateTicketNumber(ticketNumber string) error {
+	if len(ticketNumber) != TicketNumberLength || !isDigits(ticketNumber) {
+		return fmt.Errorf("ticket number %s must be %d digits", ticketNumber, TicketNumberLength)
+	}
+	checkDigit, err := checkDigitOf(ticketNumber[:TicketNumberLength-1])
+	if err != nil {
+		return err
+	}
+	if int64(ticketNumber[TicketNumberLength-1]-'0') != checkDigit {
+		return fmt.Errorf("ticket number %s has invalid check digit", ticketNumber)
+	}
+	return nil
+}
+
+func checkDigitOf(body string) (int64, error) {
+	value, err := strconv.ParseInt(body, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ticket number %s %w", body, err)
+	}
+	return value % 7, nil
+}
+
+func isDigits(value string) bool {
+	for _, ch := range value {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
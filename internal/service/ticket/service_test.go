@@ -0,0 +1,70 @@
+package ticket
+
+import "testing"
+
+func TestFormatTicketNumber(t *testing.T) {
+	tests := []struct {
+		name          string
+		airlinePrefix string
+		serial        int64
+		want          string
+		wantErr       bool
+	}{
+		{name: "first serial", airlinePrefix: "160", serial: 1, want: "1600000000012"},
+		{name: "check digit zero", airlinePrefix: "176", serial: 123456789, want: "1761234567890"},
+		{name: "leading zero prefix", airlinePrefix: "001", serial: 6, want: "0010000000065"},
+		{name: "max serial", airlinePrefix: "999", serial: 999999999, want: "9999999999990"},
+		{name: "zero serial", airlinePrefix: "160", serial: 0, wantErr: true},
+		{name: "serial over nine digits", airlinePrefix: "160", serial: 1000000000, wantErr: true},
+		{name: "short prefix", airlinePrefix: "16", serial: 1, wantErr: true},
+		{name: "letter prefix", airlinePrefix: "CX1", serial: 1, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := FormatTicketNumber(test.airlinePrefix, test.serial)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("FormatTicketNumber(%q, %d) error = %v, wantErr %v", test.airlinePrefix, test.serial, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("FormatTicketNumber(%q, %d) = %q, want %q", test.airlinePrefix, test.serial, got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateTicketNumber(t *testing.T) {
+	tests := []struct {
+		name         string
+		ticketNumber string
+		wantErr      bool
+	}{
+		{name: "valid", ticketNumber: "1600000000012"},
+		{name: "valid check digit zero", ticketNumber: "1761234567890"},
+		{name: "wrong check digit", ticketNumber: "1600000000013", wantErr: true},
+		{name: "check digit out of mod 7 range", ticketNumber: "1600000000019", wantErr: true},
+		{name: "too short", ticketNumber: "160000000001", wantErr: true},
+		{name: "too long", ticketNumber: "16000000000120", wantErr: true},
+		{name: "not digits", ticketNumber: "16000000000A2", wantErr: true},
+		{name: "empty", ticketNumber: "", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateTicketNumber(test.ticketNumber)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateTicketNumber(%q) error = %v, wantErr %v", test.ticketNumber, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormattedTicketNumberValidates(t *testing.T) {
+	for serial := int64(1); serial <= 50; serial++ {
+		ticketNumber, err := FormatTicketNumber("160", serial)
+		if err != nil {
+			t.Fatalf("FormatTicketNumber(160, %d) error = %v", serial, err)
+		}
+		if err := ValidateTicketNumber(ticketNumber); err != nil {
+			t.Errorf("ValidateTicketNumber(%q) error = %v", ticketNumber, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ticket
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+type TicketStore struct {
+	db *sql.DB
+}
+
+func NewTicketStore(db *sql.DB) *TicketStore {
+	return &TicketStore{db: db}
+}
+
+/*
+*
+NextSerials: reserve count serials for airline_prefix, return the last reserved serial
+the sequence row stays locked until tx finished, so serials are gap-free
+*/
+func (ticketStore *TicketStore) NextSerials(tx *sql.Tx, ctx context.Context, airlinePrefix string, count int64) (int64, error) {
+	queryBuilder := sq.Insert("ticket_sequences").Columns("airline_prefix", "last_serial").Values(airlinePrefix, count).
+		Suffix("ON CONFLICT (airline_prefix) DO UPDATE SET last_serial = ticket_sequences.last_serial + EXCLUDED.last_serial, updated_at = now() RETURNING last_serial;").
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("next serials query builder failed %w", err)
+	}
+	var lastSerial int64
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&lastSerial)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve serials for %s %w", airlinePrefix, err)
+	}
+	return lastSerial, nil
+}
+
+func (ticketStore *TicketStore) IssueTicket(tx *sql.Tx, ctx context.Context, passengerID uuid.UUID, ticketNumber string) (types.Passenger, error) {
+	queryBuilder := sq.Update("passengers").Set("ticket_number", ticketNumber).Set("ticket_issued_at", time.Now().UTC()).
+		Where(sq.Eq{"id": passengerID, "ticket_number": nil}).
+		Suffix("RETURNING id, order_id, name, date_of_birth, document_number, ticket_number, ticket_issued_at, seat_number, checked_in_at, checkin_sequence, volunteered_at, created_at;").
+		PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Passenger{}, fmt.Errorf("issue ticket query builder failed %w", err)
+	}
+	var passenger types.Passenger
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&passenger.ID,
+		&passenger.OrderID,
+		&passenger.Name,
+		&passenger.DateOfBirth,
+		&passenger.DocumentNumber,
+		&passenger.TicketNumber,
+		&passenger.TicketIssuedAt,
+		&passenger.SeatNumber,
+		&passenger.CheckedInAt,
+		&passenger.CheckinSequence,
+		&passenger.VolunteeredAt,
+		&passenger.CreatedAt,
+	)
+	if err != nil {
+		return types.Passenger{}, fmt.Errorf("failed to issue ticket %s for passenger %s %w", ticketNumber, passengerID, err)
+	}
+	return passenger, nil
+}
+
+/*
+*
+GetTicketByNumber: ticket with flight of every coupon, segments of multi-segment order in travel order
+*/
+func (ticketStore *TicketStore) GetTicketByNumber(ctx context.Context, ticketNumber string) (types.Ticket, error) {
+	queryBuilder := sq.Select("p.ticket_number", "p.ticket_issued_at", "p.id", "p.name", "o.id", "o.flight_id", "COALESCE(o.pnr, '')",
+		"ARRAY(SELECT s.flight_id FROM order_segments s WHERE s.order_id = o.id ORDER BY s.segment_sequence)").
+		From("passengers p").Join("orders o ON o.id = p.order_id").
+		Where(sq.Eq{"p.ticket_number": ticketNumber}).PlaceholderFormat(sq.Dollar)
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return types.Ticket{}, fmt.Errorf("failed to create query string %w", err)
+	}
+	var ticket types.Ticket
+	segmentFlightIDs := []string{}
+	err = ticketStore.db.QueryRowContext(ctx, query, args...).Scan(
+		&ticket.TicketNumber,
+		&ticket.IssuedAt,
+		&ticket.PassengerID,
+		&ticket.PassengerName,
+		&ticket.OrderID,
+		&ticket.FlightID,
+		&ticket.PNR,
+		pq.Array(&segmentFlightIDs),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Ticket{}, fmt.Errorf("no ticket with number %s %w", ticketNumber, err)
+		}
+		return types.Ticket{}, fmt.Errorf("failed to query ticket %w", err)
+	}
+	ticket.FlightIDs = []uuid.UUID{ticket.FlightID}
+	if len(segmentFlightIDs) > 0 {
+		ticket.FlightIDs = make([]uuid.UUID, 0, len(segmentFlightIDs))
+		for _, segmentFlightID := range segmentFlightIDs {
+			flightID, err := uuid.Parse(segmentFlightID)
+			if err != nil {
+				return types.Ticket{}, fmt.Errorf("failed to parse segment flight %s of ticket %s %w", segmentFlightID, ticketNumber, err)
+			}
+			ticket.FlightIDs = append(ticket.FlightIDs, flightID)
+		}
+	}
+	return ticket, nil
+}
@@ -0,0 +1,315 @@
+package waitingroom
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// roomsKey: set of flights with open waiting room, walked by gatekeeper
+const roomsKey = "waiting_rooms"
+
+type CacheStore struct {
+	rdb *redis.Client
+}
+
+func NewCacheStore(rdb *redis.Client) *CacheStore {
+	return &CacheStore{
+		rdb: rdb,
+	}
+}
+
+// roomKey: hash of waiting room of flight_id, hash tag keeps keys of one room in one slot for scripts
+func roomKey(flightID string) string {
+	return fmt.Sprintf("waiting_room:{%s}", flightID)
+}
+
+// queueKey: sorted set of waiting tokens by arrival sequence
+func queueKey(flightID string) string {
+	return roomKey(flightID) + ":queue"
+}
+
+// admittedKey: sorted set of admitted tokens by admitted until in unix ms
+func admittedKey(flightID string) string {
+	return roomKey(flightID) + ":admitted"
+}
+
+// holdersKey: hash of last token joined by each customer or ip
+func holdersKey(flightID string) string {
+	return roomKey(flightID) + ":holders"
+}
+
+// statusOfCode: waiting room status of script result code
+func statusOfCode(code int64) string {
+	switch code {
+	case -1:
+		return types.WaitingRoomStatusClosed
+	case 1:
+		return types.WaitingRoomStatusWaiting
+	case 2:
+		return types.WaitingRoomStatusAdmitted
+	default:
+		return types.WaitingRoomStatusExpired
+	}
+}
+
+/*
+*
+OpenRoom: open waiting room of flight or change admission rate of open room, queued tokens are kept
+*/
+func (cache *CacheStore) OpenRoom(ctx context.Context, roomParam types.WaitingRoomParam) error {
+	key := roomKey(roomParam.FlightID)
+	tx := cache.rdb.TxPipeline()
+	tx.HSet(ctx, key, "admit_per_second", roomParam.AdmitPerSecond, "admission_ms", roomParam.AdmissionTTL.Milliseconds())
+	tx.HSetNX(ctx, key, "seq", 0)
+	tx.ExpireAt(ctx, key, roomParam.ExpireAt)
+	tx.SAdd(ctx, roomsKey, roomParam.FlightID)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to open waiting room of flight %s %w", roomParam.FlightID, err)
+	}
+	return nil
+}
+
+// CloseRoom: drop waiting room with its queue, orders of flight are not gated anymore
+func (cache *CacheStore) CloseRoom(ctx context.Context, flightID string) error {
+	key := roomKey(flightID)
+	tx := cache.rdb.TxPipeline()
+	tx.Del(ctx, key, queueKey(flightID), admittedKey(flightID), holdersKey(flightID))
+	tx.SRem(ctx, roomsKey, flightID)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to close waiting room of flight %s %w", flightID, err)
+	}
+	return nil
+}
+
+func (cache *CacheStore) GetRoomFlightIDs(ctx context.Context) ([]string, error) {
+	flightIDs, err := cache.rdb.SMembers(ctx, roomsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waiting rooms %w", err)
+	}
+	return flightIDs, nil
+}
+
+/*
+*
+Join: queue token of holder in waiting room of flight, a holder keeps one active token per room,
+return token of holder with its ticket, WaitingRoomStatusClosed when flight has no waiting room
+*/
+func (cache *CacheStore) Join(ctx context.Context, flightID string, holder string, token string) (string, types.WaitingRoomTicket, error) {
+	result, err := JoinWaitingRoom.Run(ctx, cache.rdb,
+		[]string{roomKey(flightID), queueKey(flightID), admittedKey(flightID), holdersKey(flightID)}, token, holder).Slice()
+	if err != nil {
+		return "", types.WaitingRoomTicket{}, fmt.Errorf("failed to join waiting room of flight %s %w", flightID, err)
+	}
+	if len(result) != 4 {
+		return "", types.WaitingRoomTicket{}, fmt.Errorf("unexpected join result of flight %s %v", flightID, result)
+	}
+	code, _ := result[0].(int64)
+	position, _ := result[1].(int64)
+	admittedUntil, _ := result[2].(int64)
+	holderToken, _ := result[3].(string)
+	return holderToken, types.WaitingRoomTicket{
+		Status:        statusOfCode(code),
+		Position:      position,
+		AdmittedUntil: time.UnixMilli(admittedUntil),
+	}, nil
+}
+
+func (cache *CacheStore) GetTicket(ctx context.Context, flightID string, token string) (types.WaitingRoomTicket, error) {
+	resultList, err := GetWaitingRoomTicket.Run(ctx, cache.rdb,
+		[]string{roomKey(flightID), queueKey(flightID), admittedKey(flightID)}, token).Int64Slice()
+	if err != nil {
+		return types.WaitingRoomTicket{}, fmt.Errorf("failed to get waiting room ticket of flight %s %w", flightID, err)
+	}
+	return types.WaitingRoomTicket{
+		Status:        statusOfCode(resultList[0]),
+		Position:      resultList[1],
+		AdmittedUntil: time.UnixMilli(resultList[2]),
+	}, nil
+}
+
+/*
+*
+Admit: admit queued tokens in arrival order at admit_per_second of room since last admission,
+return tokens admitted, -1 when room expired with departure of flight and is dropped from rooms
+*/
+func (cache *CacheStore) Admit(ctx context.Context, flightID string) (int64, error) {
+	admitted, err := AdmitFromWaitingRoom.Run(ctx, cache.rdb,
+		[]string{roomKey(flightID), queueKey(flightID), admittedKey(flightID)}).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to admit waiting room of flight %s %w", flightID, err)
+	}
+	if admitted < 0 {
+		if err := cache.rdb.SRem(ctx, roomsKey, flightID).Err(); err != nil {
+			return 0, fmt.Errorf("failed to drop waiting room of flight %s %w", flightID, err)
+		}
+	}
+	return admitted, nil
+}
+
+/*
+*
+ConsumeAdmission: use admitted token once, return ticket with WaitingRoomStatusAdmitted and AdmittedUntil when token is consumed,
+WaitingRoomStatusClosed when flight has no waiting room, WaitingRoomStatusExpired otherwise
+*/
+func (cache *CacheStore) ConsumeAdmission(ctx context.Context, flightID string, token string) (types.WaitingRoomTicket, error) {
+	resultList, err := ConsumeWaitingRoomAdmission.Run(ctx, cache.rdb,
+		[]string{roomKey(flightID), admittedKey(flightID)}, token).Int64Slice()
+	if err != nil {
+		return types.WaitingRoomTicket{}, fmt.Errorf("failed to consume admission of flight %s %w", flightID, err)
+	}
+	return types.WaitingRoomTicket{
+		Status:        statusOfCode(resultList[0]),
+		AdmittedUntil: time.UnixMilli(resultList[1]),
+	}, nil
+}
+
+// RestoreAdmission: give back consumed token until its admission expires, when order using it failed
+func (cache *CacheStore) RestoreAdmission(ctx context.Context, flightID string, token string, admittedUntil time.Time) error {
+	err := RestoreWaitingRoomAdmission.Run(ctx, cache.rdb,
+		[]string{roomKey(flightID), admittedKey(flightID)}, token, admittedUntil.UnixMilli()).Err()
+	if err != nil {
+		return fmt.Errorf("failed to restore admission of flight %s %w", flightID, err)
+	}
+	return nil
+}
+
+/*
+*
+JoinWaitingRoom: luascript for queueing token in arrival order, active token of holder is returned instead of queueing again
+input keys: room key, queue key, admitted key, holders key, arguments: token, holder
+return {status, position, admitted_until_ms, token}, status -1 closed, 1 waiting, 2 admitted
+*
+*/
+var JoinWaitingRoom = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return {-1, 0, 0, ""}
+end
+local current = redis.call("HGET", KEYS[4], ARGV[2])
+if current then
+	local rank = redis.call("ZRANK", KEYS[2], current)
+	if rank then
+		return {1, rank + 1, 0, current}
+	end
+	local time = redis.call("TIME")
+	local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+	local admitted_until = tonumber(redis.call("ZSCORE", KEYS[3], current))
+	if admitted_until and admitted_until > now then
+		return {2, 0, admitted_until, current}
+	end
+end
+local seq = redis.call("HINCRBY", KEYS[1], "seq", 1)
+redis.call("ZADD", KEYS[2], seq, ARGV[1])
+redis.call("HSET", KEYS[4], ARGV[2], ARGV[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+	redis.call("PEXPIRE", KEYS[4], ttl)
+end
+return {1, redis.call("ZRANK", KEYS[2], ARGV[1]) + 1, 0, ARGV[1]}
+`)
+
+/*
+*
+GetWaitingRoomTicket: luascript for status of token
+input keys: room key, queue key, admitted key, arguments: token
+return {status, position, admitted_until_ms}, status -1 closed, 0 expired, 1 waiting, 2 admitted
+*
+*/
+var GetWaitingRoomTicket = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return {-1, 0, 0}
+end
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local admitted_until = tonumber(redis.call("ZSCORE", KEYS[3], ARGV[1]))
+if admitted_until and admitted_until > now then
+	return {2, 0, admitted_until}
+end
+local rank = redis.call("ZRANK", KEYS[2], ARGV[1])
+if rank then
+	return {1, rank + 1, 0}
+end
+return {0, 0, 0}
+`)
+
+/*
+*
+AdmitFromWaitingRoom: luascript for moving head of queue to admitted tokens,
+admits admit_per_second per elapsed second since last admission, at most one second worth at once,
+so gatekeepers of every instance together keep the rate
+input keys: room key, queue key, admitted key
+return tokens admitted, -1 when room is not open anymore
+*
+*/
+var AdmitFromWaitingRoom = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local room = redis.call("HMGET", KEYS[1], "admit_per_second", "admission_ms", "last_admit_ms")
+local rate = tonumber(room[1])
+local admission = tonumber(room[2])
+local last = tonumber(room[3]) or (now - 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[3], "-inf", now)
+local count = math.min(rate, math.floor((now - last) * rate / 1000))
+if count < 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "last_admit_ms", now)
+local popped = redis.call("ZPOPMIN", KEYS[2], count)
+for index = 1, #popped, 2 do
+	redis.call("ZADD", KEYS[3], now + admission, popped[index])
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[3], ttl)
+end
+return #popped / 2
+`)
+
+/*
+*
+ConsumeWaitingRoomAdmission: luascript for using admitted token once
+input keys: room key, admitted key, arguments: token
+return {status, admitted_until_ms}, status 2 when consumed, -1 when room is not open, 0 when token is not admitted
+*
+*/
+var ConsumeWaitingRoomAdmission = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return {-1, 0}
+end
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local admitted_until = tonumber(redis.call("ZSCORE", KEYS[2], ARGV[1]))
+if admitted_until and admitted_until > now then
+	redis.call("ZREM", KEYS[2], ARGV[1])
+	return {2, admitted_until}
+end
+return {0, 0}
+`)
+
+/*
+*
+RestoreWaitingRoomAdmission: luascript for giving back consumed token while its admission lasts
+input keys: room key, admitted key, arguments: token, admitted_until_ms
+return 1 when restored, 0 when room is not open or admission expired
+*
+*/
+var RestoreWaitingRoomAdmission = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local admitted_until = tonumber(ARGV[2])
+if admitted_until <= now then
+	return 0
+end
+redis.call("ZADD", KEYS[2], admitted_until, ARGV[1])
+return 1
+`)
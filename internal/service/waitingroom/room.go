@@ -0,0 +1,122 @@
+package waitingroom
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// TokenHeader: header carrying admitted waiting room tokens of order request, one per gated flight
+const TokenHeader = "X-Waiting-Room-Token"
+
+var (
+	ErrInvalidToken      = apperr.New(apperr.BadRequest, "invalid waiting room token")
+	ErrRoomNotOpen       = apperr.New(apperr.NotFound, "flight has no waiting room")
+	ErrAdmissionRequired = apperr.New(apperr.Forbidden, "waiting room admission required")
+)
+
+// NewToken: token of flight_id, flight is part of token so it needs no lookup
+func NewToken(flightID string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate waiting room token %w", err)
+	}
+	return fmt.Sprintf("%s.%s", flightID, hex.EncodeToString(buf)), nil
+}
+
+// ParseToken: flight_id of token, ErrInvalidToken when malformed
+func ParseToken(token string) (string, error) {
+	flightID, secret, found := strings.Cut(token, ".")
+	if !found || len(secret) != 32 {
+		return "", ErrInvalidToken
+	}
+	if _, err := hex.DecodeString(secret); err != nil {
+		return "", ErrInvalidToken
+	}
+	if _, err := uuid.Parse(flightID); err != nil {
+		return "", ErrInvalidToken
+	}
+	return flightID, nil
+}
+
+/*
+*
+RequireAdmission: flights with waiting room need an admitted token of that flight among tokens,
+tokens are only checked here and used by ConsumeAdmission once seats are held, flights without waiting room pass
+*/
+func RequireAdmission(ctx context.Context, cacheStore types.WaitingRoomCacheStore, flightIDs []string, tokens []string) ([]types.WaitingRoomAdmission, error) {
+	tokenOfFlight := make(map[string]string, len(tokens))
+	for _, token := range tokens {
+		for _, value := range strings.Split(token, ",") {
+			value = strings.TrimSpace(value)
+			if flightID, err := ParseToken(value); err == nil {
+				tokenOfFlight[strings.ToLower(flightID)] = value
+			}
+		}
+	}
+	admissions := []types.WaitingRoomAdmission{}
+	for _, flightID := range flightIDs {
+		// rooms are keyed by lowercase flight id
+		flightID = strings.ToLower(flightID)
+		ticket, err := cacheStore.GetTicket(ctx, flightID, tokenOfFlight[flightID])
+		if err != nil {
+			return nil, err
+		}
+		switch ticket.Status {
+		case types.WaitingRoomStatusClosed:
+			continue
+		case types.WaitingRoomStatusAdmitted:
+			admissions = append(admissions, types.WaitingRoomAdmission{FlightID: flightID, Token: tokenOfFlight[flightID]})
+		default:
+			return nil, admissionRequired(flightID)
+		}
+	}
+	return admissions, nil
+}
+
+/*
+*
+ConsumeAdmission: use every admitted token of RequireAdmission once, all or none,
+ErrAdmissionRequired when a token expired or was used by another order meanwhile
+*/
+func ConsumeAdmission(ctx context.Context, cacheStore types.WaitingRoomCacheStore, admissions []types.WaitingRoomAdmission) ([]types.WaitingRoomAdmission, error) {
+	consumed := make([]types.WaitingRoomAdmission, 0, len(admissions))
+	for _, admission := range admissions {
+		ticket, err := cacheStore.ConsumeAdmission(ctx, admission.FlightID, admission.Token)
+		if err == nil && ticket.Status == types.WaitingRoomStatusExpired {
+			err = admissionRequired(admission.FlightID)
+		}
+		if err != nil {
+			RestoreAdmission(ctx, cacheStore, consumed)
+			return nil, err
+		}
+		// room closed meanwhile, flight is not gated anymore
+		if ticket.Status == types.WaitingRoomStatusClosed {
+			continue
+		}
+		admission.AdmittedUntil = ticket.AdmittedUntil
+		consumed = append(consumed, admission)
+	}
+	return consumed, nil
+}
+
+// RestoreAdmission: give back tokens of ConsumeAdmission when order was not published, failure is only logged
+func RestoreAdmission(ctx context.Context, cacheStore types.WaitingRoomCacheStore, admissions []types.WaitingRoomAdmission) {
+	for _, admission := range admissions {
+		if err := cacheStore.RestoreAdmission(ctx, admission.FlightID, admission.Token, admission.AdmittedUntil); err != nil {
+			log.Printf("failed to restore admission %v", err)
+		}
+	}
+}
+
+func admissionRequired(flightID string) error {
+	return fmt.Errorf("flight %s %w, join with POST /waiting-room and send %s once admitted",
+		flightID, ErrAdmissionRequired, TokenHeader)
+}
@@ -0,0 +1,176 @@
+package waitingroom
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/service/customer"
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+	"github.com/yuanyu90221/airline-order-system/internal/util"
+)
+
+type Handler struct {
+	cacheStore   types.WaitingRoomCacheStore
+	flightStore  types.FlightStore
+	admissionTTL time.Duration
+}
+
+func NewHandler(cacheStore types.WaitingRoomCacheStore, flightStore types.FlightStore, admissionTTL time.Duration) *Handler {
+	return &Handler{
+		cacheStore:   cacheStore,
+		flightStore:  flightStore,
+		admissionTTL: admissionTTL,
+	}
+}
+
+func (h *Handler) RegisterRoute(router *gin.RouterGroup) {
+	router.POST("/", h.JoinWaitingRoom)
+	router.GET("/:token", h.GetTicket)
+}
+
+func (h *Handler) RegisterAdminRoute(router *gin.RouterGroup) {
+	router.PUT("/flights/:id/waiting-room", h.OpenWaitingRoom)
+	router.DELETE("/flights/:id/waiting-room", h.CloseWaitingRoom)
+}
+
+/*
+*
+JoinWaitingRoom: queue token for flight with waiting room, poll GET /waiting-room/:token until admitted,
+signed in customer or else client ip holds one active token per room, joining again returns that token
+*/
+func (h *Handler) JoinWaitingRoom(ctx *gin.Context) {
+	var joinRequest types.JoinWaitingRoomRequest
+	if err := util.ParseJSON(ctx.Request, &joinRequest); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(joinRequest); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	flightID := strings.ToLower(joinRequest.FlightID)
+	token, err := NewToken(flightID)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	holderToken, ticket, err := h.cacheStore.Join(ctx, flightID, holder(ctx), token)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	if ticket.Status == types.WaitingRoomStatusClosed {
+		util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("flight %s %w, order directly", flightID, ErrRoomNotOpen))
+		return
+	}
+	status := http.StatusCreated
+	if holderToken != token {
+		status = http.StatusOK
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, status, types.ConvertWaitingRoomTicketToResponse(holderToken, flightID, ticket)),
+		"failed to response json")
+}
+
+// holder: signed in customer, client ip for anonymous requests
+func holder(ctx *gin.Context) string {
+	if customerID, ok := customer.CustomerID(ctx); ok {
+		return "customer:" + customerID.String()
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+func (h *Handler) GetTicket(ctx *gin.Context) {
+	token := ctx.Param("token")
+	flightID, err := ParseToken(token)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	ticket, err := h.cacheStore.GetTicket(ctx, flightID, token)
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	if ticket.Status == types.WaitingRoomStatusWaiting {
+		ctx.Header("Retry-After", "1")
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.ConvertWaitingRoomTicketToResponse(token, flightID, ticket)), "failed to response json")
+}
+
+/*
+*
+OpenWaitingRoom: gate orders of flight behind waiting room until flight departs or room is closed
+*/
+func (h *Handler) OpenWaitingRoom(ctx *gin.Context) {
+	flightID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", ctx.Param("id"), err))
+		return
+	}
+	var openRequest types.OpenWaitingRoomRequest
+	if err := util.ParseJSON(ctx.Request, &openRequest); err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := util.Validdate.Struct(openRequest); err != nil {
+		var valErrs validator.ValidationErrors
+		if errors.As(err, &valErrs) {
+			util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("invalid payload:%w", valErrs))
+		}
+		return
+	}
+	flights, err := h.flightStore.GetFlightsByIds(ctx, []uuid.UUID{flightID})
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	if len(flights) == 0 {
+		util.WriteError(ctx.Writer, http.StatusNotFound, fmt.Errorf("flight %s not found", flightID))
+		return
+	}
+	if !flights[0].FlightDate.After(time.Now().UTC()) {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("flight %s already departed", flightID))
+		return
+	}
+	admissionTTL := h.admissionTTL
+	if openRequest.AdmissionSeconds > 0 {
+		admissionTTL = time.Duration(openRequest.AdmissionSeconds) * time.Second
+	}
+	err = h.cacheStore.OpenRoom(ctx, types.WaitingRoomParam{
+		FlightID:       flightID.String(),
+		AdmitPerSecond: openRequest.AdmitPerSecond,
+		AdmissionTTL:   admissionTTL,
+		ExpireAt:       flights[0].FlightDate,
+	})
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	util.FailOnError(util.WriteJSON(ctx.Writer, http.StatusOK, types.WaitingRoomResponse{
+		FlightID:         flightID.String(),
+		AdmitPerSecond:   openRequest.AdmitPerSecond,
+		AdmissionSeconds: int64(admissionTTL / time.Second),
+	}), "failed to response json")
+}
+
+func (h *Handler) CloseWaitingRoom(ctx *gin.Context) {
+	flightID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		util.WriteError(ctx.Writer, http.StatusBadRequest, fmt.Errorf("failed to parse id %s into uuid %w", ctx.Param("id"), err))
+		return
+	}
+	if err := h.cacheStore.CloseRoom(ctx, flightID.String()); err != nil {
+		util.WriteError(ctx.Writer, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package waitingroom
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yuanyu90221/airline-order-system/internal/types"
+)
+
+// admit queued waiting room tokens of every open room, rate is kept by room script across instances
+type GatekeeperWorker struct {
+	cacheStore types.WaitingRoomCacheStore
+	interval   time.Duration
+}
+
+func NewGatekeeperWorker(cacheStore types.WaitingRoomCacheStore, interval time.Duration) *GatekeeperWorker {
+	return &GatekeeperWorker{
+		cacheStore: cacheStore,
+		interval:   interval,
+	}
+}
+
+func (worker *GatekeeperWorker) Run(ctx context.Context) error {
+	log.Println("waiting room gatekeeper worker start")
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("waiting room gatekeeper worker end")
+			return nil
+		case <-ticker.C:
+			worker.admit(ctx)
+		}
+	}
+}
+
+func (worker *GatekeeperWorker) admit(ctx context.Context) {
+	flightIDs, err := worker.cacheStore.GetRoomFlightIDs(ctx)
+	if err != nil {
+		log.Printf("failed to get waiting rooms %v", err)
+		return
+	}
+	for _, flightID := range flightIDs {
+		if _, err := worker.cacheStore.Admit(ctx, flightID); err != nil {
+			log.Printf("failed to admit waiting room of flight %s %v", flightID, err)
+		}
+	}
+}
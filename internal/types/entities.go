@@ -2,30 +2,323 @@ package types
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Flight struct {
-	ID             uuid.UUID `json:"id" db:"id"`
+	ID          uuid.UUID `json:"id" db:"id"`
+	Departure   string    `json:"departure" db:"departure"`
+	Destination string    `json:"destination" db:"destination"`
+	FlightDate  time.Time `json:"flight_date" db:"flight_date"`
+	// scheduled arrival, not valid when unknown
+	ArrivalDate    sql.NullTime `json:"arrival_date" db:"arrival_date"`
+	Price          float64      `json:"price" db:"price"`
+	AvailableSeats int32        `json:"available_seats" db:"available_seats"`
+	WaitSeats      int32        `json:"wait_seats" db:"wait_seats"`
+	NextWaitOrder  int32        `json:"next_wait_order" db:"next_wait_order"`
+	Capacity       int32        `json:"capacity" db:"capacity"`
+	WaitCapacity   int32        `json:"wait_capacity" db:"wait_capacity"`
+	CarrierCode    string       `json:"carrier_code" db:"carrier_code"`
+	FlightNumber   string       `json:"flight_number" db:"flight_number"`
+	AircraftType   string       `json:"aircraft_type" db:"aircraft_type"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+
+	// wait seats given explicitly, overbooking policy never recomputes them
+	WaitCapacityOverride bool `json:"wait_capacity_override" db:"wait_capacity_override"`
+}
+
+// Designator: carrier code followed by flight number, e.g. ZZ100, empty for flight without flight number
+func (flight Flight) Designator() string {
+	if flight.FlightNumber == "" {
+		return ""
+	}
+	return flight.CarrierCode + flight.FlightNumber
+}
+
+type Order struct {
+	ID            uuid.UUID      `json:"id" db:"id"`
+	FlightID      uuid.UUID      `json:"flight_id" db:"flight_id"`
+	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
+	CanceledAt    sql.NullTime   `json:"canceled_at,omitempty" db:"canceled_at"`
+	PaidAt        sql.NullTime   `json:"paid_at,omitempty" db:"paid_at"`
+	WaitOrder     int32          `json:"wait_order,omitempty" db:"wait_order"`
+	TicketNumbers int32          `json:"ticket_numbers" db:"ticket_numbers"`
+	PNR           sql.NullString `json:"pnr,omitempty" db:"pnr"`
+	// set on orders booked together as legs of a connecting itinerary
+	ItineraryID uuid.NullUUID `json:"itinerary_id,omitempty" db:"itinerary_id"`
+	LegSequence sql.NullInt16 `json:"leg_sequence,omitempty" db:"leg_sequence"`
+	CustomerID  uuid.NullUUID `json:"customer_id,omitempty" db:"customer_id"`
+	// travel agency booked order with api key
+	AgencyID uuid.NullUUID `json:"agency_id,omitempty" db:"agency_id"`
+}
+
+// IsWaitlisted: wait_order is -1 for confirmed orders, otherwise the waitlist position
+func (order Order) IsWaitlisted() bool {
+	return order.WaitOrder >= 0
+}
+
+// order status derived from canceled_at, paid_at and wait_order
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusWaitlisted = "waitlisted"
+	OrderStatusPaid       = "paid"
+	OrderStatusCanceled   = "canceled"
+)
+
+// Status: canceled wins over paid, unpaid orders are waitlisted or pending payment
+func (order Order) Status() string {
+	switch {
+	case order.CanceledAt.Valid:
+		return OrderStatusCanceled
+	case order.PaidAt.Valid:
+		return OrderStatusPaid
+	case order.IsWaitlisted():
+		return OrderStatusWaitlisted
+	default:
+		return OrderStatusPending
+	}
+}
+
+// OrderSegment: flight of multi-segment order, orders.flight_id is the flight of segment 1
+type OrderSegment struct {
+	OrderID         uuid.UUID `json:"order_id" db:"order_id"`
+	SegmentSequence int16     `json:"segment_sequence" db:"segment_sequence"`
+	FlightID        uuid.UUID `json:"flight_id" db:"flight_id"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+type Passenger struct {
+	ID              uuid.UUID      `json:"id" db:"id"`
+	OrderID         uuid.UUID      `json:"order_id" db:"order_id"`
+	Name            string         `json:"name" db:"name"`
+	DateOfBirth     time.Time      `json:"date_of_birth" db:"date_of_birth"`
+	DocumentNumber  string         `json:"document_number" db:"document_number"`
+	TicketNumber    sql.NullString `json:"ticket_number,omitempty" db:"ticket_number"`
+	TicketIssuedAt  sql.NullTime   `json:"ticket_issued_at,omitempty" db:"ticket_issued_at"`
+	SeatNumber      sql.NullString `json:"seat_number,omitempty" db:"seat_number"`
+	CheckedInAt     sql.NullTime   `json:"checked_in_at,omitempty" db:"checked_in_at"`
+	CheckinSequence sql.NullInt32  `json:"checkin_sequence,omitempty" db:"checkin_sequence"`
+	VolunteeredAt   sql.NullTime   `json:"volunteered_at,omitempty" db:"volunteered_at"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+}
+
+type Ticket struct {
+	TicketNumber  string    `json:"ticket_number" db:"ticket_number"`
+	IssuedAt      time.Time `json:"issued_at" db:"ticket_issued_at"`
+	PassengerID   uuid.UUID `json:"passenger_id" db:"passenger_id"`
+	PassengerName string    `json:"passenger_name" db:"name"`
+	OrderID       uuid.UUID `json:"order_id" db:"order_id"`
+	FlightID      uuid.UUID `json:"flight_id" db:"flight_id"`
+	PNR           string    `json:"pnr" db:"pnr"`
+	// FlightIDs: flight of every coupon, one per segment of multi-segment order
+	FlightIDs []uuid.UUID `json:"flight_ids"`
+}
+
+type CabinLayout struct {
+	Cabin   string `json:"cabin"`
+	FromRow int32  `json:"from_row"`
+	ToRow   int32  `json:"to_row"`
+	Letters string `json:"letters"`
+}
+
+type SeatMapLayout struct {
+	Cabins       []CabinLayout `json:"cabins"`
+	ExitRows     []int32       `json:"exit_rows"`
+	BlockedSeats []string      `json:"blocked_seats"`
+}
+
+type SeatMapTemplate struct {
+	ID        uuid.UUID     `json:"id" db:"id"`
+	Name      string        `json:"name" db:"name"`
+	Layout    SeatMapLayout `json:"layout" db:"layout"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+}
+
+type FlightSeat struct {
+	FlightID   uuid.UUID `json:"flight_id" db:"flight_id"`
+	SeatNumber string    `json:"seat_number" db:"seat_number"`
+	RowNumber  int32     `json:"row_number" db:"row_number"`
+	Letter     string    `json:"letter" db:"letter"`
+	Cabin      string    `json:"cabin" db:"cabin"`
+	IsExitRow  bool      `json:"is_exit_row" db:"is_exit_row"`
+	IsBlocked  bool      `json:"is_blocked" db:"is_blocked"`
+}
+
+// Seats: expand layout into concrete seats for flightID, e.g. 12A
+func (layout SeatMapLayout) Seats(flightID uuid.UUID) []FlightSeat {
+	exitRows := make(map[int32]bool, len(layout.ExitRows))
+	for _, row := range layout.ExitRows {
+		exitRows[row] = true
+	}
+	blockedSeats := make(map[string]bool, len(layout.BlockedSeats))
+	for _, seatNumber := range layout.BlockedSeats {
+		blockedSeats[seatNumber] = true
+	}
+	seats := []FlightSeat{}
+	for _, cabin := range layout.Cabins {
+		for row := cabin.FromRow; row <= cabin.ToRow; row++ {
+			for _, letter := range cabin.Letters {
+				seatNumber := fmt.Sprintf("%d%c", row, letter)
+				seats = append(seats, FlightSeat{
+					FlightID:   flightID,
+					SeatNumber: seatNumber,
+					RowNumber:  row,
+					Letter:     string(letter),
+					Cabin:      cabin.Cabin,
+					IsExitRow:  exitRows[row],
+					IsBlocked:  blockedSeats[seatNumber],
+				})
+			}
+		}
+	}
+	return seats
+}
+
+// BookableSeats: number of seats not blocked in layout
+func (layout SeatMapLayout) BookableSeats() int {
+	count := 0
+	for _, seat := range layout.Seats(uuid.Nil) {
+		if !seat.IsBlocked {
+			count++
+		}
+	}
+	return count
+}
+
+type GateClosure struct {
+	FlightID  uuid.UUID `json:"flight_id" db:"flight_id"`
+	Capacity  int32     `json:"capacity" db:"capacity"`
+	CheckedIn int32     `json:"checked_in" db:"checked_in"`
+	Denied    int32     `json:"denied" db:"denied"`
+	ClosedAt  time.Time `json:"closed_at" db:"closed_at"`
+}
+
+type DeniedBoarding struct {
+	ID               uuid.UUID     `json:"id" db:"id"`
+	FlightID         uuid.UUID     `json:"flight_id" db:"flight_id"`
+	OrderID          uuid.UUID     `json:"order_id" db:"order_id"`
+	PassengerID      uuid.UUID     `json:"passenger_id" db:"passenger_id"`
+	IsVoluntary      bool          `json:"is_voluntary" db:"is_voluntary"`
+	Compensation     float64       `json:"compensation" db:"compensation"`
+	RebookedFlightID uuid.NullUUID `json:"rebooked_flight_id" db:"rebooked_flight_id"`
+	RebookedOrderID  uuid.NullUUID `json:"rebooked_order_id" db:"rebooked_order_id"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+}
+
+type OverbookingPolicy struct {
+	ID                 uuid.UUID    `json:"id" db:"id"`
+	Departure          string       `json:"departure" db:"departure"`
+	Destination        string       `json:"destination" db:"destination"`
+	OversellPercentage float64      `json:"oversell_percentage" db:"oversell_percentage"`
+	NoShowRate         float64      `json:"no_show_rate" db:"no_show_rate"`
+	MaxWaitSeats       int32        `json:"max_wait_seats" db:"max_wait_seats"`
+	UseNoShowStats     bool         `json:"use_noshow_stats" db:"use_noshow_stats"`
+	EffectiveFrom      time.Time    `json:"effective_from" db:"effective_from"`
+	EffectiveTo        sql.NullTime `json:"effective_to" db:"effective_to"`
+	CreatedAt          time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+type NoShowStat struct {
 	Departure      string    `json:"departure" db:"departure"`
 	Destination    string    `json:"destination" db:"destination"`
-	FlightDate     time.Time `json:"flight_date" db:"flight_date"`
-	Price          float64   `json:"price" db:"price"`
-	AvailableSeats int32     `json:"available_seats" db:"available_seats"`
-	WaitSeats      int32     `json:"wait_seats" db:"wait_seats"`
-	NextWaitOrder  int32     `json:"next_wait_order" db:"next_wait_order"`
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	DayOfWeek      int16     `json:"day_of_week" db:"day_of_week"`
+	LeadTimeBucket string    `json:"lead_time_bucket" db:"lead_time_bucket"`
+	Booked         int32     `json:"booked" db:"booked"`
+	NoShows        int32     `json:"no_shows" db:"no_shows"`
+	NoShowRate     float64   `json:"no_show_rate" db:"no_show_rate"`
+	ComputedAt     time.Time `json:"computed_at" db:"computed_at"`
 }
 
-type Order struct {
-	ID            uuid.UUID    `json:"id" db:"id"`
-	FlightID      uuid.UUID    `json:"flight_id" db:"flight_id"`
-	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
-	CanceledAt    sql.NullTime `json:"canceled_at,omitempty" db:"canceled_at"`
-	PaidAt        sql.NullTime `json:"paid_at,omitempty" db:"paid_at"`
-	WaitOrder     int32        `json:"wait_order,omitempty" db:"wait_order"`
-	TicketNumbers int32        `json:"ticket_numbers" db:"ticket_numbers"`
+type Customer struct {
+	ID           uuid.UUID      `json:"id" db:"id"`
+	Email        string         `json:"email" db:"email"`
+	PasswordHash string         `json:"-" db:"password_hash"`
+	Name         string         `json:"name" db:"name"`
+	Phone        sql.NullString `json:"phone,omitempty" db:"phone"`
+	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+type Agency struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	Name           string    `json:"name" db:"name"`
+	CommissionRate float64   `json:"commission_rate" db:"commission_rate"`
+	// max seats per flight booked by agency
+	SeatAllotment int32     `json:"seat_allotment" db:"seat_allotment"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type AgencyAPIKey struct {
+	ID                uuid.UUID    `json:"id" db:"id"`
+	AgencyID          uuid.UUID    `json:"agency_id" db:"agency_id"`
+	Prefix            string       `json:"prefix" db:"prefix"`
+	KeyHash           string       `json:"key_hash" db:"key_hash"`
+	Name              string       `json:"name" db:"name"`
+	DailyRequestQuota int32        `json:"daily_request_quota" db:"daily_request_quota"`
+	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
+	RevokedAt         sql.NullTime `json:"revoked_at" db:"revoked_at"`
+}
+
+// AbuseDecision: screening decision of order request, Signals holds observed count of each signal
+type AbuseDecision struct {
+	ID        uuid.UUID        `json:"id" db:"id"`
+	Action    string           `json:"action" db:"action"`
+	Score     int64            `json:"score" db:"score"`
+	Signals   map[string]int64 `json:"signals" db:"signals"`
+	Actors    []string         `json:"actors" db:"actors"`
+	OrderID   uuid.NullUUID    `json:"order_id" db:"order_id"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+type Airport struct {
+	IATACode  string    `json:"iata_code" db:"iata_code"`
+	Name      string    `json:"name" db:"name"`
+	City      string    `json:"city" db:"city"`
+	Country   string    `json:"country" db:"country"`
+	Timezone  string    `json:"timezone" db:"timezone"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Schedule struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	CarrierCode    string         `json:"carrier_code" db:"carrier_code"`
+	FlightNumber   string         `json:"flight_number" db:"flight_number"`
+	AircraftType   sql.NullString `json:"aircraft_type" db:"aircraft_type"`
+	Departure      string         `json:"departure" db:"departure"`
+	Destination    string         `json:"destination" db:"destination"`
+	DaysOfWeek     int16          `json:"days_of_week" db:"days_of_week"`
+	DepartureTime  string         `json:"departure_time" db:"departure_time"`
+	EffectiveFrom  time.Time      `json:"effective_from" db:"effective_from"`
+	EffectiveTo    sql.NullTime   `json:"effective_to" db:"effective_to"`
+	Capacity       int32          `json:"capacity" db:"capacity"`
+	Price          float64        `json:"price" db:"price"`
+	WaitSeats      sql.NullInt32  `json:"wait_seats" db:"wait_seats"`
+	SeatMapID      uuid.NullUUID  `json:"seat_map_id" db:"seat_map_id"`
+	BlockMinutes   sql.NullInt32  `json:"block_minutes" db:"block_minutes"`
+	IsActive       bool           `json:"is_active" db:"is_active"`
+	GeneratedUntil sql.NullTime   `json:"generated_until" db:"generated_until"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// OperatesOn: weekday bit is set in days_of_week
+func (schedule Schedule) OperatesOn(weekday time.Weekday) bool {
+	return schedule.DaysOfWeek&(1<<uint(weekday)) != 0
+}
+
+type AircraftType struct {
+	Code               string        `json:"code" db:"code"`
+	Model              string        `json:"model" db:"model"`
+	CabinConfiguration string        `json:"cabin_configuration" db:"cabin_configuration"`
+	TotalSeats         int32         `json:"total_seats" db:"total_seats"`
+	SeatMapID          uuid.NullUUID `json:"seat_map_id" db:"seat_map_id"`
+	CreatedAt          time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at" db:"updated_at"`
 }
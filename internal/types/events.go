@@ -1,11 +1,34 @@
 package types
 
 type CreateOrderEvent struct {
-	ID             string `json:"id"`
+	ID             string             `json:"id"`
+	FlightID       string             `json:"flight_id"`
+	WaitOrder      int64              `json:"wait_order"`
+	WaitSeats      int64              `json:"wait_seats"`
+	AvailableSeats int64              `json:"available_seats"`
+	TicketNumbers  int64              `json:"ticket_numbers"`
+	IsWait         bool               `json:"is_wait"`
+	PNR            string             `json:"pnr"`
+	Passengers     []PassengerRequest `json:"passengers"`
+	Prepaid        bool               `json:"prepaid"`
+	// connecting itinerary legs share itinerary_id, leg_sequence starts from 1
+	ItineraryID string `json:"itinerary_id,omitempty"`
+	LegSequence int16  `json:"leg_sequence,omitempty"`
+	// counters of every segment after reservation, FlightID is the flight of segment 1
+	Segments []OrderSegmentEvent `json:"segments,omitempty"`
+	// customer signed in when booking, empty for anonymous orders
+	CustomerID string `json:"customer_id,omitempty"`
+	// agency of api key used when booking
+	AgencyID string `json:"agency_id,omitempty"`
+	// price per ticket when booked, sum of segment prices for multi-segment order
+	Fare float64 `json:"fare,omitempty"`
+	// orders of every leg of connecting itinerary, published together and created in one transaction
+	Legs []CreateOrderEvent `json:"legs,omitempty"`
+}
+
+type OrderSegmentEvent struct {
 	FlightID       string `json:"flight_id"`
-	WaitOrder      int64  `json:"wait_order"`
-	WaitSeats      int64  `json:"wait_seats"`
 	AvailableSeats int64  `json:"available_seats"`
-	TicketNumbers  int64  `json:"ticket_numbers"`
-	IsWait         bool   `json:"is_wait"`
+	WaitSeats      int64  `json:"wait_seats"`
+	WaitOrder      int64  `json:"wait_order"`
 }
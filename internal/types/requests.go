@@ -1,20 +1,214 @@
 package types
 
+import "github.com/google/uuid"
+
 type QueryFlightRequest struct {
-	FlightDate  int64  `json:"flight_date" validate:"required"`
-	Destination string `json:"destination" validate:"required"`
-	Departure   string `json:"depature" validate:"required"`
+	// departs on or after flight_date
+	FlightDate int64 `json:"flight_date" validate:"min=0"`
+	// departs within [date_from, date_to)
+	DateFrom     int64   `json:"date_from" validate:"min=0"`
+	DateTo       int64   `json:"date_to" validate:"required_with=DepartAfter DepartBefore,omitempty,gtfield=DateFrom"`
+	Destination  string  `json:"destination" validate:"omitempty,len=3,alpha"`
+	Departure    string  `json:"departure" validate:"required_with=DepartAfter DepartBefore,omitempty,len=3,alpha"`
+	CarrierCode  string  `json:"carrier_code" validate:"omitempty,carrier_code"`
+	FlightNumber string  `json:"flight_number" validate:"omitempty,flight_number"`
+	PriceMin     float64 `json:"price_min" validate:"min=0"`
+	PriceMax     float64 `json:"price_max" validate:"omitempty,gtefield=PriceMin"`
+	MinRemain    int64   `json:"min_remain" validate:"min=0"`
+	// local departure time-of-day window at departure airport, wraps past midnight when after is later than before,
+	// needs departure and date_to
+	DepartAfter  string `json:"depart_after" validate:"omitempty,datetime=15:04"`
+	DepartBefore string `json:"depart_before" validate:"omitempty,datetime=15:04"`
+	SortBy       string `json:"sort_by" validate:"omitempty,oneof=departure_time price remain"`
+	SortOrder    string `json:"sort_order" validate:"omitempty,oneof=asc desc"`
+}
+
+type QueryFareCalendarRequest struct {
+	Departure   string `json:"departure" validate:"required,len=3,alpha"`
+	Destination string `json:"destination" validate:"required,len=3,alpha,nefield=Departure"`
+	// whole month, e.g. 2024-07
+	Month string `json:"month" validate:"required_without=Date,excluded_with=Date,omitempty,datetime=2006-01"`
+	// date with FlexDays days before and after, e.g. 2024-07-15
+	Date     string `json:"date" validate:"omitempty,datetime=2006-01-02"`
+	FlexDays int64  `json:"flex_days" validate:"min=0,max=7"`
 }
+
 type CreateFlightRequest struct {
 	Price          float64 `json:"price" validate:"required"`
 	FlightDate     int64   `json:"flight_date" validate:"required"`
-	Destination    string  `json:"destination" validate:"required"`
-	Departure      string  `json:"departure" validate:"required"`
-	AvailableSeats int64   `json:"available_seats" validate:"required"`
-	WaitSeats      int64   `json:"wait_seats" validate:"required"`
+	ArrivalDate    int64   `json:"arrival_date" validate:"omitempty,gtfield=FlightDate"`
+	Destination    string  `json:"destination" validate:"required,len=3,alpha,nefield=Departure"`
+	Departure      string  `json:"departure" validate:"required,len=3,alpha"`
+	AvailableSeats int64   `json:"available_seats" validate:"required_without=AircraftType"`
+	WaitSeats      *int64  `json:"wait_seats" validate:"omitempty,min=0"`
+	Capacity       int64   `json:"capacity" validate:"omitempty,gtefield=AvailableSeats"`
+	SeatMapID      string  `json:"seat_map_id" validate:"omitempty,uuid"`
+	CarrierCode    string  `json:"carrier_code" validate:"omitempty,carrier_code"`
+	FlightNumber   string  `json:"flight_number" validate:"omitempty,flight_number"`
+	AircraftType   string  `json:"aircraft_type" validate:"omitempty,max=10,alphanum"`
+	// set by schedule generator, not accepted from payload
+	ScheduleID uuid.NullUUID `json:"-"`
+	// set when wait_seats is given instead of computed by overbooking policy, not accepted from payload
+	WaitSeatsOverride bool `json:"-"`
+}
+
+type PassengerRequest struct {
+	Name           string `json:"name" validate:"required,max=100"`
+	DateOfBirth    string `json:"date_of_birth" validate:"required,datetime=2006-01-02"`
+	DocumentNumber string `json:"document_number" validate:"required,max=50"`
+}
+
+type OrderSegmentRequest struct {
+	FlightID string `json:"flight_id" validate:"required,uuid"`
 }
 
 type CreateOrderRequest struct {
-	FlightID      string `json:"flight_id" validate:"required"`
-	TicketNumbers int64  `json:"ticket_numbers" validate:"required"`
+	FlightID string `json:"flight_id" validate:"required_without=Segments,excluded_with=Segments,omitempty,uuid"`
+	// round-trip or multi-city flights in travel order, reserved all-or-nothing
+	Segments      []OrderSegmentRequest `json:"segments" validate:"omitempty,min=2,max=6,unique=FlightID,dive"`
+	TicketNumbers int64                 `json:"ticket_numbers" validate:"required,min=1"`
+	Passengers    []PassengerRequest    `json:"passengers" validate:"required,dive"`
+	// signed in customer and agency of api key, set from credentials instead of payload
+	CustomerID string `json:"-"`
+	AgencyID   string `json:"-"`
+}
+
+type CabinLayoutRequest struct {
+	Cabin   string `json:"cabin" validate:"required,max=20"`
+	FromRow int32  `json:"from_row" validate:"required,min=1"`
+	ToRow   int32  `json:"to_row" validate:"required,gtefield=FromRow,max=999"`
+	Letters string `json:"letters" validate:"required,alpha,uppercase"`
+}
+
+type CreateSeatMapTemplateRequest struct {
+	Name         string               `json:"name" validate:"required,max=100"`
+	Cabins       []CabinLayoutRequest `json:"cabins" validate:"required,min=1,dive"`
+	ExitRows     []int32              `json:"exit_rows"`
+	BlockedSeats []string             `json:"blocked_seats"`
+}
+
+type SeatAssignmentRequest struct {
+	PassengerID string `json:"passenger_id" validate:"required,uuid"`
+	SeatNumber  string `json:"seat_number" validate:"required,max=4"`
+}
+
+type AssignSeatsRequest struct {
+	Seats []SeatAssignmentRequest `json:"seats" validate:"required,min=1,dive"`
+}
+
+type VolunteerRequest struct {
+	PassengerIDs []string `json:"passenger_ids" validate:"omitempty,dive,uuid"`
+}
+
+type CreateOverbookingPolicyRequest struct {
+	Departure          string  `json:"departure" validate:"max=100"`
+	Destination        string  `json:"destination" validate:"max=100"`
+	OversellPercentage float64 `json:"oversell_percentage" validate:"min=0,max=100"`
+	NoShowRate         float64 `json:"no_show_rate" validate:"min=0,max=1"`
+	MaxWaitSeats       int32   `json:"max_wait_seats" validate:"min=0"`
+	UseNoShowStats     bool    `json:"use_noshow_stats"`
+	EffectiveFrom      int64   `json:"effective_from" validate:"required"`
+	EffectiveTo        int64   `json:"effective_to" validate:"omitempty,gtfield=EffectiveFrom"`
+}
+
+type CreateCustomerRequest struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Name     string `json:"name" validate:"required,max=200"`
+	Phone    string `json:"phone" validate:"omitempty,e164"`
+}
+
+// empty fields are left unchanged
+type UpdateCustomerRequest struct {
+	Name     string `json:"name" validate:"omitempty,max=200"`
+	Phone    string `json:"phone" validate:"omitempty,e164"`
+	Password string `json:"password" validate:"omitempty,min=8,max=72"`
+}
+
+type CreateAgencyRequest struct {
+	Name           string  `json:"name" validate:"required,max=200"`
+	CommissionRate float64 `json:"commission_rate" validate:"min=0,max=1"`
+	SeatAllotment  int32   `json:"seat_allotment" validate:"required,min=1"`
+}
+
+type CreateAgencyAPIKeyRequest struct {
+	Name              string `json:"name" validate:"required,max=100"`
+	DailyRequestQuota int32  `json:"daily_request_quota" validate:"required,min=1"`
+}
+
+type OpenWaitingRoomRequest struct {
+	AdmitPerSecond int64 `json:"admit_per_second" validate:"required,min=1,max=10000"`
+	// admitted token could order within admission_seconds, default WAITING_ROOM_ADMISSION_SECONDS
+	AdmissionSeconds int64 `json:"admission_seconds" validate:"omitempty,min=10,max=3600"`
+}
+
+type JoinWaitingRoomRequest struct {
+	FlightID string `json:"flight_id" validate:"required,uuid"`
+}
+
+type CreateTokenRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type QueryCustomerOrdersRequest struct {
+	Status string `json:"status" validate:"omitempty,oneof=pending waitlisted paid canceled"`
+	// created within [created_from, created_to)
+	CreatedFrom int64 `json:"created_from" validate:"min=0"`
+	CreatedTo   int64 `json:"created_to" validate:"omitempty,gtfield=CreatedFrom"`
+}
+
+type CreateAirportRequest struct {
+	IATACode string `json:"iata_code" validate:"required,len=3,alpha"`
+	Name     string `json:"name" validate:"required,max=200"`
+	City     string `json:"city" validate:"required,max=100"`
+	Country  string `json:"country" validate:"required,iso3166_1_alpha2"`
+	Timezone string `json:"timezone" validate:"required,timezone"`
+}
+
+type CreateScheduleRequest struct {
+	CarrierCode   string  `json:"carrier_code" validate:"omitempty,carrier_code"`
+	FlightNumber  string  `json:"flight_number" validate:"required,flight_number"`
+	AircraftType  string  `json:"aircraft_type" validate:"omitempty,max=10,alphanum"`
+	Departure     string  `json:"departure" validate:"required,len=3,alpha"`
+	Destination   string  `json:"destination" validate:"required,len=3,alpha,nefield=Departure"`
+	DaysOfWeek    []int   `json:"days_of_week" validate:"required,min=1,max=7,unique,dive,min=0,max=6"`
+	DepartureTime string  `json:"departure_time" validate:"required,datetime=15:04"`
+	EffectiveFrom string  `json:"effective_from" validate:"required,datetime=2006-01-02"`
+	EffectiveTo   string  `json:"effective_to" validate:"omitempty,datetime=2006-01-02"`
+	Capacity      int64   `json:"capacity" validate:"required_without=AircraftType,omitempty,min=1"`
+	Price         float64 `json:"price" validate:"required,gt=0"`
+	WaitSeats     *int64  `json:"wait_seats" validate:"omitempty,min=0"`
+	SeatMapID     string  `json:"seat_map_id" validate:"omitempty,uuid"`
+	BlockMinutes  int64   `json:"block_minutes" validate:"omitempty,min=1,max=1440"`
+}
+
+type CreateAircraftTypeRequest struct {
+	Code               string `json:"code" validate:"required,max=10,alphanum"`
+	Model              string `json:"model" validate:"required,max=100"`
+	CabinConfiguration string `json:"cabin_configuration" validate:"required,max=100"`
+	TotalSeats         int32  `json:"total_seats" validate:"required,min=1"`
+	SeatMapID          string `json:"seat_map_id" validate:"omitempty,uuid"`
+}
+
+type QueryItineraryRequest struct {
+	Departure   string `json:"departure" validate:"required,len=3,alpha"`
+	Destination string `json:"destination" validate:"required,len=3,alpha,nefield=Departure"`
+	// first leg departs within 24 hours from flight_date
+	FlightDate           int64 `json:"flight_date" validate:"required"`
+	MaxStops             int64 `json:"max_stops" validate:"min=0,max=2"`
+	MinConnectionMinutes int64 `json:"min_connection_minutes" validate:"min=0,max=1440"`
+	MaxConnectionMinutes int64 `json:"max_connection_minutes" validate:"min=1,max=2880,gtefield=MinConnectionMinutes"`
+}
+
+type CreateItineraryOrderRequest struct {
+	// flight of each leg in travel order
+	FlightIDs     []string           `json:"flight_ids" validate:"required,min=1,max=3,unique,dive,uuid"`
+	TicketNumbers int64              `json:"ticket_numbers" validate:"required,min=1"`
+	Passengers    []PassengerRequest `json:"passengers" validate:"required,dive"`
+	// signed in customer and agency of api key, set from credentials instead of payload
+	CustomerID string `json:"-"`
+	AgencyID   string `json:"-"`
+	// admitted waiting room tokens of gated legs, used once seats of every leg are held
+	Admissions []WaitingRoomAdmission `json:"-"`
 }
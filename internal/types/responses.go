@@ -7,37 +7,71 @@ import (
 )
 
 type FlightResponse struct {
-	ID             uuid.UUID `json:"id"`
-	Departure      string    `json:"departure"`
-	Destination    string    `json:"destination"`
-	FlightDate     time.Time `json:"flight_date"`
-	Price          float64   `json:"price"`
-	AvailableSeats int32     `json:"available_seats"`
-	WaitSeats      int32     `json:"wait_seats"`
-	NextWaitOrder  int32     `json:"next_wait_order"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Remain         int       `json:"remain"`
+	ID             uuid.UUID  `json:"id"`
+	Departure      string     `json:"departure"`
+	Destination    string     `json:"destination"`
+	FlightDate     time.Time  `json:"flight_date"`
+	ArrivalDate    *time.Time `json:"arrival_date,omitempty"`
+	Price          float64    `json:"price"`
+	AvailableSeats int32      `json:"available_seats"`
+	WaitSeats      int32      `json:"wait_seats"`
+	NextWaitOrder  int32      `json:"next_wait_order"`
+	Capacity       int32      `json:"capacity"`
+	WaitCapacity   int32      `json:"wait_capacity"`
+	CarrierCode    string     `json:"carrier_code"`
+	FlightNumber   string     `json:"flight_number"`
+	// carrier code followed by flight number, e.g. ZZ100
+	FlightDesignator string    `json:"flight_designator"`
+	AircraftType     string    `json:"aircraft_type"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Remain           int       `json:"remain"`
+	// departure time in departure airport timezone, RFC3339 with offset
+	FlightDateLocal   string `json:"flight_date_local,omitempty"`
+	DepartureTimezone string `json:"departure_timezone,omitempty"`
+
+	// wait seats given explicitly, kept by overbooking recompute
+	WaitCapacityOverride bool `json:"wait_capacity_override"`
+}
+
+// Localize: fill local departure time with departure airport location
+func (flight *FlightResponse) Localize(location *time.Location) {
+	flight.FlightDate = flight.FlightDate.UTC()
+	flight.FlightDateLocal = flight.FlightDate.In(location).Format(time.RFC3339)
+	flight.DepartureTimezone = location.String()
 }
+
 type FlightsFetchResponse struct {
 	Flights []FlightResponse `json:"flights"`
 	Pagination
 }
 
 func ConvertFlightToRespone(flight Flight) FlightResponse {
-	return FlightResponse{
-		ID:             flight.ID,
-		Departure:      flight.Departure,
-		Destination:    flight.Destination,
-		FlightDate:     flight.FlightDate,
-		Price:          flight.Price,
-		AvailableSeats: flight.AvailableSeats,
-		WaitSeats:      flight.WaitSeats,
-		NextWaitOrder:  flight.NextWaitOrder,
-		CreatedAt:      flight.CreatedAt,
-		UpdatedAt:      flight.UpdatedAt,
-		Remain:         int(flight.AvailableSeats) + int(flight.WaitSeats),
+	response := FlightResponse{
+		ID:               flight.ID,
+		Departure:        flight.Departure,
+		Destination:      flight.Destination,
+		FlightDate:       flight.FlightDate,
+		Price:            flight.Price,
+		AvailableSeats:   flight.AvailableSeats,
+		WaitSeats:        flight.WaitSeats,
+		NextWaitOrder:    flight.NextWaitOrder,
+		Capacity:         flight.Capacity,
+		WaitCapacity:     flight.WaitCapacity,
+		CarrierCode:      flight.CarrierCode,
+		FlightNumber:     flight.FlightNumber,
+		FlightDesignator: flight.Designator(),
+		AircraftType:     flight.AircraftType,
+		CreatedAt:        flight.CreatedAt,
+		UpdatedAt:        flight.UpdatedAt,
+		Remain:           int(flight.AvailableSeats) + int(flight.WaitSeats),
 	}
+	response.WaitCapacityOverride = flight.WaitCapacityOverride
+	if flight.ArrivalDate.Valid {
+		arrivalDate := flight.ArrivalDate.Time.UTC()
+		response.ArrivalDate = &arrivalDate
+	}
+	return response
 }
 
 type CreateOrderResponse struct {
@@ -46,6 +80,11 @@ type CreateOrderResponse struct {
 	WaitOrder     int64  `json:"wait_order"`
 	TicketNumbers int64  `json:"ticket_numbers"`
 	IsWait        bool   `json:"is_wait"`
+	PNR           string `json:"pnr"`
+	ItineraryID   string `json:"itinerary_id,omitempty"`
+	LegSequence   int16  `json:"leg_sequence,omitempty"`
+	// flight of each segment in travel order
+	SegmentFlightIDs []string `json:"segment_flight_ids,omitempty"`
 }
 
 func ConvertCreateOrderEventToResponse(event CreateOrderEvent) CreateOrderResponse {
@@ -59,24 +98,75 @@ func ConvertCreateOrderEventToResponse(event CreateOrderEvent) CreateOrderRespon
 	response.FlightID = event.FlightID
 	response.TicketNumbers = event.TicketNumbers
 	response.IsWait = event.IsWait
+	response.PNR = event.PNR
+	response.ItineraryID = event.ItineraryID
+	response.LegSequence = event.LegSequence
+	for _, segment := range event.Segments {
+		response.SegmentFlightIDs = append(response.SegmentFlightIDs, segment.FlightID)
+	}
 	return response
 }
 
+type PassengerResponse struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	DateOfBirth    string `json:"date_of_birth"`
+	DocumentNumber string `json:"document_number"`
+	TicketNumber   string `json:"ticket_number,omitempty"`
+	TicketIssuedAt string `json:"ticket_issued_at,omitempty"`
+	SeatNumber     string `json:"seat_number,omitempty"`
+	CheckedInAt    string `json:"checked_in_at,omitempty"`
+}
+
 type QueryOrderResponse struct {
-	ID            string    `json:"id"`
-	FlightID      string    `json:"flight_id"`
-	CreatedAt     time.Time `json:"created_at"`
-	CanceledAt    string    `json:"canceled_at,omitempty"`
-	PaidAt        string    `json:"paid_at,omitempty"`
-	WaitOrder     int32     `json:"wait_order"`
-	TicketNumbers int32     `json:"ticket_numbers"`
+	ID            string                 `json:"id"`
+	FlightID      string                 `json:"flight_id"`
+	PNR           string                 `json:"pnr,omitempty"`
+	Status        string                 `json:"status"`
+	CreatedAt     time.Time              `json:"created_at"`
+	CanceledAt    string                 `json:"canceled_at,omitempty"`
+	PaidAt        string                 `json:"paid_at,omitempty"`
+	WaitOrder     int32                  `json:"wait_order"`
+	TicketNumbers int32                  `json:"ticket_numbers"`
+	ItineraryID   string                 `json:"itinerary_id,omitempty"`
+	LegSequence   int16                  `json:"leg_sequence,omitempty"`
+	Segments      []OrderSegmentResponse `json:"segments,omitempty"`
+	Passengers    []PassengerResponse    `json:"passengers"`
+}
+
+type OrderSegmentResponse struct {
+	SegmentSequence int16  `json:"segment_sequence"`
+	FlightID        string `json:"flight_id"`
 }
 
-func ConvertOrderEntityToResponse(order Order) QueryOrderResponse {
+func ConvertPassengerEntityToResponse(passenger Passenger) PassengerResponse {
+	response := PassengerResponse{
+		ID:             passenger.ID.String(),
+		Name:           passenger.Name,
+		DateOfBirth:    passenger.DateOfBirth.Format(time.DateOnly),
+		DocumentNumber: passenger.DocumentNumber,
+	}
+	if passenger.TicketNumber.Valid {
+		response.TicketNumber = passenger.TicketNumber.String
+	}
+	if passenger.TicketIssuedAt.Valid {
+		response.TicketIssuedAt = passenger.TicketIssuedAt.Time.UTC().String()
+	}
+	if passenger.SeatNumber.Valid {
+		response.SeatNumber = passenger.SeatNumber.String
+	}
+	if passenger.CheckedInAt.Valid {
+		response.CheckedInAt = passenger.CheckedInAt.Time.UTC().String()
+	}
+	return response
+}
+
+func ConvertOrderEntityToResponse(order Order, passengers []Passenger) QueryOrderResponse {
 	var response QueryOrderResponse
 	response.ID = order.ID.String()
 	response.FlightID = order.FlightID.String()
 	response.CreatedAt = order.CreatedAt
+	response.Status = order.Status()
 	if order.CanceledAt.Valid {
 		response.CanceledAt = order.CanceledAt.Time.UTC().String()
 	}
@@ -85,5 +175,362 @@ func ConvertOrderEntityToResponse(order Order) QueryOrderResponse {
 	}
 	response.TicketNumbers = order.TicketNumbers
 	response.WaitOrder = order.WaitOrder
+	if order.PNR.Valid {
+		response.PNR = order.PNR.String
+	}
+	if order.ItineraryID.Valid {
+		response.ItineraryID = order.ItineraryID.UUID.String()
+		response.LegSequence = order.LegSequence.Int16
+	}
+	response.Passengers = make([]PassengerResponse, 0, len(passengers))
+	for _, passenger := range passengers {
+		response.Passengers = append(response.Passengers, ConvertPassengerEntityToResponse(passenger))
+	}
+	return response
+}
+
+func ConvertOrderSegmentsToResponse(segments []OrderSegment) []OrderSegmentResponse {
+	if len(segments) == 0 {
+		return nil
+	}
+	response := make([]OrderSegmentResponse, 0, len(segments))
+	for _, segment := range segments {
+		response = append(response, OrderSegmentResponse{
+			SegmentSequence: segment.SegmentSequence,
+			FlightID:        segment.FlightID.String(),
+		})
+	}
+	return response
+}
+
+type TicketResponse struct {
+	TicketNumber  string    `json:"ticket_number"`
+	IssuedAt      time.Time `json:"issued_at"`
+	PassengerID   string    `json:"passenger_id"`
+	PassengerName string    `json:"passenger_name"`
+	OrderID       string    `json:"order_id"`
+	FlightID      string    `json:"flight_id"`
+	PNR           string    `json:"pnr"`
+	FlightIDs     []string  `json:"flight_ids"`
+}
+
+func ConvertTicketEntityToResponse(ticket Ticket) TicketResponse {
+	flightIDs := make([]string, 0, len(ticket.FlightIDs))
+	for _, flightID := range ticket.FlightIDs {
+		flightIDs = append(flightIDs, flightID.String())
+	}
+	return TicketResponse{
+		TicketNumber:  ticket.TicketNumber,
+		IssuedAt:      ticket.IssuedAt,
+		PassengerID:   ticket.PassengerID.String(),
+		PassengerName: ticket.PassengerName,
+		OrderID:       ticket.OrderID.String(),
+		FlightID:      ticket.FlightID.String(),
+		PNR:           ticket.PNR,
+		FlightIDs:     flightIDs,
+	}
+}
+
+type SeatResponse struct {
+	SeatNumber string `json:"seat_number"`
+	RowNumber  int32  `json:"row_number"`
+	Letter     string `json:"letter"`
+	Cabin      string `json:"cabin"`
+	IsExitRow  bool   `json:"is_exit_row"`
+	IsBlocked  bool   `json:"is_blocked"`
+	IsOccupied bool   `json:"is_occupied"`
+}
+
+type SeatMapResponse struct {
+	FlightID  string         `json:"flight_id"`
+	Available int            `json:"available"`
+	Seats     []SeatResponse `json:"seats"`
+}
+
+func ConvertFlightSeatsToSeatMapResponse(flightID string, seats []FlightSeat, occupied map[string]string) SeatMapResponse {
+	response := SeatMapResponse{
+		FlightID: flightID,
+		Seats:    make([]SeatResponse, 0, len(seats)),
+	}
+	for _, seat := range seats {
+		_, isOccupied := occupied[seat.SeatNumber]
+		if !isOccupied && !seat.IsBlocked {
+			response.Available++
+		}
+		response.Seats = append(response.Seats, SeatResponse{
+			SeatNumber: seat.SeatNumber,
+			RowNumber:  seat.RowNumber,
+			Letter:     seat.Letter,
+			Cabin:      seat.Cabin,
+			IsExitRow:  seat.IsExitRow,
+			IsBlocked:  seat.IsBlocked,
+			IsOccupied: isOccupied,
+		})
+	}
+	return response
+}
+
+type BoardingPassResponse struct {
+	PassengerID      string    `json:"passenger_id"`
+	PassengerName    string    `json:"passenger_name"`
+	PNR              string    `json:"pnr"`
+	TicketNumber     string    `json:"ticket_number"`
+	FlightID         string    `json:"flight_id"`
+	FlightDesignator string    `json:"flight_designator"`
+	Departure        string    `json:"departure"`
+	Destination      string    `json:"destination"`
+	FlightDate       time.Time `json:"flight_date"`
+	SeatNumber       string    `json:"seat_number"`
+	CheckinSequence  int32     `json:"checkin_sequence"`
+	Barcode          string    `json:"barcode"`
+	BarcodePNG       string    `json:"barcode_png"`
+}
+
+type CheckinResponse struct {
+	OrderID        string                 `json:"order_id"`
+	PNR            string                 `json:"pnr"`
+	BoardingPasses []BoardingPassResponse `json:"boarding_passes"`
+}
+
+type GateClosureResponse struct {
+	GateClosure
+	DeniedBoardings []DeniedBoarding `json:"denied_boardings"`
+}
+
+type NoShowRateResponse struct {
+	Booked     int32   `json:"booked"`
+	NoShows    int32   `json:"no_shows"`
+	NoShowRate float64 `json:"no_show_rate"`
+}
+
+type NoShowDayOfWeekResponse struct {
+	DayOfWeek int16 `json:"day_of_week"`
+	NoShowRateResponse
+}
+
+type NoShowLeadTimeResponse struct {
+	LeadTimeBucket string `json:"lead_time_bucket"`
+	NoShowRateResponse
+}
+
+type NoShowStatsResponse struct {
+	Departure   string                    `json:"departure"`
+	Destination string                    `json:"destination"`
+	Overall     NoShowRateResponse        `json:"overall"`
+	ByDayOfWeek []NoShowDayOfWeekResponse `json:"by_day_of_week"`
+	ByLeadTime  []NoShowLeadTimeResponse  `json:"by_lead_time"`
+	Stats       []NoShowStat              `json:"stats"`
+}
+
+type ScheduleResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	CarrierCode    string     `json:"carrier_code"`
+	FlightNumber   string     `json:"flight_number"`
+	AircraftType   string     `json:"aircraft_type,omitempty"`
+	Departure      string     `json:"departure"`
+	Destination    string     `json:"destination"`
+	DaysOfWeek     []int      `json:"days_of_week"`
+	DepartureTime  string     `json:"departure_time"`
+	EffectiveFrom  string     `json:"effective_from"`
+	EffectiveTo    string     `json:"effective_to,omitempty"`
+	Capacity       int32      `json:"capacity"`
+	Price          float64    `json:"price"`
+	WaitSeats      *int32     `json:"wait_seats"`
+	SeatMapID      *uuid.UUID `json:"seat_map_id"`
+	BlockMinutes   *int32     `json:"block_minutes,omitempty"`
+	IsActive       bool       `json:"is_active"`
+	GeneratedUntil string     `json:"generated_until,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func ConvertScheduleEntityToResponse(schedule Schedule) ScheduleResponse {
+	response := ScheduleResponse{
+		ID:            schedule.ID,
+		CarrierCode:   schedule.CarrierCode,
+		FlightNumber:  schedule.FlightNumber,
+		AircraftType:  schedule.AircraftType.String,
+		Departure:     schedule.Departure,
+		Destination:   schedule.Destination,
+		DaysOfWeek:    []int{},
+		DepartureTime: schedule.DepartureTime,
+		EffectiveFrom: schedule.EffectiveFrom.Format(time.DateOnly),
+		Capacity:      schedule.Capacity,
+		Price:         schedule.Price,
+		IsActive:      schedule.IsActive,
+		CreatedAt:     schedule.CreatedAt,
+		UpdatedAt:     schedule.UpdatedAt,
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if schedule.OperatesOn(weekday) {
+			response.DaysOfWeek = append(response.DaysOfWeek, int(weekday))
+		}
+	}
+	if schedule.EffectiveTo.Valid {
+		response.EffectiveTo = schedule.EffectiveTo.Time.Format(time.DateOnly)
+	}
+	if schedule.WaitSeats.Valid {
+		response.WaitSeats = &schedule.WaitSeats.Int32
+	}
+	if schedule.BlockMinutes.Valid {
+		response.BlockMinutes = &schedule.BlockMinutes.Int32
+	}
+	if schedule.SeatMapID.Valid {
+		response.SeatMapID = &schedule.SeatMapID.UUID
+	}
+	if schedule.GeneratedUntil.Valid {
+		response.GeneratedUntil = schedule.GeneratedUntil.Time.Format(time.DateOnly)
+	}
+	return response
+}
+
+type GenerateScheduleResponse struct {
+	ScheduleID uuid.UUID        `json:"schedule_id"`
+	Created    []FlightResponse `json:"created"`
+}
+
+type ItineraryResponse struct {
+	Stops      int64   `json:"stops"`
+	TotalPrice float64 `json:"total_price"`
+	// least remain seats across legs, live from cache store
+	Remain int64 `json:"remain"`
+	// minutes between arrival and next departure at each connecting airport
+	ConnectionMinutes []int64          `json:"connection_minutes"`
+	Legs              []FlightResponse `json:"legs"`
+}
+
+type ItinerariesFetchResponse struct {
+	Itineraries []ItineraryResponse `json:"itineraries"`
+	Pagination
+}
+
+type CreateItineraryOrderResponse struct {
+	ItineraryID string                `json:"itinerary_id"`
+	Orders      []CreateOrderResponse `json:"orders"`
+}
+
+type QueryItineraryOrderResponse struct {
+	ItineraryID string               `json:"itinerary_id"`
+	Orders      []QueryOrderResponse `json:"orders"`
+}
+
+type FareCalendarDay struct {
+	Date string `json:"date"`
+	// null when no bookable flight on date
+	LowestPrice    *float64 `json:"lowest_price"`
+	Flights        int64    `json:"flights"`
+	AvailableSeats int64    `json:"available_seats"`
+}
+
+type FareCalendarResponse struct {
+	Departure    string            `json:"departure"`
+	Destination  string            `json:"destination"`
+	Timezone     string            `json:"timezone"`
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	CheapestDate string            `json:"cheapest_date,omitempty"`
+	Days         []FareCalendarDay `json:"days"`
+}
+
+type CustomerResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ConvertCustomerToResponse(customer Customer) CustomerResponse {
+	return CustomerResponse{
+		ID:        customer.ID.String(),
+		Email:     customer.Email,
+		Name:      customer.Name,
+		Phone:     customer.Phone.String,
+		CreatedAt: customer.CreatedAt,
+		UpdatedAt: customer.UpdatedAt,
+	}
+}
+
+type CustomerOrdersFetchResponse struct {
+	Orders []QueryOrderResponse `json:"orders"`
+	Pagination
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// seconds until token expires
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+type AgencyAPIKeyResponse struct {
+	ID                string    `json:"id"`
+	AgencyID          string    `json:"agency_id"`
+	Prefix            string    `json:"prefix"`
+	Name              string    `json:"name"`
+	DailyRequestQuota int32     `json:"daily_request_quota"`
+	CreatedAt         time.Time `json:"created_at"`
+	RevokedAt         string    `json:"revoked_at,omitempty"`
+	// plaintext key, only returned when issued
+	Key string `json:"key,omitempty"`
+}
+
+func ConvertAgencyAPIKeyToResponse(apiKey AgencyAPIKey) AgencyAPIKeyResponse {
+	response := AgencyAPIKeyResponse{
+		ID:                apiKey.ID.String(),
+		AgencyID:          apiKey.AgencyID.String(),
+		Prefix:            apiKey.Prefix,
+		Name:              apiKey.Name,
+		DailyRequestQuota: apiKey.DailyRequestQuota,
+		CreatedAt:         apiKey.CreatedAt,
+	}
+	if apiKey.RevokedAt.Valid {
+		response.RevokedAt = apiKey.RevokedAt.Time.UTC().Format(time.RFC3339)
+	}
 	return response
 }
+
+type AgencyCommissionResponse struct {
+	AgencyID       string    `json:"agency_id"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	Orders         int64     `json:"orders"`
+	Tickets        int64     `json:"tickets"`
+	Revenue        float64   `json:"revenue"`
+	CommissionRate float64   `json:"commission_rate"`
+	Commission     float64   `json:"commission"`
+}
+
+type WaitingRoomTicketResponse struct {
+	Token         string     `json:"token"`
+	FlightID      string     `json:"flight_id"`
+	Status        string     `json:"status"`
+	Position      int64      `json:"position,omitempty"`
+	AdmittedUntil *time.Time `json:"admitted_until,omitempty"`
+}
+
+func ConvertWaitingRoomTicketToResponse(token string, flightID string, ticket WaitingRoomTicket) WaitingRoomTicketResponse {
+	response := WaitingRoomTicketResponse{
+		Token:    token,
+		FlightID: flightID,
+		Status:   ticket.Status,
+		Position: ticket.Position,
+	}
+	if ticket.Status == WaitingRoomStatusAdmitted {
+		admittedUntil := ticket.AdmittedUntil.UTC()
+		response.AdmittedUntil = &admittedUntil
+	}
+	return response
+}
+
+type WaitingRoomResponse struct {
+	FlightID         string `json:"flight_id"`
+	AdmitPerSecond   int64  `json:"admit_per_second"`
+	AdmissionSeconds int64  `json:"admission_seconds"`
+}
+
+type AbuseDecisionsFetchResponse struct {
+	Decisions []AbuseDecision `json:"decisions"`
+	Pagination
+}
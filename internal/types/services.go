@@ -1,10 +1,82 @@
 package types
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type OrderServcie interface {
 	CreateOrderHandler(ctx context.Context,
 		createOrderParam CreateOrderEntityParam,
 		updateFlightParam UpdateFlightEntityParam,
+		createPassengerParams []CreatePassengerEntityParam,
 	) (Flight, Order, error)
+	CreateSegmentedOrderHandler(ctx context.Context,
+		createOrderParam CreateOrderEntityParam,
+		updateFlightParams []UpdateFlightEntityParam,
+		createPassengerParams []CreatePassengerEntityParam,
+	) ([]Flight, Order, error)
+	CreateItineraryOrdersHandler(ctx context.Context, legs []ItineraryLegEntityParam) ([]Flight, []Order, error)
+	PayOrderHandler(ctx context.Context, orderID uuid.UUID) (Order, []Passenger, error)
+}
+
+type TicketService interface {
+	IssueTickets(tx *sql.Tx, ctx context.Context, order Order, passengers []Passenger) ([]Passenger, error)
+}
+
+type CheckinService interface {
+	CheckinOrder(ctx context.Context, orderID uuid.UUID) (Order, FlightResponse, []Passenger, error)
+}
+
+type DeniedBoardingService interface {
+	CloseGate(ctx context.Context, flightID uuid.UUID) (GateClosure, []DeniedBoarding, error)
+}
+
+type OverbookingService interface {
+	ComputeWaitSeats(ctx context.Context, departure string, destination string, flightDate time.Time, capacity int64) (int64, error)
+	RecomputeFlight(ctx context.Context, flightID uuid.UUID) (Flight, error)
+}
+
+type AirportDirectory interface {
+	Location(ctx context.Context, iataCode string) (*time.Location, error)
+	LocalizeFlights(ctx context.Context, flights []FlightResponse) error
+}
+
+type FlightService interface {
+	CreateFlight(ctx context.Context, createFlight CreateFlightRequest) (Flight, error)
+}
+
+type ScheduleService interface {
+	GenerateFlights(ctx context.Context, schedule Schedule, days int) ([]Flight, error)
+}
+
+type ItineraryService interface {
+	BookItinerary(ctx context.Context, createItineraryOrder CreateItineraryOrderRequest) (CreateItineraryOrderResponse, error)
+}
+
+type CustomerService interface {
+	Register(ctx context.Context, createCustomer CreateCustomerRequest) (Customer, error)
+	Authenticate(ctx context.Context, email string, password string) (Customer, error)
+	GetCustomer(ctx context.Context, customerID uuid.UUID) (Customer, error)
+	UpdateProfile(ctx context.Context, customerID uuid.UUID, updateCustomer UpdateCustomerRequest) (Customer, error)
+}
+
+type TokenIssuer interface {
+	IssueToken(subject string, roles []string) (string, time.Duration, error)
+}
+
+type AgencyService interface {
+	CreateAgency(ctx context.Context, createAgency CreateAgencyRequest) (Agency, error)
+	IssueAPIKey(ctx context.Context, agencyID uuid.UUID, createAPIKey CreateAgencyAPIKeyRequest) (AgencyAPIKey, string, error)
+	RevokeAPIKey(ctx context.Context, agencyID uuid.UUID, keyID uuid.UUID) (AgencyAPIKey, error)
+	Authenticate(ctx context.Context, rawKey string) (AgencyCredential, error)
+	ConsumeRequestQuota(ctx context.Context, apiKey AgencyAPIKey) (int64, error)
+}
+
+type AbuseService interface {
+	Evaluate(ctx context.Context, request AbuseRequest) (AbuseEvaluation, error)
+	Record(ctx context.Context, evaluation AbuseEvaluation, orderIDs []string) error
 }
@@ -3,6 +3,7 @@ package types
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -10,21 +11,176 @@ import (
 type OrderStore interface {
 	CreateOrder(tx *sql.Tx, ctx context.Context, createOrderInfo CreateOrderEntityParam) (Order, error)
 	GetOrderById(ctx context.Context, orderID uuid.UUID) (Order, error)
+	GetOrderByPNR(ctx context.Context, pnr string) (Order, error)
+	GetOrdersByItineraryID(ctx context.Context, itineraryID uuid.UUID) ([]Order, error)
+	GetOrdersByCustomerID(ctx context.Context, customerID uuid.UUID, queryParams QueryCustomerOrdersRequest, pagination Pagination) ([]Order, Pagination, error)
+	CreateOrderSegments(tx *sql.Tx, ctx context.Context, orderID uuid.UUID, flightIDs []uuid.UUID) ([]OrderSegment, error)
+	GetOrderSegments(ctx context.Context, orderID uuid.UUID) ([]OrderSegment, error)
+	CreatePassengers(tx *sql.Tx, ctx context.Context, passengers []CreatePassengerEntityParam) ([]Passenger, error)
+	GetPassengersByOrderID(ctx context.Context, orderID uuid.UUID) ([]Passenger, error)
+	PayOrder(tx *sql.Tx, ctx context.Context, orderID uuid.UUID) (Order, error)
+	UpdatePassengerSeats(ctx context.Context, seatParams []PassengerSeatParam) ([]Passenger, error)
+	CheckinPassengers(ctx context.Context, flightID uuid.UUID, passengerIDs []uuid.UUID) ([]Passenger, error)
+	VolunteerPassengers(ctx context.Context, orderID uuid.UUID, passengerIDs []uuid.UUID) ([]Passenger, error)
+}
+
+type BoardingStore interface {
+	GetBoardingCandidates(ctx context.Context, flightID uuid.UUID) ([]BoardingCandidate, error)
+	GetFlightsPendingGateClose(ctx context.Context, departBefore time.Time) ([]uuid.UUID, error)
+	GetGateClosure(ctx context.Context, flightID uuid.UUID) (GateClosure, error)
+	CloseGate(ctx context.Context, closure GateClosure, deniedBoardings []DeniedBoarding) (GateClosure, bool, error)
+	UpdateRebooking(ctx context.Context, rebooking DeniedBoardingRebooking, expectedOrderID uuid.NullUUID) (bool, error)
+	GetDeniedBoardingsByFlightID(ctx context.Context, flightID uuid.UUID) ([]DeniedBoarding, error)
+}
+
+type CheckinCacheStore interface {
+	IncrCheckedIn(ctx context.Context, flightID string, count int64) (int64, error)
+	GetCheckedInCount(ctx context.Context, flightID string) (int64, error)
+}
+
+type SeatMapStore interface {
+	CreateTemplate(ctx context.Context, createParams CreateSeatMapTemplateRequest) (SeatMapTemplate, error)
+	GetTemplateById(ctx context.Context, templateID uuid.UUID) (SeatMapTemplate, error)
+	CreateFlightSeats(ctx context.Context, seats []FlightSeat) error
+	GetFlightSeats(ctx context.Context, flightID uuid.UUID) ([]FlightSeat, error)
+}
+
+type SeatCacheStore interface {
+	AssignSeats(ctx context.Context, assignParam SeatCacheAssignParam) (SeatCacheAssignResult, error)
+	RestoreSeats(ctx context.Context, restoreParam SeatCacheAssignParam) error
+	GetOccupiedSeats(ctx context.Context, flightID string) (map[string]string, error)
+}
+
+type TicketStore interface {
+	NextSerials(tx *sql.Tx, ctx context.Context, airlinePrefix string, count int64) (int64, error)
+	IssueTicket(tx *sql.Tx, ctx context.Context, passengerID uuid.UUID, ticketNumber string) (Passenger, error)
+	GetTicketByNumber(ctx context.Context, ticketNumber string) (Ticket, error)
 }
 
 type OrderCacheStore interface {
 	CreateOrder(ctx context.Context, createOrderParam OrderCacheCreateParam) (OrderCacheResult, error)
 	GetCurrentRemain(ctx context.Context, getOrderRemain OrderCacheParam) (OrderCacheRemain, error)
+	GetCurrentRemains(ctx context.Context, getOrderRemains []OrderCacheParam) ([]OrderCacheRemain, error)
+	CreateSegmentedOrder(ctx context.Context, createParam OrderCacheSegmentsCreateParam) (OrderCacheSegmentsResult, error)
+	ReleaseOrder(ctx context.Context, releaseParam OrderCacheReleaseParam) (OrderCacheResult, error)
+	ReservePNR(ctx context.Context, pnr string, orderID string) (bool, error)
+	ReleasePNR(ctx context.Context, pnr string, orderID string) error
+	AdjustWait(ctx context.Context, adjustParam WaitCacheAdjustParam) (WaitCacheAdjustResult, error)
 }
 
 type FlightCacheStore interface {
 	UpdateFlight(ctx context.Context, flightInfo Flight) (Flight, error)
 	GetFlightCacheInfo(ctx context.Context, fligtID string) (Flight, error)
+	FillFlightCacheInfo(ctx context.Context, flightInfo Flight) error
+	GetFareCalendar(ctx context.Context, key string) (FareCalendarResponse, bool, error)
+	SetFareCalendar(ctx context.Context, key string, calendar FareCalendarResponse, ttl time.Duration) error
+	GetFlightSearch(ctx context.Context, key string) (FlightsFetchResponse, bool, error)
+	SetFlightSearch(ctx context.Context, departure string, destination string, key string, result FlightsFetchResponse, ttl time.Duration) error
+	InvalidateFlightSearch(ctx context.Context, departure string, destination string) error
 }
 
 type FlightStore interface {
-	GetFlightsByCriteria(ctx context.Context, queryParams QueryFlightRequest, pagination Pagination) (FlightsFetchResponse, error)
+	GetFlightsByCriteria(ctx context.Context, queryParams QueryFlightRequest, location *time.Location, pagination Pagination) (FlightsFetchResponse, error)
 	CreateFlight(ctx context.Context, createParams CreateFlightRequest) (Flight, error)
 	GetFlightById(ctx context.Context, flightID uuid.UUID) (FlightResponse, error)
 	UpdateFlight(tx *sql.Tx, ctx context.Context, updateFlightParams UpdateFlightEntityParam) (Flight, error)
+	GetNextFlightsOnRoute(ctx context.Context, departure string, destination string, after time.Time, limit uint64) ([]Flight, error)
+	UpdateWaitCapacity(ctx context.Context, flightID uuid.UUID, waitCapacity int32, waitSeats int32) (Flight, error)
+	GetFlightsDepartingBetween(ctx context.Context, from time.Time, to time.Time) ([]Flight, error)
+	GetFlightsByIds(ctx context.Context, flightIDs []uuid.UUID) ([]Flight, error)
+	GetFlightByScheduleDate(ctx context.Context, scheduleID uuid.UUID, flightDate time.Time) (Flight, error)
+	GetFareCalendar(ctx context.Context, departure string, destination string, timezone string, from time.Time, to time.Time) ([]FareCalendarEntry, error)
+}
+
+type OverbookingPolicyStore interface {
+	CreatePolicy(ctx context.Context, createParams CreateOverbookingPolicyRequest) (OverbookingPolicy, error)
+	GetPolicies(ctx context.Context, departure string, destination string) ([]OverbookingPolicy, error)
+	GetEffectivePolicy(ctx context.Context, departure string, destination string, flightDate time.Time) (OverbookingPolicy, error)
+}
+
+type NoShowStore interface {
+	GetFlightsPendingOutcome(ctx context.Context, departedBefore time.Time, limit uint64) ([]uuid.UUID, error)
+	RecordFlightOutcomes(ctx context.Context, flightID uuid.UUID) (int64, error)
+	ComputeStats(ctx context.Context) (int64, error)
+	GetRouteStats(ctx context.Context, departure string, destination string) ([]NoShowStat, error)
+}
+
+type CustomerStore interface {
+	CreateCustomer(ctx context.Context, customer Customer) (Customer, error)
+	GetCustomerById(ctx context.Context, customerID uuid.UUID) (Customer, error)
+	GetCustomerByEmail(ctx context.Context, email string) (Customer, error)
+	UpdateCustomer(ctx context.Context, customer Customer) (Customer, error)
+}
+
+type AgencyStore interface {
+	CreateAgency(ctx context.Context, createParams CreateAgencyRequest) (Agency, error)
+	GetAgencies(ctx context.Context) ([]Agency, error)
+	GetAgencyById(ctx context.Context, agencyID uuid.UUID) (Agency, error)
+	CreateAPIKey(ctx context.Context, apiKey AgencyAPIKey) (AgencyAPIKey, error)
+	GetAPIKeysByAgencyID(ctx context.Context, agencyID uuid.UUID) ([]AgencyAPIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (AgencyAPIKey, error)
+	RevokeAPIKey(ctx context.Context, agencyID uuid.UUID, keyID uuid.UUID) (AgencyAPIKey, error)
+	GetCommission(ctx context.Context, agencyID uuid.UUID, from time.Time, to time.Time) (AgencyCommission, error)
+}
+
+type AgencyCacheStore interface {
+	GetCredential(ctx context.Context, prefix string) (AgencyCredential, bool, error)
+	SetCredential(ctx context.Context, credential AgencyCredential, ttl time.Duration) error
+	DeleteCredential(ctx context.Context, prefix string) error
+	IncrRequestCount(ctx context.Context, keyID string, day string, ttl time.Duration) (int64, error)
+	ReserveAllotment(ctx context.Context, allotmentParam AgencyAllotmentParam) (bool, error)
+	ReleaseAllotment(ctx context.Context, allotmentParam AgencyAllotmentParam) error
+}
+
+type WaitingRoomCacheStore interface {
+	OpenRoom(ctx context.Context, roomParam WaitingRoomParam) error
+	CloseRoom(ctx context.Context, flightID string) error
+	GetRoomFlightIDs(ctx context.Context) ([]string, error)
+	Join(ctx context.Context, flightID string, holder string, token string) (string, WaitingRoomTicket, error)
+	GetTicket(ctx context.Context, flightID string, token string) (WaitingRoomTicket, error)
+	Admit(ctx context.Context, flightID string) (int64, error)
+	ConsumeAdmission(ctx context.Context, flightID string, token string) (WaitingRoomTicket, error)
+	RestoreAdmission(ctx context.Context, flightID string, token string, admittedUntil time.Time) error
+}
+
+type AbuseStore interface {
+	CreateDecision(ctx context.Context, decision AbuseDecision) error
+	GetDecisions(ctx context.Context, action string, pagination Pagination) ([]AbuseDecision, Pagination, error)
+	CountUnpaidOrders(ctx context.Context, orderIDs []uuid.UUID, createdBefore time.Time) (int64, error)
+}
+
+type AbuseCacheStore interface {
+	TrackAttempt(ctx context.Context, attemptParam AbuseAttemptParam) (AbuseAttemptResult, error)
+	AddOrders(ctx context.Context, actors []string, orderIDs []string, history time.Duration) error
+	GetOrders(ctx context.Context, actors []string, createdBefore time.Time) ([]string, error)
+	IssueChallenge(ctx context.Context, challengeID string, bits int64, ttl time.Duration) error
+	ConsumeChallenge(ctx context.Context, challengeID string) (int64, bool, error)
+}
+
+type RateLimitCacheStore interface {
+	Take(ctx context.Context, buckets []RateLimitBucket) (RateLimitResult, error)
+}
+
+type AirportStore interface {
+	CreateAirport(ctx context.Context, createParams CreateAirportRequest) (Airport, error)
+	GetAirportByCode(ctx context.Context, iataCode string) (Airport, error)
+	GetAirports(ctx context.Context) ([]Airport, error)
+}
+
+type ScheduleStore interface {
+	CreateSchedule(ctx context.Context, schedule Schedule) (Schedule, error)
+	GetScheduleById(ctx context.Context, scheduleID uuid.UUID) (Schedule, error)
+	GetSchedules(ctx context.Context) ([]Schedule, error)
+	GetActiveSchedules(ctx context.Context, from time.Time) ([]Schedule, error)
+	UpdateGeneratedUntil(ctx context.Context, scheduleID uuid.UUID, generatedUntil time.Time) error
+}
+
+type AircraftTypeStore interface {
+	CreateAircraftType(ctx context.Context, createParams CreateAircraftTypeRequest) (AircraftType, error)
+	GetAircraftTypeByCode(ctx context.Context, code string) (AircraftType, error)
+	GetAircraftTypes(ctx context.Context) ([]AircraftType, error)
+}
+
+type ItineraryStore interface {
+	SearchItineraries(ctx context.Context, queryParams QueryItineraryRequest, pagination Pagination) ([]ItineraryCandidate, error)
 }
@@ -1,13 +1,20 @@
 package types
 
 import (
+	"database/sql"
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// offset mode or keyset mode when cursor is given, offset is ignored in keyset mode
 type Pagination struct {
-	NextOffset int64 `json:"next_offset"`
-	Offset     int64 `json:"offset"`
-	Limit      int64 `json:"limit"`
+	NextOffset int64  `json:"next_offset"`
+	Offset     int64  `json:"offset"`
+	Limit      int64  `json:"limit"`
+	Cursor     string `json:"cursor,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
 }
 
 type UpdateFlightEntityParam struct {
@@ -18,11 +25,34 @@ type UpdateFlightEntityParam struct {
 }
 
 type CreateOrderEntityParam struct {
-	ID            uuid.UUID `json:"id" db:"id"`
-	FlightID      uuid.UUID `json:"flight_id" db:"flight_id"`
-	WaitOrder     int32     `json:"wait_order,omitempty" db:"wait_order"`
-	TicketNumbers int32     `json:"ticket_numbers" db:"ticket_numbers"`
+	ID            uuid.UUID     `json:"id" db:"id"`
+	FlightID      uuid.UUID     `json:"flight_id" db:"flight_id"`
+	WaitOrder     int32         `json:"wait_order,omitempty" db:"wait_order"`
+	TicketNumbers int32         `json:"ticket_numbers" db:"ticket_numbers"`
+	PNR           string        `json:"pnr" db:"pnr"`
+	ItineraryID   uuid.NullUUID `json:"itinerary_id" db:"itinerary_id"`
+	LegSequence   sql.NullInt16 `json:"leg_sequence" db:"leg_sequence"`
+	CustomerID    uuid.NullUUID `json:"customer_id" db:"customer_id"`
+	AgencyID      uuid.NullUUID `json:"agency_id" db:"agency_id"`
+	// price per ticket when booked, empty for events published before fares were kept
+	Fare sql.NullFloat64 `json:"fare" db:"fare"`
 }
+
+type CreatePassengerEntityParam struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	OrderID        uuid.UUID `json:"order_id" db:"order_id"`
+	Name           string    `json:"name" db:"name"`
+	DateOfBirth    time.Time `json:"date_of_birth" db:"date_of_birth"`
+	DocumentNumber string    `json:"document_number" db:"document_number"`
+}
+
+// ItineraryLegEntityParam: order of one itinerary leg with passengers and counters of its flight
+type ItineraryLegEntityParam struct {
+	CreateOrder  CreateOrderEntityParam       `json:"create_order"`
+	UpdateFlight UpdateFlightEntityParam      `json:"update_flight"`
+	Passengers   []CreatePassengerEntityParam `json:"passengers"`
+}
+
 type OrderCacheParam struct {
 	FlightID         string `json:"flight_id" validate:"required"`
 	CurrentTotal     int64  `json:"current_total" validate:"required"`
@@ -34,6 +64,13 @@ type OrderCacheCreateParam struct {
 	OrderCacheParam
 	TicketNumbers int64 `json:"ticket_numbers" validate:"required"`
 }
+
+// OrderCacheReleaseParam: give back seats taken by CreateOrder, IsWait releases to wait counter
+type OrderCacheReleaseParam struct {
+	OrderCacheParam
+	TicketNumbers int64 `json:"ticket_numbers" validate:"required"`
+	IsWait        bool  `json:"is_wait"`
+}
 type OrderCacheResult struct {
 	CurrentTotal     int64 `json:"current_total" validate:"required"`
 	CurrentWait      int64 `json:"current_wait" validate:"required"`
@@ -41,6 +78,222 @@ type OrderCacheResult struct {
 	IsValid          bool  `json:"is_valid"`
 	IsWait           bool  `json:"is_wait"`
 }
+
+// OrderCacheSegmentsCreateParam: reserve TicketNumbers confirmed seats on every segment or none
+type OrderCacheSegmentsCreateParam struct {
+	Segments      []OrderCacheParam `json:"segments" validate:"required"`
+	TicketNumbers int64             `json:"ticket_numbers" validate:"required"`
+}
+type OrderCacheSegmentsResult struct {
+	Segments []OrderCacheResult `json:"segments"`
+	IsValid  bool               `json:"is_valid"`
+}
 type OrderCacheRemain struct {
 	CurrentRemain int64 `json:"current_remain" validate:"required"`
 }
+
+type PassengerSeatParam struct {
+	PassengerID uuid.UUID `json:"passenger_id" db:"id"`
+	SeatNumber  string    `json:"seat_number" db:"seat_number"`
+}
+
+type SeatCacheAssignParam struct {
+	FlightID    string               `json:"flight_id" validate:"required"`
+	Assignments []PassengerSeatParam `json:"assignments" validate:"required"`
+}
+
+type SeatCacheAssignResult struct {
+	IsValid      bool   `json:"is_valid"`
+	ConflictSeat string `json:"conflict_seat,omitempty"`
+}
+
+type BoardingCandidate struct {
+	Passenger       Passenger     `json:"passenger"`
+	OrderCreatedAt  time.Time     `json:"order_created_at"`
+	OrderCustomerID uuid.NullUUID `json:"order_customer_id"`
+	OrderAgencyID   uuid.NullUUID `json:"order_agency_id"`
+	// OrderFare: fare per ticket paid for flight, kept on rebooked order
+	OrderFare float64 `json:"order_fare"`
+}
+
+const (
+	// bump passengers who checked in last first
+	SelectionLastCheckin = "last_checkin"
+	// bump passengers who booked last first
+	SelectionLastBooking = "last_booking"
+)
+
+type DeniedBoardingRules struct {
+	SelectionOrder         string        `json:"selection_order"`
+	VolunteerCompensation  float64       `json:"volunteer_compensation"`
+	ShortDelayCompensation float64       `json:"short_delay_compensation"`
+	LongDelayCompensation  float64       `json:"long_delay_compensation"`
+	LongDelay              time.Duration `json:"long_delay"`
+}
+
+// DeniedBoardingRebooking: rebooked flight and order of denied passengers of order, compensation applies to involuntary ones
+type DeniedBoardingRebooking struct {
+	FlightID         uuid.UUID     `json:"flight_id"`
+	OrderID          uuid.UUID     `json:"order_id"`
+	RebookedFlightID uuid.NullUUID `json:"rebooked_flight_id"`
+	RebookedOrderID  uuid.NullUUID `json:"rebooked_order_id"`
+	Compensation     float64       `json:"compensation"`
+}
+
+// WaitCapacity is the oversell flight should have, CurrentWaitCapacity is used when cache has none
+type WaitCacheAdjustParam struct {
+	OrderCacheParam
+	WaitCapacity        int64 `json:"wait_capacity"`
+	CurrentWaitCapacity int64 `json:"current_wait_capacity"`
+}
+
+// AppliedDelta differs from requested change when wait seats already sold exceed the new oversell
+type WaitCacheAdjustResult struct {
+	OrderCacheResult
+	WaitCapacity int64 `json:"wait_capacity"`
+	AppliedDelta int64 `json:"applied_delta"`
+}
+
+const (
+	OutcomeBoarded = "boarded"
+	OutcomeNoShow  = "no_show"
+	OutcomeDenied  = "denied"
+)
+
+// LeadTimeBucket: booking lead time range in days, MaxDays < 0 means unbounded
+type LeadTimeBucket struct {
+	Label   string
+	MinDays int
+	MaxDays int
+}
+
+// ItineraryCandidate: flights of itinerary in travel order with combined price
+type ItineraryCandidate struct {
+	LegIDs     []uuid.UUID `json:"leg_ids"`
+	Stops      int64       `json:"stops"`
+	TotalPrice float64     `json:"total_price"`
+}
+
+// FareCalendarEntry: bookable flights departing on local date of departure airport
+type FareCalendarEntry struct {
+	Date           time.Time `json:"date"`
+	LowestPrice    float64   `json:"lowest_price"`
+	Flights        int64     `json:"flights"`
+	AvailableSeats int64     `json:"available_seats"`
+}
+
+// AgencyCredential: api key with its agency, cached by key prefix
+type AgencyCredential struct {
+	Key    AgencyAPIKey `json:"key"`
+	Agency Agency       `json:"agency"`
+}
+
+type AgencyAllotmentParam struct {
+	AgencyID  string
+	FlightIDs []string
+	Seats     int64
+	Allotment int64
+	// counters expire after last flight departs
+	ExpireAt time.Time
+}
+
+// AgencyCommission: paid orders of agency within reporting period
+type AgencyCommission struct {
+	Orders  int64   `json:"orders"`
+	Tickets int64   `json:"tickets"`
+	Revenue float64 `json:"revenue"`
+}
+
+// RateLimitBucket: token bucket refilling Limit tokens every Window
+type RateLimitBucket struct {
+	Key    string
+	Limit  int64
+	Window time.Duration
+}
+
+// RateLimitResult: Limit, Remaining and Reset describe the bucket closest to exhaustion
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+const (
+	WaitingRoomStatusWaiting  = "waiting"
+	WaitingRoomStatusAdmitted = "admitted"
+	// token admission expired, used or unknown
+	WaitingRoomStatusExpired = "expired"
+	// flight has no waiting room, orders are not gated
+	WaitingRoomStatusClosed = "closed"
+)
+
+type WaitingRoomParam struct {
+	FlightID       string
+	AdmitPerSecond int64
+	AdmissionTTL   time.Duration
+	// room closes when flight departs
+	ExpireAt time.Time
+}
+
+// WaitingRoomTicket: Position only for waiting token, AdmittedUntil only for admitted token
+type WaitingRoomTicket struct {
+	Status        string
+	Position      int64
+	AdmittedUntil time.Time
+}
+
+// WaitingRoomAdmission: admitted token of gated flight, AdmittedUntil is known once token is consumed
+type WaitingRoomAdmission struct {
+	FlightID      string
+	Token         string
+	AdmittedUntil time.Time
+}
+
+const (
+	AbuseActionAllow     = "allow"
+	AbuseActionFlag      = "flag"
+	AbuseActionChallenge = "challenge"
+	AbuseActionBlock     = "block"
+)
+
+const (
+	// order attempts of actor within window
+	AbuseSignalVelocity = "velocity"
+	// distinct flights ordered by actor within window
+	AbuseSignalFlightSpread = "flight_spread"
+	// orders of actor left unpaid past hold time
+	AbuseSignalUnpaidExpired = "unpaid_expired"
+	// order attempts with the same passenger list within window
+	AbuseSignalIdenticalPassengers = "identical_passengers"
+)
+
+// AbuseRequest: order request to screen, actors look like ip:<ip>, customer:<id> or device:<fingerprint>
+type AbuseRequest struct {
+	Actors            []string
+	FlightIDs         []string
+	Passengers        []PassengerRequest
+	ChallengeResponse string
+}
+
+type AbuseAttemptParam struct {
+	AttemptID      string
+	Actors         []string
+	FlightIDs      []string
+	PassengersHash string
+	Window         time.Duration
+}
+
+// AbuseAttemptResult: highest counts among actors including current attempt
+type AbuseAttemptResult struct {
+	Attempts            int64
+	Flights             int64
+	IdenticalPassengers int64
+}
+
+// AbuseEvaluation: Challenge is the challenge issued when Action is challenge
+type AbuseEvaluation struct {
+	Decision  AbuseDecision
+	Challenge string
+}
@@ -1,22 +1,68 @@
 package util
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/yuanyu90221/airline-order-system/internal/apperr"
 )
 
-var Validdate = validator.New()
+var Validdate = newValidator()
+
+var (
+	// flight number without carrier, 1-4 digits with optional operational suffix
+	flightNumberPattern = regexp.MustCompile(`^[0-9]{1,4}[A-Z]?$`)
+	// two character iata airline designator, carrier codes may contain a digit but never two
+	carrierCodePattern = regexp.MustCompile(`^([A-Z][A-Z0-9]|[0-9][A-Z])$`)
+)
+
+// IsFlightNumber: flight number without carrier code, e.g. 100 or 100A
+func IsFlightNumber(flightNumber string) bool {
+	return flightNumberPattern.MatchString(flightNumber)
+}
+
+// IsCarrierCode: two character iata airline designator, e.g. ZZ or 9W
+func IsCarrierCode(carrierCode string) bool {
+	return carrierCodePattern.MatchString(carrierCode)
+}
+
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	// report fields by json name so validation errors match request payload
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || name == "" {
+			return field.Name
+		}
+		return name
+	})
+	FailOnError(validate.RegisterValidation("flight_number", func(fl validator.FieldLevel) bool {
+		return IsFlightNumber(fl.Field().String())
+	}), "failed to register flight_number validation")
+	FailOnError(validate.RegisterValidation("carrier_code", func(fl validator.FieldLevel) bool {
+		return IsCarrierCode(fl.Field().String())
+	}), "failed to register carrier_code validation")
+	return validate
+}
 
 func ParseJSON(r *http.Request, payload any) error {
 	if r.Body == nil {
-		return fmt.Errorf("missing request body")
+		return fmt.Errorf("%w: missing", ErrInvalidBody)
+	}
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBody, err)
 	}
-	return json.NewDecoder(r.Body).Decode(payload)
+	return nil
 }
 
 func WriteJSON(w http.ResponseWriter, status int, value any) error {
@@ -25,9 +71,15 @@ func WriteJSON(w http.ResponseWriter, status int, value any) error {
 	return json.NewEncoder(w).Encode(value)
 }
 
+/*
+*
+WriteError: write err as problem+json, status comes from code of err, given status is used for errors without code
+*/
 func WriteError(w http.ResponseWriter, status int, err error) {
-	errResp := WriteJSON(w, status, map[string]string{"error": err.Error()})
-	if errResp != nil {
+	problem := apperr.NewProblem(status, err)
+	w.Header().Set("Content-Type", apperr.ProblemContentType)
+	w.WriteHeader(problem.Status)
+	if errResp := json.NewEncoder(w).Encode(problem); errResp != nil {
 		log.Fatal(errResp)
 	}
 }
@@ -54,3 +106,49 @@ func CloseChannel(ch chan error) {
 		close(ch)
 	}
 }
+
+// pnrAlphabet excludes 0, 1, I and O to avoid misreading record locators
+const pnrAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const PNRLength = 6
+
+// GeneratePNR: generate 6-character airline-style record locator
+func GeneratePNR() (string, error) {
+	pnr := make([]byte, PNRLength)
+	max := big.NewInt(int64(len(pnrAlphabet)))
+	for idx := range pnr {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate pnr %w", err)
+		}
+		pnr[idx] = pnrAlphabet[n.Int64()]
+	}
+	return string(pnr), nil
+}
+
+var (
+	ErrInvalidCursor = apperr.New(apperr.BadRequest, "invalid cursor")
+	// ErrInvalidBody: request body missing or not json of payload, decoder message is sent as it only describes the body
+	ErrInvalidBody = apperr.New(apperr.BadRequest, "invalid request body")
+)
+
+// EncodeCursor: opaque pagination token of position value, url safe base64 of json
+func EncodeCursor(position any) (string, error) {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cursor %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor: position value of token from EncodeCursor
+func DecodeCursor(cursor string, position any) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(data, position); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return nil
+}
@@ -13,7 +13,13 @@ export default function () {
   const idx = Math.random() >= 0.5? 1: 0;
   const flight_id = flight_ids[idx];
   const ticket_numbers = Math.floor(Math.random()*10)+1;
-  const data = {flight_id, ticket_numbers};
+  // 依照 ticket_numbers 產生乘客資料
+  const passengers = Array.from({length: ticket_numbers}, (_, i) => ({
+    name: `passenger ${i}`,
+    date_of_birth: '1990-01-01',
+    document_number: `P${Math.floor(Math.random()*100000000)}`,
+  }));
+  const data = {flight_id, ticket_numbers, passengers};
   const params = {
     headers: {
       'Content-Type': 'application/json',
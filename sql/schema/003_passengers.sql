@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pnr VARCHAR(6) DEFAULT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS order_pnr ON orders (pnr);
+
+CREATE TABLE IF NOT EXISTS passengers (
+  id UUID PRIMARY KEY NOT NULL,
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  date_of_birth DATE NOT NULL,
+  document_number VARCHAR(50) NOT NULL,
+  ordinal SMALLINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS passenger_order_id ON passengers (order_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS passenger_order_id CASCADE;
+DROP TABLE IF EXISTS passengers;
+DROP INDEX IF EXISTS order_pnr CASCADE;
+ALTER TABLE orders DROP COLUMN IF EXISTS pnr;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS ticket_sequences (
+  airline_prefix VARCHAR(3) PRIMARY KEY NOT NULL,
+  last_serial BIGINT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER TABLE passengers ADD COLUMN IF NOT EXISTS ticket_number VARCHAR(13) DEFAULT NULL;
+ALTER TABLE passengers ADD COLUMN IF NOT EXISTS ticket_issued_at TIMESTAMP DEFAULT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS passenger_ticket_number ON passengers (ticket_number);
+
+-- +goose Down
+DROP INDEX IF EXISTS passenger_ticket_number CASCADE;
+ALTER TABLE passengers DROP COLUMN IF EXISTS ticket_issued_at;
+ALTER TABLE passengers DROP COLUMN IF EXISTS ticket_number;
+DROP TABLE IF EXISTS ticket_sequences;
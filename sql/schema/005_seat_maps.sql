@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS seat_map_templates (
+  id UUID PRIMARY KEY NOT NULL,
+  name VARCHAR(100) NOT NULL UNIQUE,
+  layout JSONB NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS flight_seats (
+  flight_id UUID NOT NULL REFERENCES flights(id) ON DELETE CASCADE,
+  seat_number VARCHAR(4) NOT NULL,
+  row_number INTEGER NOT NULL,
+  letter VARCHAR(1) NOT NULL,
+  cabin VARCHAR(20) NOT NULL,
+  is_exit_row BOOLEAN NOT NULL DEFAULT FALSE,
+  is_blocked BOOLEAN NOT NULL DEFAULT FALSE,
+  PRIMARY KEY (flight_id, seat_number)
+);
+
+ALTER TABLE passengers ADD COLUMN IF NOT EXISTS seat_number VARCHAR(4) DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE passengers DROP COLUMN IF EXISTS seat_number;
+DROP TABLE IF EXISTS flight_seats;
+DROP TABLE IF EXISTS seat_map_templates;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE passengers ADD COLUMN IF NOT EXISTS checked_in_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE passengers ADD COLUMN IF NOT EXISTS checkin_sequence INTEGER DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE passengers DROP COLUMN IF EXISTS checkin_sequence;
+ALTER TABLE passengers DROP COLUMN IF EXISTS checked_in_at;
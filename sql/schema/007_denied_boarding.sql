@@ -0,0 +1,38 @@
+-- +goose Up
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS capacity INTEGER;
+UPDATE flights SET capacity = available_seats WHERE capacity IS NULL;
+ALTER TABLE flights ALTER COLUMN capacity SET DEFAULT 0;
+ALTER TABLE flights ALTER COLUMN capacity SET NOT NULL;
+
+ALTER TABLE passengers ADD COLUMN IF NOT EXISTS volunteered_at TIMESTAMP DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS gate_closures (
+  flight_id UUID PRIMARY KEY NOT NULL REFERENCES flights(id) ON DELETE CASCADE,
+  capacity INTEGER NOT NULL,
+  checked_in INTEGER NOT NULL,
+  denied INTEGER NOT NULL,
+  closed_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS denied_boardings (
+  id UUID PRIMARY KEY NOT NULL,
+  flight_id UUID NOT NULL REFERENCES flights(id) ON DELETE CASCADE,
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  passenger_id UUID NOT NULL REFERENCES passengers(id) ON DELETE CASCADE,
+  is_voluntary BOOLEAN NOT NULL DEFAULT FALSE,
+  compensation DECIMAL(10,2) NOT NULL DEFAULT 0,
+  rebooked_flight_id UUID DEFAULT NULL REFERENCES flights(id) ON DELETE SET NULL,
+  rebooked_order_id UUID DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS denied_boarding_flight_id ON denied_boardings (flight_id);
+CREATE UNIQUE INDEX IF NOT EXISTS denied_boarding_passenger ON denied_boardings (flight_id, passenger_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS denied_boarding_passenger CASCADE;
+DROP INDEX IF EXISTS denied_boarding_flight_id CASCADE;
+DROP TABLE IF EXISTS denied_boardings;
+DROP TABLE IF EXISTS gate_closures;
+ALTER TABLE passengers DROP COLUMN IF EXISTS volunteered_at;
+ALTER TABLE flights DROP COLUMN IF EXISTS capacity;
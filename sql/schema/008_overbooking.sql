@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS wait_capacity INTEGER;
+UPDATE flights SET wait_capacity = wait_seats WHERE wait_capacity IS NULL;
+ALTER TABLE flights ALTER COLUMN wait_capacity SET DEFAULT 0;
+ALTER TABLE flights ALTER COLUMN wait_capacity SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS overbooking_policies (
+  id UUID PRIMARY KEY NOT NULL,
+  departure VARCHAR(100) NOT NULL DEFAULT '',
+  destination VARCHAR(100) NOT NULL DEFAULT '',
+  oversell_percentage DECIMAL(5,2) NOT NULL DEFAULT 0,
+  no_show_rate DECIMAL(5,4) NOT NULL DEFAULT 0,
+  max_wait_seats INTEGER NOT NULL DEFAULT 0,
+  effective_from TIMESTAMP NOT NULL,
+  effective_to TIMESTAMP DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS overbooking_policy_route ON overbooking_policies (departure, destination, effective_from);
+
+-- +goose Down
+DROP INDEX IF EXISTS overbooking_policy_route CASCADE;
+DROP TABLE IF EXISTS overbooking_policies;
+ALTER TABLE flights DROP COLUMN IF EXISTS wait_capacity;
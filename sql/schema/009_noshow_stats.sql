@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS boarding_outcomes (
+  passenger_id UUID PRIMARY KEY NOT NULL REFERENCES passengers(id) ON DELETE CASCADE,
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  flight_id UUID NOT NULL REFERENCES flights(id) ON DELETE CASCADE,
+  departure VARCHAR(100) NOT NULL,
+  destination VARCHAR(100) NOT NULL,
+  day_of_week SMALLINT NOT NULL,
+  lead_time_days INTEGER NOT NULL,
+  outcome VARCHAR(20) NOT NULL,
+  recorded_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS boarding_outcome_route ON boarding_outcomes (departure, destination);
+CREATE INDEX IF NOT EXISTS boarding_outcome_flight_id ON boarding_outcomes (flight_id);
+
+CREATE TABLE IF NOT EXISTS flight_outcome_records (
+  flight_id UUID PRIMARY KEY NOT NULL REFERENCES flights(id) ON DELETE CASCADE,
+  passengers INTEGER NOT NULL,
+  recorded_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS noshow_stats (
+  departure VARCHAR(100) NOT NULL,
+  destination VARCHAR(100) NOT NULL,
+  day_of_week SMALLINT NOT NULL,
+  lead_time_bucket VARCHAR(20) NOT NULL,
+  booked INTEGER NOT NULL,
+  no_shows INTEGER NOT NULL,
+  no_show_rate DECIMAL(5,4) NOT NULL,
+  computed_at TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (departure, destination, day_of_week, lead_time_bucket)
+);
+
+ALTER TABLE overbooking_policies ADD COLUMN IF NOT EXISTS use_noshow_stats BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE overbooking_policies DROP COLUMN IF EXISTS use_noshow_stats;
+DROP TABLE IF EXISTS noshow_stats;
+DROP TABLE IF EXISTS flight_outcome_records;
+DROP INDEX IF EXISTS boarding_outcome_flight_id CASCADE;
+DROP INDEX IF EXISTS boarding_outcome_route CASCADE;
+DROP TABLE IF EXISTS boarding_outcomes;
@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS airports (
+  iata_code VARCHAR(3) PRIMARY KEY NOT NULL,
+  name VARCHAR(200) NOT NULL,
+  city VARCHAR(100) NOT NULL,
+  country VARCHAR(2) NOT NULL,
+  timezone VARCHAR(64) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO airports (iata_code, name, city, country, timezone) VALUES
+  ('TPE', 'Taiwan Taoyuan International Airport', 'Taipei', 'TW', 'Asia/Taipei'),
+  ('TSA', 'Taipei Songshan Airport', 'Taipei', 'TW', 'Asia/Taipei'),
+  ('KHH', 'Kaohsiung International Airport', 'Kaohsiung', 'TW', 'Asia/Taipei'),
+  ('HKG', 'Hong Kong International Airport', 'Hong Kong', 'HK', 'Asia/Hong_Kong'),
+  ('NRT', 'Narita International Airport', 'Tokyo', 'JP', 'Asia/Tokyo'),
+  ('HND', 'Tokyo Haneda Airport', 'Tokyo', 'JP', 'Asia/Tokyo'),
+  ('KIX', 'Kansai International Airport', 'Osaka', 'JP', 'Asia/Tokyo'),
+  ('ICN', 'Incheon International Airport', 'Seoul', 'KR', 'Asia/Seoul'),
+  ('SIN', 'Singapore Changi Airport', 'Singapore', 'SG', 'Asia/Singapore'),
+  ('BKK', 'Suvarnabhumi Airport', 'Bangkok', 'TH', 'Asia/Bangkok'),
+  ('LAX', 'Los Angeles International Airport', 'Los Angeles', 'US', 'America/Los_Angeles'),
+  ('SFO', 'San Francisco International Airport', 'San Francisco', 'US', 'America/Los_Angeles'),
+  ('JFK', 'John F. Kennedy International Airport', 'New York', 'US', 'America/New_York'),
+  ('LHR', 'Heathrow Airport', 'London', 'GB', 'Europe/London')
+ON CONFLICT (iata_code) DO NOTHING;
+
+-- naive timestamps were written as utc
+ALTER TABLE flights ALTER COLUMN flight_date TYPE TIMESTAMPTZ USING flight_date AT TIME ZONE 'UTC';
+ALTER TABLE flights ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE flights ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+
+-- existing free-text rows are kept, new rows must reference airports
+ALTER TABLE flights ADD CONSTRAINT flights_departure_airport FOREIGN KEY (departure) REFERENCES airports(iata_code) NOT VALID;
+ALTER TABLE flights ADD CONSTRAINT flights_destination_airport FOREIGN KEY (destination) REFERENCES airports(iata_code) NOT VALID;
+
+-- +goose Down
+ALTER TABLE flights DROP CONSTRAINT IF EXISTS flights_destination_airport;
+ALTER TABLE flights DROP CONSTRAINT IF EXISTS flights_departure_airport;
+ALTER TABLE flights ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
+ALTER TABLE flights ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+ALTER TABLE flights ALTER COLUMN flight_date TYPE TIMESTAMP USING flight_date AT TIME ZONE 'UTC';
+DROP TABLE IF EXISTS airports;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS schedules (
+  id UUID PRIMARY KEY NOT NULL,
+  flight_number VARCHAR(10) NOT NULL,
+  departure VARCHAR(3) NOT NULL REFERENCES airports(iata_code),
+  destination VARCHAR(3) NOT NULL REFERENCES airports(iata_code),
+  -- bit n set when schedule operates on weekday n, 0 = sunday
+  days_of_week SMALLINT NOT NULL,
+  -- local time at departure airport, HH:MM
+  departure_time VARCHAR(5) NOT NULL,
+  effective_from DATE NOT NULL,
+  effective_to DATE DEFAULT NULL,
+  capacity INTEGER NOT NULL,
+  price DECIMAL(10,2) NOT NULL,
+  wait_seats INTEGER DEFAULT NULL,
+  seat_map_id UUID DEFAULT NULL REFERENCES seat_map_templates(id),
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  generated_until DATE DEFAULT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS schedule_id UUID DEFAULT NULL REFERENCES schedules(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS flight_schedule_date ON flights (schedule_id, flight_date);
+
+-- +goose Down
+DROP INDEX IF EXISTS flight_schedule_date CASCADE;
+ALTER TABLE flights DROP COLUMN IF EXISTS schedule_id;
+DROP TABLE IF EXISTS schedules;
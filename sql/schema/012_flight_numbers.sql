@@ -0,0 +1,41 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS aircraft_types (
+  code VARCHAR(10) PRIMARY KEY NOT NULL,
+  model VARCHAR(100) NOT NULL,
+  -- cabin configuration, e.g. "J32 W24 Y250"
+  cabin_configuration VARCHAR(100) NOT NULL,
+  total_seats INTEGER NOT NULL,
+  seat_map_id UUID DEFAULT NULL REFERENCES seat_map_templates(id),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO aircraft_types (code, model, cabin_configuration, total_seats) VALUES
+  ('A321', 'Airbus A321neo', 'J12 Y168', 180),
+  ('A333', 'Airbus A330-300', 'J30 Y277', 307),
+  ('A359', 'Airbus A350-900', 'J32 W31 Y243', 306),
+  ('B738', 'Boeing 737-800', 'J8 Y150', 158),
+  ('B77W', 'Boeing 777-300ER', 'J40 W56 Y262', 358),
+  ('B789', 'Boeing 787-9', 'J26 W28 Y236', 290)
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS carrier_code VARCHAR(2) DEFAULT NULL;
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS flight_number VARCHAR(5) DEFAULT NULL;
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS aircraft_type VARCHAR(10) DEFAULT NULL REFERENCES aircraft_types(code);
+-- operating date at departure airport, flight numbers are unique per carrier and operating date
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS operating_date DATE DEFAULT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS flight_number_date ON flights (carrier_code, flight_number, operating_date);
+
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS carrier_code VARCHAR(2) NOT NULL DEFAULT 'ZZ';
+ALTER TABLE schedules ALTER COLUMN carrier_code DROP DEFAULT;
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS aircraft_type VARCHAR(10) DEFAULT NULL REFERENCES aircraft_types(code);
+
+-- +goose Down
+ALTER TABLE schedules DROP COLUMN IF EXISTS aircraft_type;
+ALTER TABLE schedules DROP COLUMN IF EXISTS carrier_code;
+DROP INDEX IF EXISTS flight_number_date CASCADE;
+ALTER TABLE flights DROP COLUMN IF EXISTS operating_date;
+ALTER TABLE flights DROP COLUMN IF EXISTS aircraft_type;
+ALTER TABLE flights DROP COLUMN IF EXISTS flight_number;
+ALTER TABLE flights DROP COLUMN IF EXISTS carrier_code;
+DROP TABLE IF EXISTS aircraft_types;
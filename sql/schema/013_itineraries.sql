@@ -0,0 +1,16 @@
+-- +goose Up
+-- scheduled arrival, flights without arrival_date are only offered as direct itineraries
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS arrival_date TIMESTAMPTZ DEFAULT NULL;
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS block_minutes INTEGER DEFAULT NULL;
+
+-- orders booked together on a connecting itinerary share itinerary_id, leg_sequence starts from 1
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS itinerary_id UUID DEFAULT NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS leg_sequence SMALLINT DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS order_itinerary ON orders (itinerary_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS order_itinerary CASCADE;
+ALTER TABLE orders DROP COLUMN IF EXISTS leg_sequence;
+ALTER TABLE orders DROP COLUMN IF EXISTS itinerary_id;
+ALTER TABLE schedules DROP COLUMN IF EXISTS block_minutes;
+ALTER TABLE flights DROP COLUMN IF EXISTS arrival_date;
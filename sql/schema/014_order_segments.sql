@@ -0,0 +1,14 @@
+-- +goose Up
+-- flights of round-trip and multi-city orders, orders.flight_id stays the flight of segment 1
+CREATE TABLE IF NOT EXISTS order_segments (
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  segment_sequence SMALLINT NOT NULL,
+  flight_id UUID NOT NULL REFERENCES flights(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (order_id, segment_sequence)
+);
+CREATE INDEX IF NOT EXISTS order_segment_flight_id ON order_segments (flight_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS order_segment_flight_id CASCADE;
+DROP TABLE IF EXISTS order_segments;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- customer accounts, email is stored lower case and password only as bcrypt hash
+CREATE TABLE IF NOT EXISTS customers (
+  id UUID PRIMARY KEY,
+  email VARCHAR(254) NOT NULL UNIQUE,
+  password_hash VARCHAR(100) NOT NULL,
+  name VARCHAR(200) NOT NULL,
+  phone VARCHAR(16) DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+-- orders booked without customer stay anonymous
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id UUID DEFAULT NULL REFERENCES customers(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS order_customer_created_at ON orders (customer_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS order_customer_created_at CASCADE;
+ALTER TABLE orders DROP COLUMN IF EXISTS customer_id;
+DROP TABLE IF EXISTS customers;
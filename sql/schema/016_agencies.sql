@@ -0,0 +1,31 @@
+-- +goose Up
+-- travel agencies booking server-to-server, seat_allotment caps seats per flight booked by agency
+CREATE TABLE IF NOT EXISTS agencies (
+  id UUID PRIMARY KEY,
+  name VARCHAR(200) NOT NULL,
+  commission_rate DECIMAL(5,4) NOT NULL DEFAULT 0,
+  seat_allotment INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+-- only sha256 of api key is kept, prefix is the public part used for lookup
+CREATE TABLE IF NOT EXISTS agency_api_keys (
+  id UUID PRIMARY KEY,
+  agency_id UUID NOT NULL REFERENCES agencies(id) ON DELETE CASCADE,
+  prefix VARCHAR(16) NOT NULL UNIQUE,
+  key_hash VARCHAR(64) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  daily_request_quota INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  revoked_at TIMESTAMP DEFAULT NULL
+);
+CREATE INDEX IF NOT EXISTS agency_api_key_agency_id ON agency_api_keys (agency_id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS agency_id UUID DEFAULT NULL REFERENCES agencies(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS order_agency_paid_at ON orders (agency_id, paid_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS order_agency_paid_at CASCADE;
+ALTER TABLE orders DROP COLUMN IF EXISTS agency_id;
+DROP INDEX IF EXISTS agency_api_key_agency_id CASCADE;
+DROP TABLE IF EXISTS agency_api_keys;
+DROP TABLE IF EXISTS agencies;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- flag, challenge and block decisions of order abuse screening, order_id is set for flagged orders accepted
+CREATE TABLE IF NOT EXISTS abuse_decisions (
+  id UUID PRIMARY KEY,
+  action VARCHAR(20) NOT NULL,
+  score INTEGER NOT NULL,
+  signals JSONB NOT NULL,
+  actors VARCHAR(100)[] NOT NULL,
+  order_id UUID DEFAULT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS abuse_decision_action_created_at ON abuse_decisions (action, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS abuse_decision_action_created_at CASCADE;
+DROP TABLE IF EXISTS abuse_decisions;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- price per ticket when order was booked, sum of segment prices for multi-segment order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fare DECIMAL(10,2) DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE orders DROP COLUMN IF EXISTS fare;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- wait seats given explicitly by admin or schedule are kept when overbooking policies are recomputed,
+-- flights existing before the flag cannot tell explicit wait seats apart so they keep theirs too
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS wait_capacity_override BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE flights SET wait_capacity_override = TRUE;
+
+-- +goose Down
+ALTER TABLE flights DROP COLUMN IF EXISTS wait_capacity_override;